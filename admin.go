@@ -0,0 +1,95 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// minAdminToken is the shortest -admin-token accepted, in bytes.
+const minAdminToken = 16
+
+// adminFingerprintResponse is the stored history of one fingerprint.
+type adminFingerprintResponse struct {
+	Fingerprint   string `json:"fingerprint"`
+	HitCount      int64  `json:"hit_count"`
+	FirstSeen     string `json:"first_seen"`
+	LastSeen      string `json:"last_seen"`
+	LastIP        string `json:"last_ip,omitempty"`
+	LastUserAgent string `json:"last_user_agent,omitempty"`
+	// IPs and UserAgents are the distinct values recently seen, most
+	// recent first, up to maxVisitValues of each
+	IPs        []string `json:"ips"`
+	UserAgents []string `json:"user_agents"`
+	// Flags are the labels the server currently applies to the
+	// fingerprint, such as "blocked" by -denylist or -allowlist
+	Flags []string `json:"flags"`
+}
+
+// requireAdmin rejects requests that do not carry the bearer token in
+// their Authorization header.
+func (s *server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.isAdmin(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// isAdmin reports whether r carries the -admin-token as a bearer token in
+// its Authorization header, never when no token is set. It compares in
+// constant time so the token cannot be guessed a byte at a time.
+func (s *server) isAdmin(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && s.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1
+}
+
+// handleAdminFingerprint returns what the store has recorded about the
+// fingerprint in the path.
+func (s *server) handleAdminFingerprint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+	hash := r.PathValue("hash")
+	v, err := s.store.Get(r.Context(), hash)
+	if errors.Is(err, errNotFound) {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		slog.Error("failed to look up fingerprint", "fingerprint", hash, "error", err)
+		writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "fingerprint store unavailable"})
+		return
+	}
+
+	resp := adminFingerprintResponse{
+		Fingerprint:   hash,
+		HitCount:      v.HitCount,
+		FirstSeen:     v.FirstSeen.UTC().Format(time.RFC3339),
+		LastSeen:      v.LastSeen.UTC().Format(time.RFC3339),
+		LastIP:        v.LastIP,
+		LastUserAgent: v.LastUserAgent,
+		IPs:           v.IPs,
+		UserAgents:    v.UserAgents,
+		Flags:         []string{},
+	}
+	if resp.IPs == nil {
+		resp.IPs = []string{}
+	}
+	if resp.UserAgents == nil {
+		resp.UserAgents = []string{}
+	}
+	if s.list != nil && s.list.blocks(hash) {
+		resp.Flags = append(resp.Flags, "blocked")
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// defaultTorExitList is the list of Tor exit addresses the Tor Project
+// publishes, one IP per line.
+const defaultTorExitList = "https://check.torproject.org/torbulkexitlist"
+
+// maxTorExitList bounds the size of a fetched exit list; the published one
+// is a few hundred kilobytes.
+const maxTorExitList = 16 << 20
+
+// Anonymizer kinds reported in the anonymizer field.
+const (
+	anonymizerTor = "tor"
+	anonymizerVPN = "vpn"
+)
+
+// anonymizers flags client IPs that are Tor exit nodes or belong to
+// commercial VPN and proxy services. Like datacenter detection, it is
+// enrichment only and never feeds the fingerprint hash.
+type anonymizers struct {
+	// tor is nil when Tor detection is off
+	tor *torExitList
+	// vpn holds the operator's VPN ranges, in the -datacenter-ranges
+	// format, and is nil when none were given
+	vpn *datacenterList
+}
+
+// Lookup returns anonymizerTor or anonymizerVPN for an IP that is a Tor
+// exit or in a VPN range, with the VPN provider, or "" for other
+// addresses. asn is the AS number from a GeoIP ASN database, 0 if unknown.
+func (a *anonymizers) Lookup(ip string, asn uint) (kind, provider string) {
+	if a == nil {
+		return "", ""
+	}
+	if a.tor.contains(ip) {
+		return anonymizerTor, ""
+	}
+	if provider := a.vpn.Lookup(ip, asn); provider != "" {
+		return anonymizerVPN, provider
+	}
+	return "", ""
+}
+
+// torExitList is a set of Tor exit addresses loaded from a URL or file and
+// refreshed in the background. A failed refresh keeps the last list that
+// loaded, so a transient outage of the source does not stop detection.
+type torExitList struct {
+	source string
+	client *http.Client
+
+	addrs atomic.Pointer[map[netip.Addr]bool]
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// newTorExitList loads the exit list from source, an http(s) URL or a file
+// path, and refreshes it every interval until Close. A failed first load
+// is returned along with the list, which starts empty and keeps retrying,
+// so the server can start while the source is unreachable.
+func newTorExitList(source string, interval, timeout time.Duration) (*torExitList, error) {
+	l := &torExitList{
+		source: source,
+		client: &http.Client{Timeout: timeout},
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	l.addrs.Store(&map[netip.Addr]bool{})
+	err := l.refresh()
+	go l.run(interval)
+	return l, err
+}
+
+func (l *torExitList) run(interval time.Duration) {
+	defer close(l.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			if err := l.refresh(); err != nil {
+				slog.Error("failed to refresh Tor exit list, keeping the previous one",
+					"source", l.source, "count", l.size(), "error", err)
+				continue
+			}
+			slog.Info("refreshed Tor exit list", "source", l.source, "count", l.size())
+		}
+	}
+}
+
+// Close stops the background refresh.
+func (l *torExitList) Close() {
+	close(l.stop)
+	<-l.done
+}
+
+func (l *torExitList) size() int {
+	return len(*l.addrs.Load())
+}
+
+func (l *torExitList) contains(ip string) bool {
+	if l == nil {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	return (*l.addrs.Load())[addr.Unmap()]
+}
+
+// refresh replaces the list with a fresh copy from the source, keeping it
+// unchanged on any error.
+func (l *torExitList) refresh() error {
+	body, err := l.open()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	addrs, err := parseTorExitList(l.source, io.LimitReader(body, maxTorExitList))
+	if err != nil {
+		return err
+	}
+	l.addrs.Store(&addrs)
+	return nil
+}
+
+func (l *torExitList) open() (io.ReadCloser, error) {
+	if !strings.HasPrefix(l.source, "http://") && !strings.HasPrefix(l.source, "https://") {
+		return os.Open(l.source)
+	}
+	resp, err := l.client.Get(l.source)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetch %s: %s", l.source, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// parseTorExitList reads one IP address per line, skipping blank lines and
+// # comments. A list without any address is an error, so an empty
+// response never replaces a good list.
+func parseTorExitList(name string, r io.Reader) (map[netip.Addr]bool, error) {
+	addrs := make(map[netip.Addr]bool)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		addr, err := netip.ParseAddr(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+		addrs[addr.Unmap()] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", name, err)
+	}
+	if len(addrs) == 0 {
+		return nil, errors.New(name + ": no exit addresses")
+	}
+	return addrs, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const (
+	// maxBatchItems caps the number of entries in a /batch request.
+	maxBatchItems = 10000
+
+	// maxBatchBody bounds the size of a /batch request body.
+	maxBatchBody = 32 << 20
+)
+
+type batchResult struct {
+	Fingerprint       string `json:"fingerprint"`
+	StableFingerprint string `json:"stable_fingerprint"`
+}
+
+// handleBatch fingerprints a JSON array of request attributes, such as
+// entries parsed from archived access logs, and returns the results in
+// the same order. The array is decoded one entry at a time so only the
+// results, not the whole request, are held in memory.
+func (s *server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+
+	results, err := s.decodeBatch(w, r)
+	if err != nil {
+		status := http.StatusBadRequest
+		var maxBytes *http.MaxBytesError
+		if errors.As(err, &maxBytes) {
+			status = http.StatusRequestEntityTooLarge
+			err = fmt.Errorf("request body exceeds %d bytes", maxBytes.Limit)
+		}
+		writeJSON(w, status, errorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+func (s *server) decodeBatch(w http.ResponseWriter, r *http.Request) ([]batchResult, error) {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBody))
+	dec.DisallowUnknownFields()
+
+	if tok, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("invalid JSON body: %w", err)
+	} else if tok != json.Delim('[') {
+		return nil, errors.New("invalid JSON body: expected an array")
+	}
+
+	results := []batchResult{}
+	for dec.More() {
+		if len(results) == maxBatchItems {
+			return nil, fmt.Errorf("batch exceeds %d entries", maxBatchItems)
+		}
+
+		var attrs requestAttributes
+		if err := dec.Decode(&attrs); err != nil {
+			return nil, fmt.Errorf("invalid JSON body at entry %d: %w", len(results), err)
+		}
+
+		data, hash := s.config.FromData(attrs.data())
+		results = append(results, batchResult{
+			Fingerprint:       hash,
+			StableFingerprint: s.config.GenerateStable(data),
+		})
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("invalid JSON body: %w", err)
+	}
+	if dec.More() {
+		return nil, errors.New("invalid JSON body: unexpected data after array")
+	}
+	return results, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// benchmarkRequest returns the Chrome-like HTTP/2 request of the self-test
+// fixtures, the shape of most real traffic, for /fingerprint.
+func benchmarkRequest(b *testing.B) *http.Request {
+	b.Helper()
+	for _, fixture := range fingerprint.SelfTestFixtures() {
+		if fixture.Name == "chrome-h2" {
+			r := fixture.Request()
+			r.URL.Path = "/fingerprint"
+			return r
+		}
+	}
+	b.Fatal("no chrome-h2 self-test fixture")
+	return nil
+}
+
+// discardLogs silences the per-request log lines for the rest of b.
+func discardLogs(b *testing.B) {
+	b.Helper()
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	b.Cleanup(func() { slog.SetDefault(prev) })
+}
+
+// BenchmarkFingerprintHandler measures repeated identical requests, the
+// traffic the hash cache is meant for.
+func BenchmarkFingerprintHandler(b *testing.B) {
+	tests := []struct {
+		name  string
+		cache *fingerprint.HashCache
+	}{
+		{name: "uncached"},
+		{name: "cached", cache: fingerprint.NewHashCache(1000)},
+	}
+	for _, tt := range tests {
+		b.Run(tt.name, func(b *testing.B) {
+			discardLogs(b)
+			s := newTestServer(b)
+			s.config.Cache = tt.cache
+			b.ReportAllocs()
+			for b.Loop() {
+				b.StopTimer()
+				r := benchmarkRequest(b)
+				w := httptest.NewRecorder()
+				b.StartTimer()
+				s.handleFingerprint(w, r)
+				if w.Code != http.StatusOK {
+					b.Fatalf("status = %d: %s", w.Code, w.Body)
+				}
+			}
+		})
+	}
+}
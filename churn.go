@@ -0,0 +1,135 @@
+package main
+
+import (
+	"container/list"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// churnKeyHeader carries a caller-provided stable client key, such as
+	// an account or session ID, that churn tracking uses instead of the
+	// visitor cookie.
+	churnKeyHeader = "X-Fingerprint-Key"
+
+	// maxChurnKey bounds the length of a churnKeyHeader value.
+	maxChurnKey = 256
+
+	// maxChurnHistory bounds the distinct fingerprints remembered per key.
+	// Counts beyond it are reported as maxChurnHistory.
+	maxChurnHistory = 64
+)
+
+// churnTracker records the distinct fingerprints each client key, the
+// visitor cookie ID or a churnKeyHeader, produced within a sliding window.
+// A client cycling through more than threshold fingerprints within window
+// is likely rotating its User-Agent, headers, or TLS stack to evade
+// tracking. At most size keys are kept; the least recently seen is
+// evicted to make room, and a key whose fingerprints have all left the
+// window is forgotten. It is safe for concurrent use.
+type churnTracker struct {
+	threshold int
+	window    time.Duration
+	size      int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type churnEntry struct {
+	key string
+	// history holds the distinct fingerprints in the window, least
+	// recently seen first
+	history []churnSighting
+}
+
+type churnSighting struct {
+	hash string
+	seen time.Time
+}
+
+// churnResult is a client key's fingerprint history within the window.
+type churnResult struct {
+	// Count is the number of distinct fingerprints in the window,
+	// including the current one
+	Count int
+	// Churn is set when Count exceeds the threshold
+	Churn bool
+	// Fingerprints are the distinct fingerprints, in the order they were
+	// last seen
+	Fingerprints []string
+}
+
+func newChurnTracker(threshold int, window time.Duration, size int) *churnTracker {
+	return &churnTracker{
+		threshold: threshold,
+		window:    window,
+		size:      size,
+		entries:   make(map[string]*list.Element, size),
+		order:     list.New(),
+	}
+}
+
+// clientKey returns the key r is tracked by: its churnKeyHeader, or else
+// visitor, which is empty without -cookie. Over-long keys are ignored.
+func clientKey(r *http.Request, visitor string) string {
+	if key := r.Header.Get(churnKeyHeader); key != "" && len(key) <= maxChurnKey {
+		return key
+	}
+	return visitor
+}
+
+// Observe records that key produced hash at now and returns the key's
+// history within the window.
+func (c *churnTracker) Observe(key, hash string, now time.Time) churnResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var entry *churnEntry
+	if elem, ok := c.entries[key]; ok {
+		entry = elem.Value.(*churnEntry)
+		c.order.MoveToFront(elem)
+	} else {
+		entry = &churnEntry{key: key}
+		c.entries[key] = c.order.PushFront(entry)
+		if c.order.Len() > c.size {
+			oldest := c.order.Back()
+			c.order.Remove(oldest)
+			delete(c.entries, oldest.Value.(*churnEntry).key)
+		}
+	}
+
+	cutoff := now.Add(-c.window)
+	entry.history = slices.DeleteFunc(entry.history, func(s churnSighting) bool {
+		return s.hash == hash || !s.seen.After(cutoff)
+	})
+	entry.history = append(entry.history, churnSighting{hash: hash, seen: now})
+	if len(entry.history) > maxChurnHistory {
+		entry.history = slices.Delete(entry.history, 0, len(entry.history)-maxChurnHistory)
+	}
+	c.expire(now)
+
+	result := churnResult{Count: len(entry.history), Fingerprints: make([]string, len(entry.history))}
+	for i, s := range entry.history {
+		result.Fingerprints[i] = s.hash
+	}
+	result.Churn = result.Count > c.threshold
+	return result
+}
+
+// expire forgets the least recently seen keys whose fingerprints have all
+// left the window, so idle keys do not wait for eviction to free memory.
+func (c *churnTracker) expire(now time.Time) {
+	cutoff := now.Add(-c.window)
+	for elem := c.order.Back(); elem != nil; elem = c.order.Back() {
+		entry := elem.Value.(*churnEntry)
+		if entry.history[len(entry.history)-1].seen.After(cutoff) {
+			return
+		}
+		c.order.Remove(elem)
+		delete(c.entries, entry.key)
+	}
+}
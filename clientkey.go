@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// client describes the sender of a request to a clientKeyStrategy.
+type client struct {
+	ip        string
+	userAgent string
+	// fingerprint computes the fingerprint of the request, which may cost
+	// a full hash when no handler has computed it yet
+	fingerprint func() string
+}
+
+// clientKeyStrategy derives the key a client is rate limited and counted
+// in /stats by. Requests with the same key share a rate limit bucket.
+type clientKeyStrategy interface {
+	key(c client) string
+}
+
+// clientKeys lists the -client-key strategies.
+var clientKeys = []string{"ip", "subnet24", "subnet48-v6", "ip+ua", "fingerprint"}
+
+// parseClientKey returns the strategy named name.
+func parseClientKey(name string) (clientKeyStrategy, error) {
+	switch name {
+	case "ip":
+		return ipKey{}, nil
+	case "subnet24":
+		return subnetKey{v4: 24, v6: 64}, nil
+	case "subnet48-v6":
+		return subnetKey{v4: 24, v6: 48}, nil
+	case "ip+ua":
+		return ipUserAgentKey{}, nil
+	case "fingerprint":
+		return fingerprintKey{}, nil
+	}
+	return nil, fmt.Errorf("unknown client key %q, expected one of %s", name, strings.Join(clientKeys, ", "))
+}
+
+// ipKey keys clients by their exact IP address.
+type ipKey struct{}
+
+func (ipKey) key(c client) string { return c.ip }
+
+// subnetKey keys clients by the network their address is in: its first
+// v4 bits for IPv4 and v6 bits for IPv6. IPv4-mapped IPv6 addresses are
+// treated as IPv4, and an address that does not parse is used whole.
+type subnetKey struct {
+	v4, v6 int
+}
+
+func (k subnetKey) key(c client) string {
+	addr, err := netip.ParseAddr(c.ip)
+	if err != nil {
+		return c.ip
+	}
+	addr = addr.Unmap().WithZone("")
+	bits := k.v6
+	if addr.Is4() {
+		bits = k.v4
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return c.ip
+	}
+	return prefix.String()
+}
+
+// maxKeyUserAgent bounds the part of the User-Agent in an ip+ua key, so a
+// client cannot make each tracked key arbitrarily large.
+const maxKeyUserAgent = 256
+
+// ipUserAgentKey keys clients by their IP address and User-Agent, so
+// clients behind one NAT that run different browsers are told apart.
+type ipUserAgentKey struct{}
+
+func (ipUserAgentKey) key(c client) string {
+	ua := c.userAgent
+	if len(ua) > maxKeyUserAgent {
+		ua = ua[:maxKeyUserAgent]
+	}
+	return c.ip + " " + ua
+}
+
+// fingerprintKey keys clients by their fingerprint, whatever address they
+// come from.
+type fingerprintKey struct{}
+
+func (fingerprintKey) key(c client) string { return c.fingerprint() }
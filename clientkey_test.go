@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClientKey(t *testing.T) {
+	longUA := strings.Repeat("a", maxKeyUserAgent+10)
+	tests := []struct {
+		strategy  string
+		ip        string
+		userAgent string
+		want      string
+	}{
+		{strategy: "ip", ip: "203.0.113.7", want: "203.0.113.7"},
+		{strategy: "ip", ip: "2001:db8::7", want: "2001:db8::7"},
+		{strategy: "subnet24", ip: "203.0.113.7", want: "203.0.113.0/24"},
+		{strategy: "subnet24", ip: "2001:db8:1:2:3::7", want: "2001:db8:1:2::/64"},
+		{strategy: "subnet24", ip: "::ffff:203.0.113.7", want: "203.0.113.0/24"},
+		{strategy: "subnet24", ip: "fe80::1%eth0", want: "fe80::/64"},
+		{strategy: "subnet24", ip: "unknown", want: "unknown"},
+		{strategy: "subnet48-v6", ip: "203.0.113.7", want: "203.0.113.0/24"},
+		{strategy: "subnet48-v6", ip: "2001:db8:1:2:3::7", want: "2001:db8:1::/48"},
+		{strategy: "ip+ua", ip: "203.0.113.7", userAgent: "curl/8.5.0", want: "203.0.113.7 curl/8.5.0"},
+		{strategy: "ip+ua", ip: "2001:db8::7", userAgent: "curl/8.5.0", want: "2001:db8::7 curl/8.5.0"},
+		{strategy: "ip+ua", ip: "203.0.113.7", userAgent: longUA, want: "203.0.113.7 " + longUA[:maxKeyUserAgent]},
+		{strategy: "fingerprint", ip: "203.0.113.7", want: "v1:3f1a"},
+		{strategy: "fingerprint", ip: "2001:db8::7", want: "v1:3f1a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy+"/"+tt.ip, func(t *testing.T) {
+			strategy, err := parseClientKey(tt.strategy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := client{ip: tt.ip, userAgent: tt.userAgent, fingerprint: func() string { return "v1:3f1a" }}
+			if got := strategy.key(c); got != tt.want {
+				t.Errorf("key = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseClientKey("cookie"); err == nil {
+		t.Error("parseClientKey accepted an unknown strategy")
+	}
+}
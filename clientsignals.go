@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// maxClientSignalsBody bounds the size of a /client-signals request body.
+// The reference collector sends well under 4 KiB.
+const maxClientSignalsBody = 16 << 10
+
+// fpJS is the reference collector served on /fp.js.
+//
+//go:embed fp.js
+var fpJS []byte
+
+type clientSignalsResponse struct {
+	Fingerprint          string   `json:"fingerprint"`
+	StableFingerprint    string   `json:"stable_fingerprint"`
+	CompositeFingerprint string   `json:"composite_fingerprint"`
+	HitCount             int64    `json:"hit_count,omitempty"`
+	FirstSeen            string   `json:"first_seen,omitempty"`
+	Components           []string `json:"components,omitempty"`
+	Timestamp            string   `json:"timestamp"`
+}
+
+// handleClientSignals combines browser-collected signals posted as JSON
+// with the server-side fingerprint of the request into a composite
+// fingerprint. The composite is persisted like a fingerprint when a
+// store is configured.
+func (s *server) handleClientSignals(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+
+	var signals fingerprint.ClientSignals
+	if err := decodeJSONBody(w, r, &signals, maxClientSignalsBody); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	if err := signals.Validate(); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	if s.cookie {
+		r = stripVisitorCookie(r)
+	}
+	config := s.policy(r).config
+	data, hash := config.FromRequest(r)
+	now := s.now()
+	resp := clientSignalsResponse{
+		Fingerprint:          hash,
+		StableFingerprint:    config.GenerateStable(data),
+		CompositeFingerprint: config.GenerateComposite(data, signals),
+		Timestamp:            now.Format(time.RFC3339),
+	}
+	if isDebug(r) {
+		resp.Components = config.CompositeComponents(data, signals)
+	}
+
+	if s.store != nil {
+		sighting := s.sighting(data, now, func(data fingerprint.Data) []string {
+			return config.CompositeComponents(data, signals)
+		})
+		v, err := s.store.Upsert(r.Context(), resp.CompositeFingerprint, sighting)
+		if err != nil {
+			slog.Error("failed to persist composite fingerprint", "fingerprint", resp.CompositeFingerprint, "error", err)
+		} else {
+			resp.HitCount = v.HitCount
+			resp.FirstSeen = v.FirstSeen.Format(time.RFC3339)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleFPJS serves the reference client signal collector.
+func handleFPJS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	http.ServeContent(w, r, "fp.js", time.Time{}, bytes.NewReader(fpJS))
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"cmp"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/maphash"
+	"slices"
+	"strings"
+	"sync"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// maxClusterMembers bounds the fingerprints remembered per cluster for the
+// exact-match fast path. Further variants still join the cluster by
+// distance.
+const maxClusterMembers = 16
+
+// clusterIndex groups fingerprints into clusters of likely variants of one
+// device, such as the same browser before and after a minor update. The
+// distance between two fingerprints is 1 minus their /compare similarity:
+// the weight of the components they disagree on, or only one has, over
+// the weight of all their components. A fingerprint joins the nearest
+// cluster whose first member is within the threshold, or starts a new one.
+//
+// Clusters are compared against their first member only, so they do not
+// drift. At most capacity clusters are kept; the least recently matched is
+// evicted to make room. A new fingerprint is compared with every cluster,
+// so the cost of a miss grows with capacity. It is safe for concurrent use.
+type clusterIndex struct {
+	threshold float64
+	capacity  int
+	seed      maphash.Seed
+
+	mu sync.Mutex
+	// clusters holds *cluster values, most recently matched first
+	clusters *list.List
+	// members maps remembered member fingerprints to their cluster
+	members map[string]*list.Element
+}
+
+type cluster struct {
+	id      string
+	vector  clusterVector
+	members []string
+}
+
+// clusterVector is the component-level form of a fingerprint used to
+// measure distances: each component's name and value hashed and weighted,
+// sorted by name.
+type clusterVector []clusterComponent
+
+type clusterComponent struct {
+	name, value uint64
+	weight      float64
+}
+
+func newClusterIndex(threshold float64, capacity int) *clusterIndex {
+	return &clusterIndex{
+		threshold: threshold,
+		capacity:  capacity,
+		seed:      maphash.MakeSeed(),
+		clusters:  list.New(),
+		members:   make(map[string]*list.Element),
+	}
+}
+
+// Assign returns the cluster ID of the fingerprint hash with the given
+// components, starting a new cluster if none is close enough.
+func (x *clusterIndex) Assign(hash string, components []string) string {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if elem, ok := x.members[hash]; ok {
+		x.clusters.MoveToFront(elem)
+		return elem.Value.(*cluster).id
+	}
+
+	v := x.vector(components)
+	if elem := x.nearest(v); elem != nil {
+		c := elem.Value.(*cluster)
+		if len(c.members) < maxClusterMembers {
+			c.members = append(c.members, hash)
+			x.members[hash] = elem
+		}
+		x.clusters.MoveToFront(elem)
+		return c.id
+	}
+
+	c := &cluster{id: clusterID(hash), vector: v, members: []string{hash}}
+	x.members[hash] = x.clusters.PushFront(c)
+	if x.clusters.Len() > x.capacity {
+		oldest := x.clusters.Back()
+		x.clusters.Remove(oldest)
+		for _, member := range oldest.Value.(*cluster).members {
+			delete(x.members, member)
+		}
+	}
+	return c.id
+}
+
+// Lookup returns the cluster ID Assign would return, without assigning.
+func (x *clusterIndex) Lookup(hash string, components []string) string {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if elem, ok := x.members[hash]; ok {
+		return elem.Value.(*cluster).id
+	}
+	if elem := x.nearest(x.vector(components)); elem != nil {
+		return elem.Value.(*cluster).id
+	}
+	return clusterID(hash)
+}
+
+// nearest returns the cluster closest to v within the threshold,
+// preferring the most recently matched on ties, or nil.
+func (x *clusterIndex) nearest(v clusterVector) *list.Element {
+	var best *list.Element
+	bestDistance := x.threshold
+	for elem := x.clusters.Front(); elem != nil; elem = elem.Next() {
+		distance := v.distance(elem.Value.(*cluster).vector)
+		if distance < bestDistance || best == nil && distance == bestDistance {
+			best, bestDistance = elem, distance
+		}
+	}
+	return best
+}
+
+func (x *clusterIndex) vector(components []string) clusterVector {
+	return newClusterVector(x.seed, components)
+}
+
+// newClusterVector builds the clusterVector of key:value components,
+// hashed with seed and weighted as fingerprint.Compare weighs them.
+func newClusterVector(seed maphash.Seed, components []string) clusterVector {
+	v := make(clusterVector, 0, len(components))
+	for _, component := range components {
+		name, value, _ := strings.Cut(component, ":")
+		v = append(v, clusterComponent{
+			name:   maphash.String(seed, name),
+			value:  maphash.String(seed, value),
+			weight: fingerprint.ComponentWeight(name),
+		})
+	}
+	slices.SortFunc(v, func(a, b clusterComponent) int { return cmp.Compare(a.name, b.name) })
+	return v
+}
+
+// distance returns 1 minus the weight of the components v and w agree on
+// over the weight of every component either has, from 0 for identical
+// vectors to 1. A 64-bit hash collision could count two different values
+// as equal, which is negligible here.
+func (v clusterVector) distance(w clusterVector) float64 {
+	var total, matched float64
+	i, j := 0, 0
+	for i < len(v) && j < len(w) {
+		switch a, b := v[i], w[j]; {
+		case a.name < b.name:
+			total += a.weight
+			i++
+		case a.name > b.name:
+			total += b.weight
+			j++
+		default:
+			total += a.weight
+			if a.value == b.value {
+				matched += a.weight
+			}
+			i++
+			j++
+		}
+	}
+	for ; i < len(v); i++ {
+		total += v[i].weight
+	}
+	for ; j < len(w); j++ {
+		total += w[j].weight
+	}
+	if total == 0 {
+		return 0
+	}
+	return 1 - matched/total
+}
+
+// edits returns the number of components v and w disagree on: those with
+// different values and those only one of them has. Weights are ignored.
+func (v clusterVector) edits(w clusterVector) int {
+	n := 0
+	i, j := 0, 0
+	for i < len(v) && j < len(w) {
+		switch a, b := v[i], w[j]; {
+		case a.name < b.name:
+			n++
+			i++
+		case a.name > b.name:
+			n++
+			j++
+		default:
+			if a.value != b.value {
+				n++
+			}
+			i++
+			j++
+		}
+	}
+	return n + len(v) - i + len(w) - j
+}
+
+// clusterID derives a cluster ID from the fingerprint that started it, so
+// the same first member yields the same ID across restarts.
+func clusterID(hash string) string {
+	sum := sha256.Sum256([]byte(hash))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// collisionAudit watches for fingerprint collisions: one hash computed
+// from two different component vectors. Components are length-prefixed
+// before they are hashed, so a collision takes one in the hash algorithm
+// itself and should never happen; one that does points at an encoding
+// bug, such as a delimiter that is not escaped. It remembers the
+// components of up to size recent fingerprints, least recently seen first
+// out, so collisions further apart are missed. It is safe for concurrent
+// use.
+type collisionAudit struct {
+	size int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type collisionEntry struct {
+	hash       string
+	components []string
+}
+
+func newCollisionAudit(size int) *collisionAudit {
+	return &collisionAudit{size: size, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// observe records that components hashed to hash. When hash was last seen
+// with different components, it returns those and true, and remembers the
+// new ones.
+func (a *collisionAudit) observe(hash string, components []string) (previous []string, collided bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if elem, ok := a.entries[hash]; ok {
+		entry := elem.Value.(*collisionEntry)
+		a.order.MoveToFront(elem)
+		if slices.Equal(entry.components, components) {
+			return nil, false
+		}
+		previous, entry.components = entry.components, components
+		return previous, true
+	}
+	a.entries[hash] = a.order.PushFront(&collisionEntry{hash: hash, components: components})
+	if a.order.Len() > a.size {
+		oldest := a.order.Back()
+		a.order.Remove(oldest)
+		delete(a.entries, oldest.Value.(*collisionEntry).hash)
+	}
+	return nil, false
+}
+
+// auditCollision feeds the components of a fingerprinted request to the
+// -collision-audit and logs any collision. The components are taken from
+// the redacted data, since they are logged; redaction never maps one
+// request's components onto another's under the same hash, so it can hide
+// a collision but not make one up.
+func (s *server) auditCollision(config *fingerprint.Config, data fingerprint.Data, hash string) {
+	components := config.Components(s.redactor.Data(data))
+	if previous, collided := s.collisions.observe(hash, components); collided {
+		s.metrics.collisions.Inc()
+		slog.Error("fingerprint collision",
+			"fingerprint", hash,
+			"components", previous,
+			"colliding_components", components)
+	}
+}
+
+// collisionReport is one collision found by -audit-file.
+type collisionReport struct {
+	Fingerprint string `json:"fingerprint"`
+	// Entries are the 0-based positions in the input of the colliding
+	// request attributes, the earlier one first
+	Entries    [2]int   `json:"entries"`
+	Components []string `json:"components"`
+	Colliding  []string `json:"colliding_components"`
+}
+
+// auditFile fingerprints every set of request attributes read from in,
+// either a JSON array in the /batch format or one JSON object per line,
+// and writes a JSON line to out for each pair of different component
+// vectors that hash to the same fingerprint. It returns the number of
+// entries read and of collisions found.
+func auditFile(config *fingerprint.Config, in io.Reader, out io.Writer) (entries, collisions int, err error) {
+	r := bufio.NewReader(in)
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	array := false
+	if first, err := peekNonSpace(r); err == nil && first == '[' {
+		dec.Token()
+		array = true
+	}
+
+	audit := newCollisionAudit(math.MaxInt)
+	// last is the position of the entry whose components audit holds
+	last := make(map[string]int)
+	enc := json.NewEncoder(out)
+	for dec.More() {
+		var attrs requestAttributes
+		if err := dec.Decode(&attrs); err != nil {
+			return entries, collisions, fmt.Errorf("invalid JSON input at entry %d: %w", entries, err)
+		}
+		data, hash := config.FromData(attrs.data())
+		components := config.Components(data)
+		if previous, collided := audit.observe(hash, components); collided {
+			collisions++
+			report := collisionReport{Fingerprint: hash, Entries: [2]int{last[hash], entries}, Components: previous, Colliding: components}
+			if err := enc.Encode(report); err != nil {
+				return entries, collisions, err
+			}
+		}
+		last[hash] = entries
+		entries++
+	}
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return entries, collisions, fmt.Errorf("invalid JSON input: %w", err)
+		}
+		if dec.More() {
+			return entries, collisions, errors.New("invalid JSON input: unexpected data after array")
+		}
+	}
+	return entries, collisions, nil
+}
+
+// peekNonSpace skips leading JSON whitespace in r and returns the next
+// byte without consuming it.
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, r.UnreadByte()
+	}
+}
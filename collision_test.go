@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// TestCollisionAuditObserve stubs the hash, so two different component
+// vectors can share one as a broken encoding would make them.
+func TestCollisionAuditObserve(t *testing.T) {
+	a := newCollisionAudit(2)
+	tests := []struct {
+		name       string
+		hash       string
+		components []string
+		previous   []string
+	}{
+		{name: "first sighting", hash: "v1:aaaa", components: []string{"ua:curl"}},
+		{name: "same components", hash: "v1:aaaa", components: []string{"ua:curl"}},
+		{name: "collision", hash: "v1:aaaa", components: []string{"ua:wget"}, previous: []string{"ua:curl"}},
+		{name: "colliding components are kept", hash: "v1:aaaa", components: []string{"ua:wget"}},
+		{name: "other fingerprint", hash: "v1:bbbb", components: []string{"ua:curl"}},
+		{name: "third fingerprint evicts the oldest", hash: "v1:cccc", components: []string{"ua:curl"}},
+		{name: "evicted fingerprint is new again", hash: "v1:aaaa", components: []string{"ua:curl"}},
+	}
+	for _, tt := range tests {
+		previous, collided := a.observe(tt.hash, tt.components)
+		if collided != (tt.previous != nil) || !slices.Equal(previous, tt.previous) {
+			t.Errorf("%s: observe = %q, %v; want %q, %v", tt.name, previous, collided, tt.previous, tt.previous != nil)
+		}
+	}
+}
+
+func TestAuditFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		entries int
+	}{
+		{
+			name:    "array",
+			input:   ` [{"headers": {"User-Agent": "curl/8.5.0"}}, {"headers": {"User-Agent": "Wget/1.21"}}, {"headers": {"User-Agent": "curl/8.5.0"}}]`,
+			entries: 3,
+		},
+		{
+			name:    "JSON lines",
+			input:   "{\"headers\": {\"User-Agent\": \"curl/8.5.0\"}}\n{\"headers\": {\"User-Agent\": \"curl/8.5.0\", \"Accept\": \"*/*\"}}\n",
+			entries: 2,
+		},
+		{
+			// Without length prefixes these would both hash "a:1|b:2"
+			name:    "values holding the delimiter",
+			input:   `[{"headers": {"a": "1|b:2"}}, {"headers": {"a": "1", "b": "2"}}]`,
+			entries: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			entries, collisions, err := auditFile(&fingerprint.Config{}, strings.NewReader(tt.input), &out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entries != tt.entries || collisions != 0 || out.Len() != 0 {
+				t.Errorf("auditFile = %d entries, %d collisions, output %q; want %d entries and none", entries, collisions, out.String(), tt.entries)
+			}
+		})
+	}
+
+	for _, input := range []string{`[{"headers": 1}]`, `{"unknown": true}`, `[{}] {}`} {
+		if _, _, err := auditFile(&fingerprint.Config{}, strings.NewReader(input), &bytes.Buffer{}); err == nil {
+			t.Errorf("auditFile accepted %s", input)
+		}
+	}
+}
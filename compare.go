@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// maxCompareBody bounds the size of a /compare request body.
+const maxCompareBody = 1 << 20
+
+// requestAttributes is the JSON form of the request signals accepted by
+// /compare and /batch. Headers carry User-Agent, Accept, and the other fingerprinted
+// headers exactly as a request would.
+type requestAttributes struct {
+	IP              string            `json:"ip"`
+	Method          string            `json:"method"`
+	Protocol        string            `json:"protocol"`
+	TLSVersion      string            `json:"tls_version"`
+	CipherSuite     string            `json:"cipher_suite"`
+	ALPN            string            `json:"alpn"`
+	ClientCert      string            `json:"client_cert_thumbprint"`
+	JA3             string            `json:"ja3"`
+	JA4             string            `json:"ja4"`
+	H2Fingerprint   string            `json:"h2_fingerprint"`
+	HeaderOrder     []string          `json:"header_order"`
+	TLSExtensions   []uint16          `json:"tls_extensions"`
+	SupportedGroups []uint16          `json:"supported_groups"`
+	Port            string            `json:"port"`
+	Path            string            `json:"path"`
+	QueryKeys       []string          `json:"query_keys"`
+	CookieNames     []string          `json:"cookie_names"`
+	Headers         map[string]string `json:"headers"`
+}
+
+// data converts the attributes to fingerprint data, lowercasing header
+// names as ExtractHeaders does.
+func (a requestAttributes) data() fingerprint.Data {
+	headers := make(map[string]string, len(a.Headers))
+	for name, value := range a.Headers {
+		headers[strings.ToLower(name)] = value
+	}
+
+	return fingerprint.Data{
+		IPAddress:            a.IP,
+		UserAgent:            headers["user-agent"],
+		AcceptLang:           headers["accept-language"],
+		AcceptEnc:            headers["accept-encoding"],
+		Accept:               headers["accept"],
+		Headers:              headers,
+		Method:               a.Method,
+		Protocol:             a.Protocol,
+		TLSVersion:           a.TLSVersion,
+		CipherSuite:          a.CipherSuite,
+		ALPN:                 a.ALPN,
+		ClientCertThumbprint: a.ClientCert,
+		JA3:                  a.JA3,
+		JA4:                  a.JA4,
+		H2Fingerprint:        a.H2Fingerprint,
+		HeaderOrder:          a.HeaderOrder,
+		TLSExtensions:        a.TLSExtensions,
+		SupportedGroups:      a.SupportedGroups,
+		Port:                 a.Port,
+		RequestPath:          a.Path,
+		QueryKeys:            a.QueryKeys,
+		CookieNames:          a.CookieNames,
+	}
+}
+
+type compareRequest struct {
+	A *requestAttributes `json:"a"`
+	B *requestAttributes `json:"b"`
+}
+
+type compareResponse struct {
+	Score       float64                  `json:"score"`
+	TierScore   float64                  `json:"tier_score"`
+	Match       bool                     `json:"match"`
+	A           string                   `json:"fingerprint_a"`
+	B           string                   `json:"fingerprint_b"`
+	Differences []fingerprint.Difference `json:"differences"`
+}
+
+// handleCompare scores the similarity of two sets of request attributes
+// posted as {"a": {...}, "b": {...}}.
+func (s *server) handleCompare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+
+	var req compareRequest
+	if err := decodeJSONBody(w, r, &req, maxCompareBody); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	if req.A == nil || req.B == nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: `both "a" and "b" are required`})
+		return
+	}
+
+	a, hashA := s.config.FromData(req.A.data())
+	b, hashB := s.config.FromData(req.B.data())
+	comparison := s.config.Compare(a, b)
+	resp := compareResponse{
+		Score:       comparison.Score,
+		TierScore:   fingerprint.CompareTiered(s.config.GenerateTiered(a), s.config.GenerateTiered(b)),
+		Match:       comparison.Score == 1,
+		A:           hashA,
+		B:           hashB,
+		Differences: comparison.Differences,
+	}
+	if resp.Differences == nil {
+		resp.Differences = []fingerprint.Difference{}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// decodeJSONBody decodes a JSON request body of at most limit bytes into
+// v, rejecting unknown fields and trailing data.
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, v any, limit int64) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, limit))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var maxBytes *http.MaxBytesError
+		if errors.As(err, &maxBytes) {
+			return fmt.Errorf("request body exceeds %d bytes", maxBytes.Limit)
+		}
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+	if dec.More() {
+		return errors.New("invalid JSON body: unexpected data after object")
+	}
+	return nil
+}
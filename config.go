@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix prefixes the environment variable of every flag, so -store-dsn
+// is also set by FINGERPRINT_STORE_DSN.
+const envPrefix = "FINGERPRINT_"
+
+// secretFlags are the flags whose values are redacted when the effective
+// configuration is logged. DSNs and webhook URLs may embed credentials.
+var secretFlags = map[string]bool{
+	"admin-token":  true,
+	"nonce-secret": true,
+	"redact-key":   true,
+	"salt":         true,
+	"store-dsn":    true,
+	"token-secret": true,
+	"webhook-url":  true,
+}
+
+// Sources a flag's effective value can come from, from lowest to highest
+// precedence.
+const (
+	sourceDefault = "default"
+	sourceFile    = "config"
+	sourceEnv     = "env"
+	sourceFlag    = "flag"
+)
+
+// flagEnv returns the environment variable that sets the named flag.
+func flagEnv(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyConfig sets every flag of fs not given on the command line from its
+// environment variable or, failing that, the YAML file at path, if any. It
+// returns where each flag's value came from. The file maps flag names
+// without the leading dash to values, with lists joined by commas:
+//
+//	addr: ":8443"
+//	tls-cert: /etc/fingerprint/cert.pem
+//	rate: 5
+//	trusted-proxies: [10.0.0.0/8, 192.168.0.0/16]
+//
+// Unknown keys and values a flag rejects are errors, reported with their
+// line in the file.
+func applyConfig(fs *flag.FlagSet, path string) (map[string]string, error) {
+	sources := make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) { sources[f.Name] = sourceDefault })
+	fs.Visit(func(f *flag.Flag) { sources[f.Name] = sourceFlag })
+
+	if path != "" {
+		values, err := loadConfigFile(fs, path)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range values {
+			if sources[name] != sourceFlag {
+				if err := fs.Set(name, value.value); err != nil {
+					return nil, fmt.Errorf("%s:%d: invalid value %q for %s: %w", path, value.line, value.value, name, err)
+				}
+				sources[name] = sourceFile
+			}
+		}
+	}
+
+	var errs []error
+	fs.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(flagEnv(f.Name))
+		if !ok || value == "" || sources[f.Name] == sourceFlag || f.Name == "config" {
+			return
+		}
+		if err := fs.Set(f.Name, value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid value %q for %s: %w", value, flagEnv(f.Name), err))
+			return
+		}
+		sources[f.Name] = sourceEnv
+	})
+	return sources, errors.Join(errs...)
+}
+
+type configValue struct {
+	value string
+	line  int
+}
+
+// loadConfigFile reads the YAML config file at path and returns its values
+// keyed by flag name.
+func loadConfigFile(fs *flag.FlagSet, path string) (map[string]configValue, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.NewDecoder(bytes.NewReader(raw)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	values := make(map[string]configValue)
+	if len(doc.Content) == 0 {
+		return values, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s:%d: config must be a mapping of flag names to values", path, root.Line)
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, node := root.Content[i], root.Content[i+1]
+		name := strings.TrimPrefix(key.Value, "-")
+		if fs.Lookup(name) == nil || name == "config" {
+			return nil, fmt.Errorf("%s:%d: unknown setting %q", path, key.Line, key.Value)
+		}
+		if _, ok := values[name]; ok {
+			return nil, fmt.Errorf("%s:%d: duplicate setting %q", path, key.Line, key.Value)
+		}
+
+		var value string
+		switch node.Kind {
+		case yaml.ScalarNode:
+			value = node.Value
+		case yaml.SequenceNode:
+			items := make([]string, len(node.Content))
+			for j, item := range node.Content {
+				if item.Kind != yaml.ScalarNode {
+					return nil, fmt.Errorf("%s:%d: %s must be a list of plain values", path, item.Line, name)
+				}
+				items[j] = item.Value
+			}
+			value = strings.Join(items, ",")
+		default:
+			return nil, fmt.Errorf("%s:%d: %s must be a value or a list of values", path, node.Line, name)
+		}
+		values[name] = configValue{value: value, line: key.Line}
+	}
+	return values, nil
+}
+
+// logConfig logs the effective value and source of every setting that is
+// not at its default, redacting secrets.
+func logConfig(fs *flag.FlagSet, sources map[string]string) {
+	var attrs []any
+	fs.VisitAll(func(f *flag.Flag) {
+		source := sources[f.Name]
+		if source == sourceDefault {
+			return
+		}
+		value := f.Value.String()
+		if secretFlags[f.Name] && value != "" {
+			value = "[redacted]"
+		}
+		attrs = append(attrs, slog.Group(f.Name, "value", value, "source", source))
+	})
+	slog.Info("effective configuration", attrs...)
+}
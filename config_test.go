@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestApplyConfigPrecedence checks that a flag on the command line beats
+// its environment variable, which beats the config file, which beats the
+// default.
+func TestApplyConfigPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	config := "addr: \":9000\"\nrate: 5\nburst: 7\ntrusted-proxies: [10.0.0.0/8, 192.168.0.0/16]\n"
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(flagEnv("addr"), ":9100")
+	t.Setenv(flagEnv("rate"), "3")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("addr", ":8080", "")
+	fs.Float64("rate", 0, "")
+	fs.Int("burst", 10, "")
+	fs.String("trusted-proxies", "", "")
+	fs.Duration("idle-timeout", 2*time.Minute, "")
+	if err := fs.Parse([]string{"-addr", ":9200"}); err != nil {
+		t.Fatal(err)
+	}
+	sources, err := applyConfig(fs, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		want   string
+		source string
+	}{
+		{name: "addr", want: ":9200", source: sourceFlag},
+		{name: "rate", want: "3", source: sourceEnv},
+		{name: "burst", want: "7", source: sourceFile},
+		{name: "trusted-proxies", want: "10.0.0.0/8,192.168.0.0/16", source: sourceFile},
+		{name: "idle-timeout", want: "2m0s", source: sourceDefault},
+	}
+	for _, tt := range tests {
+		got := fs.Lookup(tt.name).Value.String()
+		if got != tt.want || sources[tt.name] != tt.source {
+			t.Errorf("%s = %q from %s, want %q from %s", tt.name, got, sources[tt.name], tt.want, tt.source)
+		}
+	}
+}
+
+func TestApplyConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{name: "unknown setting", config: "addr: \":9000\"\nport: 80\n", want: "config.yaml:2: unknown setting \"port\""},
+		{name: "invalid value", config: "burst: many\n", want: "config.yaml:1: invalid value \"many\" for burst"},
+		{name: "duplicate setting", config: "burst: 1\nburst: 2\n", want: "config.yaml:2: duplicate setting"},
+		{name: "not a mapping", config: "- burst\n", want: "must be a mapping"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.String("addr", ":8080", "")
+			fs.Int("burst", 10, "")
+			_, err := applyConfig(fs, path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("applyConfig error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	visitorCookieName = "fp_visitor"
+
+	// visitorCookieMaxAge is the longest lifetime browsers accept for a
+	// cookie (RFC 6265bis caps Max-Age at 400 days).
+	visitorCookieMaxAge = 400 * 24 * time.Hour
+)
+
+// visitorID returns the visitor ID from the request's cookie, or a new
+// random ID and true when the cookie is missing or not a valid UUID.
+func visitorID(r *http.Request) (string, bool) {
+	if cookie, err := r.Cookie(visitorCookieName); err == nil {
+		if id, err := uuid.Parse(cookie.Value); err == nil {
+			return id.String(), false
+		}
+	}
+	return uuid.NewString(), true
+}
+
+// setVisitorCookie sets the long-lived visitor ID cookie.
+func setVisitorCookie(w http.ResponseWriter, r *http.Request, id string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     visitorCookieName,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   int(visitorCookieMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// stripVisitorCookie removes the visitor ID cookie from the request's
+// Cookie header so it can never feed the fingerprint hash, even when
+// Cookie is one of the fingerprinted headers. When it was the only cookie,
+// the Cookie header is removed from the recorded header order too, so a
+// returning client hashes the same as on its first request.
+func stripVisitorCookie(r *http.Request) *http.Request {
+	if _, err := r.Cookie(visitorCookieName); err != nil {
+		return r
+	}
+
+	var kept []string
+	for _, cookie := range r.Cookies() {
+		if cookie.Name != visitorCookieName {
+			kept = append(kept, cookie.String())
+		}
+	}
+	if len(kept) == 0 {
+		return stripHeader(r, "Cookie")
+	}
+	r.Header.Set("Cookie", strings.Join(kept, "; "))
+	return r
+}
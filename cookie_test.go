@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// TestVisitorCookieKeepsFingerprint checks that a client sending back the
+// visitor cookie gets the fingerprint of its first request.
+func TestVisitorCookieKeepsFingerprint(t *testing.T) {
+	tests := []struct {
+		name string
+		// cookie is what the client sends besides the visitor cookie
+		cookie string
+	}{
+		{name: "visitor cookie only"},
+		{name: "other cookies", cookie: "theme=dark"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			s.cookie = true
+			request := func(visitor *http.Cookie) (*httptest.ResponseRecorder, string) {
+				r := httptest.NewRequest(http.MethodGet, "/fingerprint", nil)
+				r.RemoteAddr = "203.0.113.7:5000"
+				r.Header.Set("User-Agent", "Mozilla/5.0")
+				r.Header.Set("Accept", "*/*")
+				order := []string{"Host", "User-Agent", "Accept"}
+				if tt.cookie != "" {
+					r.Header.Set("Cookie", tt.cookie)
+				}
+				if visitor != nil {
+					r.AddCookie(visitor)
+				}
+				if r.Header.Get("Cookie") != "" {
+					order = append(order, "Cookie")
+				}
+				r = r.WithContext(fingerprint.WithHeaderOrder(r.Context(), order))
+				w := httptest.NewRecorder()
+				s.handleFingerprint(w, r)
+				var resp fingerprintResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatal(err)
+				}
+				return w, resp.Fingerprint
+			}
+
+			w, first := request(nil)
+			cookies := w.Result().Cookies()
+			if len(cookies) != 1 || cookies[0].Name != visitorCookieName {
+				t.Fatalf("first response set cookies %v", cookies)
+			}
+			_, returning := request(&http.Cookie{Name: visitorCookieName, Value: cookies[0].Value})
+			if first == "" || returning != first {
+				t.Errorf("returning fingerprint = %q, want the first %q", returning, first)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+//go:embed datacenters.txt
+var defaultDatacenters string
+
+// datacenterList maps hosting provider IP ranges and AS numbers to the
+// provider name. Like GeoIP, it is enrichment only and never feeds the
+// fingerprint hash.
+type datacenterList struct {
+	// A lookup masks the address once per distinct prefix length instead
+	// of scanning every range
+	prefixes map[netip.Prefix]string
+	lengths  []int
+	asns     map[uint]string
+}
+
+func newDatacenterList() *datacenterList {
+	return &datacenterList{
+		prefixes: make(map[netip.Prefix]string),
+		asns:     make(map[uint]string),
+	}
+}
+
+// load adds the ranges read from r. Each non-blank line that is not a #
+// comment is a CIDR or AS number followed by the provider name:
+//
+//	3.0.0.0/9 Amazon Web Services
+//	AS14061 DigitalOcean
+//
+// Later lines override earlier ones for the same range.
+func (d *datacenterList) load(name string, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, provider, _ := strings.Cut(text, " ")
+		provider = strings.TrimSpace(provider)
+		if provider == "" {
+			return fmt.Errorf("%s:%d: missing provider name", name, line)
+		}
+
+		if number, ok := strings.CutPrefix(strings.ToUpper(key), "AS"); ok {
+			asn, err := strconv.ParseUint(number, 10, 32)
+			if err != nil || asn == 0 {
+				return fmt.Errorf("%s:%d: invalid AS number %q", name, line, key)
+			}
+			d.asns[uint(asn)] = provider
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(key)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+		prefix = prefix.Masked()
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		d.prefixes[prefix] = provider
+		if !slices.Contains(d.lengths, prefix.Bits()) {
+			d.lengths = append(d.lengths, prefix.Bits())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read %s: %w", name, err)
+	}
+
+	// Most specific ranges are checked first
+	slices.Sort(d.lengths)
+	slices.Reverse(d.lengths)
+	return nil
+}
+
+// loadFile adds the ranges in the file at path.
+func (d *datacenterList) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return d.load(path, f)
+}
+
+// Lookup returns the hosting provider that ip belongs to, matching its
+// address ranges first and then asn, the AS number from a GeoIP ASN
+// database (0 if unknown). It returns "" for other addresses.
+func (d *datacenterList) Lookup(ip string, asn uint) string {
+	if d == nil {
+		return ""
+	}
+
+	if addr, err := netip.ParseAddr(ip); err == nil {
+		addr = addr.Unmap()
+		for _, length := range d.lengths {
+			// Lengths longer than the address family allows fail here
+			prefix, err := addr.Prefix(length)
+			if err != nil {
+				continue
+			}
+			if provider, ok := d.prefixes[prefix]; ok {
+				return provider
+			}
+		}
+	}
+	return d.asns[asn]
+}
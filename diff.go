@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// diffResponse lists the components that differ between two stored
+// fingerprints.
+type diffResponse struct {
+	A     string  `json:"fingerprint_a"`
+	B     string  `json:"fingerprint_b"`
+	Score float64 `json:"score"`
+	// Differences are the components whose latest stored values differ or
+	// that only one side has, sorted by name; a missing component has an
+	// empty value
+	Differences []fingerprint.Difference `json:"differences"`
+}
+
+// handleDiff compares the components last stored for the fingerprints a
+// and b of the query, such as two fingerprints a client had before and
+// after an update, so an analyst can see what changed without having the
+// requests. The components are those the store recorded, redacted as
+// they were persisted.
+func (s *server) handleDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+	query := r.URL.Query()
+	hashA, hashB := query.Get("a"), query.Get("b")
+	if hashA == "" || hashB == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: `both "a" and "b" are required`})
+		return
+	}
+
+	var components [2][]string
+	for i, hash := range []string{hashA, hashB} {
+		v, err := s.store.Get(r.Context(), hash)
+		if errors.Is(err, errNotFound) {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: fmt.Sprintf("fingerprint %s not found", hash)})
+			return
+		}
+		if err != nil {
+			slog.Error("failed to look up fingerprint", "fingerprint", hash, "error", err)
+			writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "fingerprint store unavailable"})
+			return
+		}
+		if len(v.Components) == 0 {
+			// Recorded before the store kept components
+			writeJSON(w, http.StatusConflict, errorResponse{Error: fmt.Sprintf("fingerprint %s has no stored components", hash)})
+			return
+		}
+		components[i] = v.Components
+	}
+
+	comparison := fingerprint.CompareComponents(components[0], components[1])
+	resp := diffResponse{A: hashA, B: hashB, Score: comparison.Score, Differences: comparison.Differences}
+	if resp.Differences == nil {
+		resp.Differences = []fingerprint.Difference{}
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"hash/maphash"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxEntropyValues bounds the number of distinct values tracked per
+	// signal, so high-cardinality signals such as the client IP cannot
+	// grow the table without limit.
+	maxEntropyValues = 1000
+
+	// entropyDecayInterval is how often counts are decayed.
+	entropyDecayInterval = time.Minute
+)
+
+// entropyTable estimates how identifying each fingerprint signal is from
+// how often its value has been seen. Counts decay exponentially so the
+// table follows the current traffic mix rather than all history.
+type entropyTable struct {
+	halfLife time.Duration
+	seed     maphash.Seed
+
+	mu        sync.Mutex
+	signals   map[string]*signalCounts
+	lastDecay time.Time
+}
+
+// signalCounts holds the decayed observation counts of one signal, keyed
+// by a hash of the value so long values such as User-Agents are not kept.
+type signalCounts struct {
+	total  float64
+	values map[uint64]float64
+}
+
+func newEntropyTable(halfLife time.Duration) *entropyTable {
+	return &entropyTable{
+		halfLife: halfLife,
+		seed:     maphash.MakeSeed(),
+		signals:  make(map[string]*signalCounts),
+	}
+}
+
+// Observe records the key:value components of a request and returns the
+// bits of entropy (surprisal) each signal contributed, -log2 of the share
+// of earlier requests with the same value, along with their sum. The sum
+// treats signals as independent, so it overestimates how identifying
+// correlated signals are.
+func (t *entropyTable) Observe(components []string, now time.Time) (float64, map[string]float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.decay(now)
+
+	var total float64
+	contributions := make(map[string]float64, len(components))
+	for _, component := range components {
+		name, value, _ := strings.Cut(component, ":")
+
+		counts, ok := t.signals[name]
+		if !ok {
+			counts = &signalCounts{values: make(map[uint64]float64)}
+			t.signals[name] = counts
+		}
+
+		key := maphash.String(t.seed, value)
+		bits := counts.surprisal(key)
+		if _, ok := counts.values[key]; !ok && len(counts.values) >= maxEntropyValues {
+			counts.evict()
+		}
+		counts.values[key]++
+		counts.total++
+
+		contributions[name] = math.Round(bits*100) / 100
+		total += bits
+	}
+	return math.Round(total*100) / 100, contributions
+}
+
+// surprisal returns the bits of entropy of the value with the given key,
+// from the counts before it is recorded: -log2 of its share of the
+// requests seen so far. A value not seen before gets the most a value
+// can: log2 of the number of values tracked, or of the requests so far
+// when there are more.
+func (c *signalCounts) surprisal(key uint64) float64 {
+	if count := c.values[key]; count > 0 {
+		return math.Log2(c.total / count)
+	}
+	return math.Log2(max(c.total+1, maxEntropyValues))
+}
+
+// decay scales every count by the time elapsed since the last decay, and
+// drops values whose count has faded to nothing.
+func (t *entropyTable) decay(now time.Time) {
+	if t.lastDecay.IsZero() {
+		t.lastDecay = now
+		return
+	}
+	elapsed := now.Sub(t.lastDecay)
+	if elapsed < entropyDecayInterval {
+		return
+	}
+	t.lastDecay = now
+
+	factor := math.Exp2(-elapsed.Seconds() / t.halfLife.Seconds())
+	for name, counts := range t.signals {
+		counts.total *= factor
+		for key, count := range counts.values {
+			count *= factor
+			if count < 0.01 {
+				delete(counts.values, key)
+				continue
+			}
+			counts.values[key] = count
+		}
+		if len(counts.values) == 0 {
+			delete(t.signals, name)
+		}
+	}
+}
+
+// evict makes room for a new value by dropping values seen less than
+// twice. If the signal is still at capacity, arbitrary values are dropped
+// until it is back under 90%. Evicted counts stay in the total, so other
+// values keep their share.
+func (c *signalCounts) evict() {
+	for key, count := range c.values {
+		if count < 2 {
+			delete(c.values, key)
+		}
+	}
+	for key := range c.values {
+		if len(c.values) < maxEntropyValues*9/10 {
+			break
+		}
+		delete(c.values, key)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEntropyObserve(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxBits := math.Round(math.Log2(maxEntropyValues)*100) / 100
+	tests := []struct {
+		name     string
+		earlier  []string
+		value    string
+		wantBits float64
+	}{
+		{name: "first sighting", value: "ua:a", wantBits: maxBits},
+		{name: "new value", earlier: []string{"ua:a", "ua:a", "ua:a"}, value: "ua:b", wantBits: maxBits},
+		{name: "every earlier request", earlier: []string{"ua:a", "ua:a"}, value: "ua:a", wantBits: 0},
+		{name: "half of earlier requests", earlier: []string{"ua:a", "ua:b"}, value: "ua:a", wantBits: 1},
+		{name: "quarter of earlier requests", earlier: []string{"ua:a", "ua:b", "ua:b", "ua:b"}, value: "ua:a", wantBits: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := newEntropyTable(time.Hour)
+			for _, value := range tt.earlier {
+				table.Observe([]string{value}, now)
+			}
+			total, contributions := table.Observe([]string{tt.value}, now)
+			if total != tt.wantBits || contributions["ua"] != tt.wantBits {
+				t.Errorf("Observe = %v, %v; want %v bits", total, contributions, tt.wantBits)
+			}
+		})
+	}
+}
+
+// TestEntropyNewValueAfterManyRequests checks that once more requests have
+// been seen than values are tracked, a new value is still the most
+// surprising one.
+func TestEntropyNewValueAfterManyRequests(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	table := newEntropyTable(time.Hour)
+	for range 2047 {
+		table.Observe([]string{"ua:a"}, now)
+	}
+	if total, _ := table.Observe([]string{"ua:b"}, now); total != 11 {
+		t.Errorf("new value after 2047 requests = %v bits, want 11", total)
+	}
+}
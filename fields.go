@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// fingerprintFields lists the JSON fields of fingerprintResponse in the
+// order they are encoded.
+var fingerprintFields = jsonFieldNames(reflect.TypeFor[fingerprintResponse]())
+
+// jsonFieldNames returns the JSON names of the exported fields of the
+// struct type t.
+func jsonFieldNames(t reflect.Type) []string {
+	var names []string
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// fieldSet is the set of response fields a request selected with
+// ?fields=. A nil set selects every field.
+type fieldSet map[string]bool
+
+// parseFields parses the comma-separated ?fields= of r, which may also be
+// repeated, and returns the selected fields and any unknown ones, which
+// are ignored. It returns a nil set when the parameter is absent.
+func parseFields(r *http.Request) (fields fieldSet, unknown []string) {
+	values, ok := r.URL.Query()["fields"]
+	if !ok {
+		return nil, nil
+	}
+
+	fields = make(fieldSet)
+	for _, value := range values {
+		for name := range strings.SplitSeq(value, ",") {
+			name = strings.TrimSpace(name)
+			switch {
+			case name == "":
+			case slices.Contains(fingerprintFields, name):
+				fields[name] = true
+			default:
+				unknown = append(unknown, name)
+			}
+		}
+	}
+	return fields, unknown
+}
+
+// wants reports whether any of the named fields is selected, so that
+// enrichment feeding only unselected fields can be skipped.
+func (f fieldSet) wants(names ...string) bool {
+	if f == nil {
+		return true
+	}
+	for _, name := range names {
+		if f[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// with returns f with the named fields also selected. It returns nil, which
+// selects every field, when f is nil.
+func (f fieldSet) with(names ...string) fieldSet {
+	if f == nil || len(names) == 0 {
+		return f
+	}
+	fields := maps.Clone(f)
+	for _, name := range names {
+		fields[name] = true
+	}
+	return fields
+}
+
+// selectFields parses ?fields= like parseFields and reports unknown fields
+// in a Warning header on w.
+func selectFields(w http.ResponseWriter, r *http.Request) fieldSet {
+	fields, unknown := parseFields(r)
+	if len(unknown) > 0 {
+		w.Header().Set("Warning", "299 - "+strconv.Quote("unknown fields ignored: "+strings.Join(unknown, ", ")))
+	}
+	return fields
+}
+
+// filter keeps only the selected fields of the encoded JSON object body,
+// in the order of fingerprintFields.
+func (f fieldSet) filter(body []byte) ([]byte, error) {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(body, &values); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, name := range fingerprintFields {
+		value, ok := values[name]
+		if !ok || !f[name] {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(strconv.Quote(name))
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
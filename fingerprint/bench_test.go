@@ -0,0 +1,58 @@
+package fingerprint
+
+import (
+	"testing"
+)
+
+// benchmarkFixture returns the Chrome-like HTTP/2 request of the self-test
+// fixtures, the shape of most real traffic.
+func benchmarkFixture(b *testing.B) SelfTestFixture {
+	b.Helper()
+	for _, fixture := range selfTestFixtures {
+		if fixture.Name == "chrome-h2" {
+			return fixture
+		}
+	}
+	b.Fatal("no chrome-h2 self-test fixture")
+	return SelfTestFixture{}
+}
+
+func BenchmarkGenerate(b *testing.B) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{name: "default"},
+		{name: "salted", config: Config{Salt: "benchmark-salt"}},
+	}
+	for _, tt := range tests {
+		b.Run(tt.name, func(b *testing.B) {
+			data, _ := tt.config.FromRequest(benchmarkFixture(b).Request())
+			b.ReportAllocs()
+			for b.Loop() {
+				tt.config.Generate(data)
+			}
+		})
+	}
+}
+
+func BenchmarkGenerateStable(b *testing.B) {
+	var c Config
+	data, _ := c.FromRequest(benchmarkFixture(b).Request())
+	b.ReportAllocs()
+	for b.Loop() {
+		c.GenerateStable(data)
+	}
+}
+
+func BenchmarkFromRequest(b *testing.B) {
+	var c Config
+	fixture := benchmarkFixture(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		b.StopTimer()
+		r := fixture.Request()
+		b.StartTimer()
+		c.FromRequest(r)
+	}
+}
@@ -0,0 +1,62 @@
+package fingerprint
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestBodySignalsContentType(t *testing.T) {
+	tests := []struct {
+		name         string
+		contentTypes []string
+		// wantShape is set when the boundary shape must be found
+		wantShape bool
+	}{
+		{"chrome multipart", []string{"multipart/form-data; boundary=----WebKitFormBoundaryABcd1234EFgh5678"}, true},
+		{"quoted boundary", []string{`multipart/form-data; boundary="a b c"`}, true},
+		{"malformed parameters", []string{"multipart/form-data; boundary=abc; =x"}, false},
+		{"not multipart", []string{"application/json; boundary=abc"}, false},
+		{"repeated header", []string{"multipart/form-data; boundary=abc", "text/plain"}, true},
+		{"empty", []string{""}, false},
+	}
+	for _, tt := range tests {
+		for _, config := range []Config{
+			{BodySignals: true},
+			{BodySignals: true, NormalizeHeaders: true},
+			{BodySignals: true, CanonicalHeaders: true, Headers: []string{"Content-Type"}},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				r, _ := http.NewRequest(http.MethodPost, "http://example.com/upload", nil)
+				r.RemoteAddr = "203.0.113.7:5000"
+				for _, value := range tt.contentTypes {
+					r.Header.Add("Content-Type", value)
+				}
+				data, _ := config.FromRequest(r)
+				if got := data.MultipartBoundary != ""; got != tt.wantShape {
+					t.Errorf("boundary shape %q for %q, want one: %v", data.MultipartBoundary, tt.contentTypes, tt.wantShape)
+				}
+			})
+		}
+	}
+}
+
+// TestMultipartBoundaryHash checks that the random part of a multipart
+// boundary does not reach the hash.
+func TestMultipartBoundaryHash(t *testing.T) {
+	config := Config{BodySignals: true}
+	hash := func(contentType string) string {
+		r, _ := http.NewRequest(http.MethodPost, "http://example.com/upload", nil)
+		r.RemoteAddr = "203.0.113.7:5000"
+		r.Header.Set("Content-Type", contentType)
+		_, h := config.FromRequest(r)
+		return h
+	}
+	a := hash("multipart/form-data; boundary=----WebKitFormBoundaryABcd1234EFgh5678")
+	b := hash("multipart/form-data; boundary=----WebKitFormBoundaryZZyy9876XXww5432")
+	if a != b {
+		t.Errorf("boundaries of one client hash differently: %s, %s", a, b)
+	}
+	if c := hash("multipart/form-data; boundary=---------------------------123456789012345678901234567"); c == a {
+		t.Error("boundaries of different clients hash the same")
+	}
+}
@@ -0,0 +1,179 @@
+package fingerprint
+
+import (
+	"strconv"
+	"strings"
+)
+
+// BotScore is the result of the bot heuristics: a score from 0 (consistent
+// with a real browser) to 100 (almost certainly automated), and the names
+// of the rules that fired.
+type BotScore struct {
+	Score int
+	Rules []string
+}
+
+// botSignals is the view of a request that bot heuristics inspect.
+type botSignals struct {
+	data      Data
+	ua        UserAgent
+	major     int
+	secure    bool
+	isBrowser bool
+}
+
+// header returns a fingerprinted header by lower-cased name.
+func (s botSignals) header(name string) string {
+	return s.data.Headers[name]
+}
+
+// botHeuristic is one rule; weight is added to the score when check fires.
+type botHeuristic struct {
+	name   string
+	weight int
+	check  func(s botSignals) bool
+}
+
+// botHeuristics are evaluated in order. Most compare what the User-Agent
+// claims against headers that the claimed browser always or never sends.
+var botHeuristics = []botHeuristic{
+	{"missing-user-agent", 40, func(s botSignals) bool {
+		return s.data.UserAgent == ""
+	}},
+	{"bot-user-agent", 60, func(s botSignals) bool {
+		return s.ua.Device == DeviceBot
+	}},
+	{"browser-without-accept", 15, func(s botSignals) bool {
+		return s.isBrowser && s.data.Accept == ""
+	}},
+	{"browser-without-accept-language", 20, func(s botSignals) bool {
+		return s.isBrowser && s.data.AcceptLang == ""
+	}},
+	// Chromium has sent Sec-Ch-Ua since version 89, but only to secure origins
+	{"chromium-without-client-hints", 30, func(s botSignals) bool {
+		return isChromium(s.ua) && s.major >= 89 && s.secure && s.header("sec-ch-ua") == ""
+	}},
+	{"client-hints-from-non-chromium", 30, func(s botSignals) bool {
+		return s.isBrowser && !isChromium(s.ua) && s.header("sec-ch-ua") != ""
+	}},
+	{"client-hints-mobile-mismatch", 25, func(s botSignals) bool {
+		switch s.header("sec-ch-ua-mobile") {
+		case "?1":
+			return s.ua.Device == DeviceDesktop
+		case "?0":
+			return s.ua.Device == DeviceMobile
+		}
+		return false
+	}},
+	{"client-hints-platform-mismatch", 25, func(s botSignals) bool {
+		platform := strings.Trim(s.header("sec-ch-ua-platform"), `"`)
+		return platform != "" && s.ua.OS != "" && !platformMatchesOS(platform, s.ua.OS)
+	}},
+	// Chrome 76+, Firefox 90+, and Safari 16.4+ send Sec-Fetch-* to secure origins
+	{"browser-without-sec-fetch", 20, func(s botSignals) bool {
+		return s.secure && sendsSecFetch(s.ua, s.major) && s.header("sec-fetch-mode") == ""
+	}},
+	{"sec-fetch-from-non-browser", 20, func(s botSignals) bool {
+		return !s.isBrowser && s.header("sec-fetch-mode") != ""
+	}},
+	// Proxies agree on the address they saw; a client wrote the one that
+	// disagrees to spoof its own
+	{"ip-header-conflict", 25, func(s botSignals) bool {
+		return s.data.IPHeaderConflict
+	}},
+	// Browsers keep HTTP/1.1 connections alive and say so in lower case;
+	// HTTP libraries close them or write Keep-Alive. Upgrades add options.
+	{"browser-transport-mismatch", 20, func(s botSignals) bool {
+		return s.isBrowser && s.data.Transport != "" && s.data.WebSocketKey == "" &&
+			NormalizeProtocol(s.data.Protocol) == ProtocolHTTP11 &&
+			transportPart(s.data.Transport, "connection") != "keep-alive/lower"
+	}},
+	// HTTP/2 and HTTP/3 forbid connection-specific headers, and TE other
+	// than trailers, so only a hand-built client sends them
+	{"hop-by-hop-over-multiplexed", 30, func(s botSignals) bool {
+		switch NormalizeProtocol(s.data.Protocol) {
+		case ProtocolHTTP2, ProtocolHTTP3:
+			t := s.data.Transport
+			return t != "" && t != "none" && t != "te=trailers"
+		}
+		return false
+	}},
+}
+
+// ScoreBot applies the bot heuristics to data and returns the combined
+// score, capped at 100. Rules look for inconsistencies between the claimed
+// User-Agent and the other headers, such as a Chrome User-Agent without
+// the client hints Chrome always sends.
+func ScoreBot(data Data) BotScore {
+	ua := ParseUserAgent(data.UserAgent)
+	signals := botSignals{
+		data:      data,
+		ua:        ua,
+		major:     majorVersion(ua.BrowserVersion),
+		isBrowser: ua.Browser != "" && ua.Device != DeviceBot,
+		secure: data.TLSVersion != "" ||
+			strings.EqualFold(data.Headers["x-forwarded-proto"], "https"),
+	}
+
+	var result BotScore
+	for _, rule := range botHeuristics {
+		if rule.check(signals) {
+			result.Score += rule.weight
+			result.Rules = append(result.Rules, rule.name)
+		}
+	}
+	result.Score = min(result.Score, 100)
+	return result
+}
+
+func isChromium(ua UserAgent) bool {
+	return ua.Engine == "Blink" && ua.Device != DeviceBot
+}
+
+func sendsSecFetch(ua UserAgent, major int) bool {
+	switch {
+	case ua.Device == DeviceBot:
+		return false
+	case ua.Engine == "Blink":
+		return major >= 76
+	case ua.Browser == "Firefox" && ua.Engine == "Gecko":
+		return major >= 90
+	case ua.Browser == "Safari":
+		return major >= 17 || major == 16 && minorVersion(ua.BrowserVersion) >= 4
+	}
+	return false
+}
+
+// platformMatchesOS reports whether a Sec-Ch-Ua-Platform value agrees with
+// the OS parsed from the User-Agent.
+func platformMatchesOS(platform, os string) bool {
+	switch platform {
+	case "Windows":
+		return os == "Windows"
+	case "macOS":
+		return os == "macOS"
+	case "Android":
+		return os == "Android"
+	case "Chrome OS", "Chromium OS":
+		return os == "Chrome OS"
+	case "iOS":
+		return os == "iOS"
+	case "Linux":
+		return os == "Linux" || os == "Android"
+	}
+	// Unknown platforms are not held against the client
+	return true
+}
+
+func majorVersion(version string) int {
+	major, _, _ := strings.Cut(version, ".")
+	n, _ := strconv.Atoi(major)
+	return n
+}
+
+func minorVersion(version string) int {
+	_, rest, _ := strings.Cut(version, ".")
+	minor, _, _ := strings.Cut(rest, ".")
+	n, _ := strconv.Atoi(minor)
+	return n
+}
@@ -0,0 +1,216 @@
+package fingerprint
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// Brand is one entry of the Sec-Ch-Ua or Sec-Ch-Ua-Full-Version-List
+// client hints, as in "Google Chrome";v="120.0.6099.71".
+type Brand struct {
+	Brand   string `json:"brand"`
+	Version string `json:"version"`
+}
+
+// brandHeaders are the client hints holding a brand list.
+var brandHeaders = []string{"sec-ch-ua", "sec-ch-ua-full-version-list"}
+
+// ParseBrands parses a Sec-Ch-Ua or Sec-Ch-Ua-Full-Version-List header, a
+// Structured Field list of strings with a v parameter (RFC 8941), and
+// returns its brands in header order without GREASE entries. ok is false
+// when header is not a valid list of strings.
+func ParseBrands(header string) (brands []Brand, ok bool) {
+	p := sfParser{s: header}
+	p.skipSpace()
+	for !p.done() {
+		brand, ok := p.string()
+		if !ok {
+			return nil, false
+		}
+		var version string
+		for p.consume(';') {
+			p.skipSpace()
+			name, value, ok := p.param()
+			if !ok {
+				return nil, false
+			}
+			if name == "v" {
+				version = value
+			}
+		}
+		if !isGREASEBrand(brand) {
+			brands = append(brands, Brand{Brand: brand, Version: version})
+		}
+
+		p.skipSpace()
+		if p.done() {
+			break
+		}
+		if !p.consume(',') {
+			return nil, false
+		}
+		p.skipSpace()
+		if p.done() {
+			return nil, false
+		}
+	}
+	return brands, true
+}
+
+// CanonicalBrands returns a brand list header without its GREASE entries
+// and with the brands sorted, serialized as
+// "Chromium";v="120", "Google Chrome";v="120". Chromium picks the GREASE
+// brand and the brand order per release, so they carry no information
+// beyond the version. A header that does not parse is returned unchanged.
+func CanonicalBrands(header string) string {
+	brands, ok := ParseBrands(header)
+	if !ok {
+		return header
+	}
+	slices.SortFunc(brands, func(a, b Brand) int {
+		return cmp.Or(cmp.Compare(a.Brand, b.Brand), cmp.Compare(a.Version, b.Version))
+	})
+
+	var b strings.Builder
+	for i, brand := range brands {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		writeSFString(&b, brand.Brand)
+		if brand.Version != "" {
+			b.WriteString(";v=")
+			writeSFString(&b, brand.Version)
+		}
+	}
+	return b.String()
+}
+
+// canonicalizeBrands applies CanonicalBrands to the brand list client
+// hints in data.
+func canonicalizeBrands(data *Data) {
+	for _, name := range brandHeaders {
+		if value, ok := data.Headers[name]; ok {
+			data.Headers[name] = CanonicalBrands(value)
+		}
+	}
+}
+
+// isGREASEBrand reports whether brand is one of the made-up brands
+// Chromium adds to discourage parsers from depending on the list, such as
+// "Not_A Brand", "Not A(Brand", or " Not;A Brand": the words Not, A, and
+// Brand separated by punctuation or spaces.
+func isGREASEBrand(brand string) bool {
+	letters := strings.Map(func(r rune) rune {
+		if 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' {
+			return r
+		}
+		return -1
+	}, brand)
+	return strings.EqualFold(letters, "NotABrand")
+}
+
+// writeSFString writes s as a Structured Field string.
+func writeSFString(b *strings.Builder, s string) {
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	b.WriteByte('"')
+}
+
+// sfParser parses the subset of Structured Field Values that brand lists
+// use: strings with parameters whose values are strings, tokens, numbers,
+// or booleans.
+type sfParser struct {
+	s   string
+	pos int
+}
+
+func (p *sfParser) done() bool { return p.pos >= len(p.s) }
+
+func (p *sfParser) skipSpace() {
+	for !p.done() && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *sfParser) consume(c byte) bool {
+	if !p.done() && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// string parses a quoted string, which may escape only '"' and '\' and
+// must be printable ASCII.
+func (p *sfParser) string() (string, bool) {
+	if !p.consume('"') {
+		return "", false
+	}
+	var b strings.Builder
+	for !p.done() {
+		c := p.s[p.pos]
+		p.pos++
+		switch {
+		case c == '"':
+			return b.String(), true
+		case c == '\\':
+			if p.done() || p.s[p.pos] != '"' && p.s[p.pos] != '\\' {
+				return "", false
+			}
+			b.WriteByte(p.s[p.pos])
+			p.pos++
+		case c < 0x20 || c > 0x7e:
+			return "", false
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", false
+}
+
+// param parses a parameter key and its optional value, which defaults to
+// the boolean true.
+func (p *sfParser) param() (name, value string, ok bool) {
+	start := p.pos
+	for !p.done() && isSFKeyChar(p.s[p.pos], p.pos == start) {
+		p.pos++
+	}
+	name = p.s[start:p.pos]
+	if name == "" {
+		return "", "", false
+	}
+	if !p.consume('=') {
+		return name, "?1", true
+	}
+	if !p.done() && p.s[p.pos] == '"' {
+		value, ok = p.string()
+		return name, value, ok
+	}
+
+	// A token, number, or boolean; their characters never include the
+	// delimiters that end a parameter
+	start = p.pos
+	for !p.done() && !strings.ContainsRune(" \t,;\"", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	value = p.s[start:p.pos]
+	return name, value, value != ""
+}
+
+// isSFKeyChar reports whether c may appear in a parameter key, which
+// starts with a lowercase letter or '*'.
+func isSFKeyChar(c byte, first bool) bool {
+	switch {
+	case 'a' <= c && c <= 'z', c == '*':
+		return true
+	case first:
+		return false
+	}
+	return '0' <= c && c <= '9' || c == '_' || c == '-' || c == '.'
+}
@@ -0,0 +1,100 @@
+package fingerprint
+
+import (
+	"container/list"
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+)
+
+// HashCache is a fixed-size LRU cache of fingerprint hashes keyed by a
+// cheap 128-bit pre-hash of the encoded components, so repeated identical
+// requests skip the digest without the cache keeping their components. It
+// is safe for concurrent use. Set it as Config.Cache.
+type HashCache struct {
+	size  int
+	seeds [2]maphash.Seed
+
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element
+	order   *list.List
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// cacheKey is two maphash sums of the same input under independent random
+// seeds. Inputs that collide in both are vanishingly unlikely, and as the
+// seeds are made per process a client cannot search for a collision.
+type cacheKey [2]uint64
+
+type cacheEntry struct {
+	key  cacheKey
+	hash string
+}
+
+// NewHashCache returns a cache holding up to size fingerprints.
+func NewHashCache(size int) *HashCache {
+	return &HashCache{
+		size:    size,
+		seeds:   [2]maphash.Seed{maphash.MakeSeed(), maphash.MakeSeed()},
+		entries: make(map[cacheKey]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+// Stats returns the number of cache hits and misses so far.
+func (c *HashCache) Stats() (hits, misses uint64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// key returns the pre-hash of input under the algorithm and salt of cfg.
+// The algorithm and salt are part of the key so configs using different
+// ones can share a cache.
+func (c *HashCache) key(cfg *Config, input []byte) cacheKey {
+	var key cacheKey
+	for i, seed := range c.seeds {
+		var pre maphash.Hash
+		pre.SetSeed(seed)
+		pre.WriteString(string(cfg.Hash))
+		pre.WriteByte(0)
+		pre.WriteString(cfg.Salt)
+		pre.WriteByte(0)
+		pre.Write(input)
+		key[i] = pre.Sum64()
+	}
+	return key
+}
+
+// hash returns the cached hash of input under the algorithm and salt of
+// cfg, computing it with cfg.digest on a miss.
+func (c *HashCache) hash(cfg *Config, input []byte) string {
+	key := c.key(cfg, input)
+
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		hash := elem.Value.(*cacheEntry).hash
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return hash
+	}
+	c.mu.Unlock()
+
+	c.misses.Add(1)
+	hash := cfg.digest(input)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		return hash
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, hash: hash})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+	return hash
+}
@@ -0,0 +1,81 @@
+package fingerprint
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestHashCache(t *testing.T) {
+	cache := NewHashCache(2)
+	tests := []struct {
+		name   string
+		config Config
+		input  string
+		hit    bool
+	}{
+		{name: "miss", input: "a"},
+		{name: "hit", input: "a", hit: true},
+		{name: "salt is part of the key", config: Config{Salt: "s"}, input: "a"},
+		{name: "algorithm is part of the key", config: Config{Hash: HashSHA1}, input: "a"},
+		{name: "least recently used is evicted", input: "a"},
+		{name: "most recently used is kept", config: Config{Hash: HashSHA1}, input: "a", hit: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Cache = cache
+			hits, _ := cache.Stats()
+			got := tt.config.hash([]byte(tt.input))
+			if want := tt.config.digest([]byte(tt.input)); got != want {
+				t.Errorf("hash = %q, want %q", got, want)
+			}
+			if after, _ := cache.Stats(); (after > hits) != tt.hit {
+				t.Errorf("hit = %v, want %v", after > hits, tt.hit)
+			}
+		})
+	}
+}
+
+func BenchmarkHash(b *testing.B) {
+	tests := []struct {
+		name  string
+		cache *HashCache
+	}{
+		{name: "uncached"},
+		{name: "cached", cache: NewHashCache(1000)},
+	}
+	for _, tt := range tests {
+		b.Run(tt.name, func(b *testing.B) {
+			c := Config{Cache: tt.cache}
+			data, _ := c.FromRequest(benchmarkFixture(b).Request())
+			b.ReportAllocs()
+			for b.Loop() {
+				c.Generate(data)
+			}
+		})
+	}
+}
+
+// TestHashCacheConcurrent shares a cache smaller than the working set
+// between goroutines, so hits, misses, and evictions race each other. Run
+// it with -race.
+func TestHashCacheConcurrent(t *testing.T) {
+	cache := NewHashCache(8)
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Go(func() {
+			c := Config{Cache: cache, Salt: strconv.Itoa(g % 2)}
+			for i := range 200 {
+				input := []byte(strconv.Itoa(i % 16))
+				if got, want := c.hash(input), c.digest(input); got != want {
+					t.Errorf("hash = %q, want %q", got, want)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+	if hits, misses := cache.Stats(); hits+misses != 8*200 {
+		t.Errorf("hits %d + misses %d, want %d lookups", hits, misses, 8*200)
+	}
+}
@@ -0,0 +1,224 @@
+package fingerprint
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// headerCanonicalization lists the rule CanonicalHeader applies to each
+// header. Headers not listed have their whitespace collapsed by
+// collapseSpace.
+var headerCanonicalization = map[string]func(string) string{
+	// Content negotiation lists
+	"accept":          CanonicalPreferences,
+	"accept-charset":  CanonicalPreferences,
+	"accept-encoding": CanonicalPreferences,
+	"accept-language": canonicalLanguages,
+
+	// Brand lists
+	"sec-ch-ua":                   CanonicalBrands,
+	"sec-ch-ua-full-version-list": CanonicalBrands,
+
+	// Structured Field strings
+	"sec-ch-ua-arch":             canonicalSFString,
+	"sec-ch-ua-bitness":          canonicalSFString,
+	"sec-ch-ua-full-version":     canonicalSFString,
+	"sec-ch-ua-model":            canonicalSFString,
+	"sec-ch-ua-platform":         canonicalSFString,
+	"sec-ch-ua-platform-version": canonicalSFString,
+
+	// Case-insensitive tokens, including Structured Field booleans
+	"dnt":                           canonicalToken,
+	"ect":                           canonicalToken,
+	"save-data":                     canonicalToken,
+	"sec-ch-prefers-color-scheme":   canonicalToken,
+	"sec-ch-prefers-reduced-motion": canonicalToken,
+	"sec-ch-ua-mobile":              canonicalToken,
+	"sec-ch-ua-wow64":               canonicalToken,
+	"sec-fetch-dest":                canonicalToken,
+	"sec-fetch-mode":                canonicalToken,
+	"sec-fetch-site":                canonicalToken,
+	"sec-fetch-user":                canonicalToken,
+	"upgrade-insecure-requests":     canonicalToken,
+
+	// Unordered lists of case-insensitive directives
+	"cache-control": canonicalDirectives,
+	"connection":    canonicalDirectives,
+	"pragma":        canonicalDirectives,
+	"priority":      canonicalDirectives,
+	"te":            canonicalDirectives,
+
+	// Numbers
+	"device-memory":          canonicalNumber,
+	"downlink":               canonicalNumber,
+	"dpr":                    canonicalNumber,
+	"rtt":                    canonicalNumber,
+	"sec-ch-device-memory":   canonicalNumber,
+	"sec-ch-dpr":             canonicalNumber,
+	"sec-ch-viewport-height": canonicalNumber,
+	"sec-ch-viewport-width":  canonicalNumber,
+	"viewport-width":         canonicalNumber,
+	"width":                  canonicalNumber,
+}
+
+// CanonicalHeader returns value in the canonical form of the named header,
+// so that spellings a client or proxy may produce for the same value hash
+// the same. The rule depends on the header:
+//
+//   - Accept, Accept-Charset, and Accept-Encoding are rewritten by
+//     CanonicalPreferences.
+//   - Accept-Language is parsed as by ParseAcceptLanguage and written
+//     with BCP 47 tag case, by descending q-value but otherwise in header
+//     order, without whitespace or a redundant q=1.
+//   - Sec-Ch-Ua and Sec-Ch-Ua-Full-Version-List are rewritten by
+//     CanonicalBrands.
+//   - The string client hints, such as Sec-Ch-Ua-Platform, are written as
+//     a quoted Structured Field string, quoting a bare token.
+//   - Token headers, such as Sec-Fetch-Site, DNT, and the boolean
+//     Sec-Ch-Ua-Mobile, are trimmed and lowercased.
+//   - Cache-Control, Connection, Pragma, and TE are lowercased outside
+//     quoted strings, stripped of whitespace around separators, sorted,
+//     de-duplicated, and joined with ", ".
+//   - Numeric hints, such as Sec-Ch-Dpr and Viewport-Width, are written
+//     in their shortest decimal form, so "1.50" becomes "1.5".
+//
+// Any other header, and a value its rule cannot parse, is trimmed and has
+// each run of spaces and tabs collapsed to a single space. Header names
+// are matched case-insensitively.
+func CanonicalHeader(name, value string) string {
+	if rule, ok := headerCanonicalization[strings.ToLower(name)]; ok {
+		return rule(value)
+	}
+	return collapseSpace(value)
+}
+
+// canonicalizeHeaders applies CanonicalHeader to the headers in data and
+// lowercases the header names in data.HeaderOrder.
+func canonicalizeHeaders(data *Data) {
+	data.UserAgent = CanonicalHeader("User-Agent", data.UserAgent)
+	data.Accept = CanonicalHeader("Accept", data.Accept)
+	data.AcceptEnc = CanonicalHeader("Accept-Encoding", data.AcceptEnc)
+	data.AcceptLang = CanonicalHeader("Accept-Language", data.AcceptLang)
+	for name, value := range data.Headers {
+		data.Headers[name] = CanonicalHeader(name, value)
+	}
+
+	if len(data.HeaderOrder) > 0 {
+		order := make([]string, len(data.HeaderOrder))
+		for i, name := range data.HeaderOrder {
+			order[i] = strings.ToLower(name)
+		}
+		data.HeaderOrder = order
+	}
+}
+
+// foldHeaderNames returns headers keyed by lower-cased name, joining the
+// values of names that differ only in case with ", " in name order.
+func foldHeaderNames(headers map[string]string) map[string]string {
+	folded := make(map[string]string, len(headers))
+	for _, name := range slices.Sorted(maps.Keys(headers)) {
+		key := strings.ToLower(name)
+		if existing, ok := folded[key]; ok {
+			folded[key] = existing + ", " + headers[name]
+		} else {
+			folded[key] = headers[name]
+		}
+	}
+	return folded
+}
+
+// canonicalLanguages rewrites an Accept-Language header as described by
+// CanonicalHeader.
+func canonicalLanguages(header string) string {
+	languages := ParseAcceptLanguage(header)
+	elements := make([]string, len(languages))
+	for i, lang := range languages {
+		elements[i] = lang.Tag
+		if lang.Quality != 1 {
+			elements[i] += ";q=" + strconv.FormatFloat(lang.Quality, 'f', -1, 64)
+		}
+	}
+	return strings.Join(elements, ",")
+}
+
+// canonicalSFString rewrites a Structured Field string, or a bare token,
+// as a quoted string.
+func canonicalSFString(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ""
+	}
+	s := value
+	if value[0] == '"' {
+		p := sfParser{s: value}
+		var ok bool
+		if s, ok = p.string(); !ok || !p.done() {
+			return collapseSpace(value)
+		}
+	} else if strings.ContainsAny(value, " \t\",;\\") {
+		return collapseSpace(value)
+	}
+
+	var b strings.Builder
+	writeSFString(&b, s)
+	return b.String()
+}
+
+// canonicalToken trims and lowercases a single token.
+func canonicalToken(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+// canonicalDirectives rewrites an unordered list of case-insensitive
+// directives as described by CanonicalHeader.
+func canonicalDirectives(value string) string {
+	var elements []string
+	for _, element := range splitQuoted(value, ',') {
+		key, val, hasValue := cutQuoted(element, '=')
+		element = lowerUnquoted(strings.TrimSpace(key))
+		if hasValue {
+			element += "=" + lowerUnquoted(strings.TrimSpace(val))
+		}
+		if element != "" {
+			elements = append(elements, element)
+		}
+	}
+	slices.Sort(elements)
+	return strings.Join(slices.Compact(elements), ", ")
+}
+
+// canonicalNumber rewrites a non-negative decimal in its shortest form.
+func canonicalNumber(value string) string {
+	trimmed := strings.TrimSpace(value)
+	n, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil || n < 0 || strings.ContainsAny(trimmed, "eEnNxXpP_") {
+		return collapseSpace(value)
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// collapseSpace trims s and replaces each run of spaces and tabs with a
+// single space, undoing the folding and padding some proxies apply.
+func collapseSpace(s string) string {
+	s = strings.Trim(s, " \t")
+	if !strings.Contains(s, "  ") && !strings.ContainsRune(s, '\t') {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	space := false
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c == ' ' || c == '\t' {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,239 @@
+package fingerprint
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// ParseTrustedProxies parses a list of CIDR prefixes or bare IP addresses
+// into prefixes suitable for Config.TrustedProxies. Bare addresses are
+// treated as single-host prefixes.
+func ParseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		if strings.Contains(value, "/") {
+			prefix, err := netip.ParsePrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// IPHeader names the header the trusted proxies write the client IP to.
+type IPHeader string
+
+// Supported client IP headers. X-Forwarded-For is the default, since most
+// proxies append to it.
+const (
+	IPHeaderXForwardedFor IPHeader = "x-forwarded-for"
+	IPHeaderForwarded     IPHeader = "forwarded"
+	IPHeaderXRealIP       IPHeader = "x-real-ip"
+)
+
+// ParseIPHeader returns the client IP header with the given name, matched
+// case-insensitively.
+func ParseIPHeader(name string) (IPHeader, error) {
+	switch header := IPHeader(strings.ToLower(strings.TrimSpace(name))); header {
+	case IPHeaderXForwardedFor, IPHeaderForwarded, IPHeaderXRealIP:
+		return header, nil
+	default:
+		return "", fmt.Errorf("unknown client IP header %q (want x-forwarded-for, forwarded, or x-real-ip)", name)
+	}
+}
+
+// ExtractIPAddress returns the client IP address for r, believing the
+// X-Forwarded-For header of the trusted proxies. Use Config.ClientIP for
+// proxies that write another header.
+func ExtractIPAddress(r *http.Request, trusted []netip.Prefix) string {
+	ip, _ := ExtractIPChain(r, trusted)
+	return ip
+}
+
+// ExtractIPChain returns the client IP address for r, as ExtractIPAddress
+// does, together with the chain it was resolved from. Use
+// Config.ClientIPChain for proxies that write another header.
+func ExtractIPChain(r *http.Request, trusted []netip.Prefix) (string, []string) {
+	c := Config{TrustedProxies: trusted}
+	return c.ClientIPChain(r)
+}
+
+// ClientIP returns the client IP address for r. Forwarding headers are
+// only honored when the connection comes from one of the trusted proxies;
+// otherwise the connection's remote address is returned so clients cannot
+// spoof their address by sending the headers themselves.
+//
+// Only ClientIPHeader is read, since it is the one the proxies write. The
+// others arrive as the client sent them, so a client could pick its own
+// address with a Forwarded header that a proxy appending to
+// X-Forwarded-For passes on untouched. When a forwarding chain is honored,
+// it is walked from right to left, skipping trusted proxies, and the
+// right-most untrusted address is returned.
+func (c *Config) ClientIP(r *http.Request) string {
+	ip, _ := c.ClientIPChain(r)
+	return ip
+}
+
+// ClientIPChain returns the client IP address for r, as ClientIP does,
+// together with the chain it was resolved from: the hops of the honored
+// forwarding header, client side first, followed by the connection's
+// remote address.
+//
+// Only the for= parameter of Forwarded is used; obfuscated and "unknown"
+// nodes end the walk.
+func (c *Config) ClientIPChain(r *http.Request) (string, []string) {
+	trusted := c.TrustedProxies
+	remote, err := parseHostAddr(r.RemoteAddr)
+	if err != nil {
+		ip, _, splitErr := net.SplitHostPort(r.RemoteAddr)
+		if splitErr != nil {
+			return r.RemoteAddr, []string{r.RemoteAddr}
+		}
+		return ip, []string{ip}
+	}
+	if !isTrusted(remote, trusted) {
+		return remote.String(), []string{remote.String()}
+	}
+
+	var chain []string
+	switch c.ClientIPHeader {
+	case IPHeaderForwarded:
+		chain = forwardedChain(r.Header)
+	case IPHeaderXRealIP:
+		// A proxy sets X-Real-IP rather than adding to it, so differing
+		// copies mean one came from the client, and there is no telling
+		// which; the peer is the one address known to be real
+		values := r.Header.Values("X-Real-IP")
+		if len(values) == 0 || slices.ContainsFunc(values[1:], func(v string) bool { return v != values[0] }) {
+			break
+		}
+		xri := strings.TrimSpace(values[0])
+		if addr, err := parseHostAddr(xri); err == nil {
+			return addr.String(), []string{xri, remote.String()}
+		}
+	default:
+		chain = xForwardedForChain(r.Header)
+	}
+	if len(chain) > 0 {
+		client := remote
+		for i := len(chain) - 1; i >= 0; i-- {
+			addr, err := parseHostAddr(chain[i])
+			if err != nil {
+				// An unparseable hop was not written by a proxy we
+				// trust, so the closest trusted hop is the client
+				break
+			}
+			client = addr
+			if !isTrusted(addr, trusted) {
+				break
+			}
+		}
+		return client.String(), append(chain, remote.String())
+	}
+	return remote.String(), []string{remote.String()}
+}
+
+// xForwardedForChain returns the X-Forwarded-For hops, combining repeated
+// header lines into a single chain.
+func xForwardedForChain(header http.Header) []string {
+	var chain []string
+	for _, value := range header.Values("X-Forwarded-For") {
+		for _, entry := range strings.Split(value, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				chain = append(chain, entry)
+			}
+		}
+	}
+	return chain
+}
+
+// forwardedChain returns the for= node of each element of the Forwarded
+// headers (RFC 7239), such as 192.0.2.60, [2001:db8::1]:4711, or _hidden,
+// with any quoting removed. Elements without a for= parameter are recorded
+// as "unknown", since the proxy that wrote them did not disclose the hop.
+// It returns nil when no element has a for= parameter, such as when a
+// proxy only uses Forwarded to pass proto=.
+func forwardedChain(header http.Header) []string {
+	var chain []string
+	disclosed := false
+	for _, value := range header.Values("Forwarded") {
+		for _, element := range splitQuoted(value, ',') {
+			if element = strings.TrimSpace(element); element == "" {
+				continue
+			}
+			node := "unknown"
+			for _, pair := range splitQuoted(element, ';') {
+				key, val, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				if strings.EqualFold(strings.TrimSpace(key), "for") {
+					node = unquote(strings.TrimSpace(val))
+					disclosed = true
+					break
+				}
+			}
+			chain = append(chain, node)
+		}
+	}
+	if !disclosed {
+		return nil
+	}
+	return chain
+}
+
+// unquote removes the quotes and backslash escapes of an HTTP
+// quoted-string, returning other values unchanged.
+func unquote(value string) string {
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return value
+	}
+	value = value[1 : len(value)-1]
+	var b strings.Builder
+	b.Grow(len(value))
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
+
+// parseHostAddr parses an IP address that may carry a port or IPv6
+// brackets, as found in RemoteAddr and forwarding headers.
+func parseHostAddr(value string) (netip.Addr, error) {
+	if addrPort, err := netip.ParseAddrPort(value); err == nil {
+		return addrPort.Addr().Unmap(), nil
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(value, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addr.Unmap(), nil
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
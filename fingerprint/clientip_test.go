@@ -0,0 +1,252 @@
+package fingerprint
+
+import (
+	"net/http"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestClientIPChain(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32"), netip.MustParsePrefix("10.0.0.0/8")}
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     IPHeader
+		headers    http.Header
+		wantIP     string
+		wantChain  []string
+	}{
+		{
+			name:       "untrusted peer ignores headers",
+			remoteAddr: "203.0.113.9:5000",
+			headers:    http.Header{"X-Forwarded-For": {"9.9.9.9"}},
+			wantIP:     "203.0.113.9",
+			wantChain:  []string{"203.0.113.9"},
+		},
+		{
+			name:       "untrusted peer ignores x-real-ip",
+			remoteAddr: "203.0.113.9:5000",
+			header:     IPHeaderXRealIP,
+			headers:    http.Header{"X-Real-Ip": {"9.9.9.9"}},
+			wantIP:     "203.0.113.9",
+			wantChain:  []string{"203.0.113.9"},
+		},
+		{
+			name:       "untrusted ipv6 peer ignores headers",
+			remoteAddr: "[2001:db8::9]:5000",
+			headers:    http.Header{"X-Forwarded-For": {"127.0.0.1"}, "X-Real-Ip": {"10.0.0.1"}},
+			wantIP:     "2001:db8::9",
+			wantChain:  []string{"2001:db8::9"},
+		},
+		{
+			name:       "x-forwarded-for from trusted peer",
+			remoteAddr: "127.0.0.1:5000",
+			headers:    http.Header{"X-Forwarded-For": {"5.5.5.5"}},
+			wantIP:     "5.5.5.5",
+			wantChain:  []string{"5.5.5.5", "127.0.0.1"},
+		},
+		{
+			name:       "client-prepended hops are skipped",
+			remoteAddr: "127.0.0.1:5000",
+			headers:    http.Header{"X-Forwarded-For": {"9.9.9.9, 5.5.5.5, 10.1.2.3"}},
+			wantIP:     "5.5.5.5",
+			wantChain:  []string{"9.9.9.9", "5.5.5.5", "10.1.2.3", "127.0.0.1"},
+		},
+		{
+			name:       "repeated x-forwarded-for lines form one chain",
+			remoteAddr: "127.0.0.1:5000",
+			headers:    http.Header{"X-Forwarded-For": {"9.9.9.9", "5.5.5.5"}},
+			wantIP:     "5.5.5.5",
+			wantChain:  []string{"9.9.9.9", "5.5.5.5", "127.0.0.1"},
+		},
+		{
+			name:       "unparseable hop ends the walk",
+			remoteAddr: "127.0.0.1:5000",
+			headers:    http.Header{"X-Forwarded-For": {"5.5.5.5, not-an-ip, 10.1.2.3"}},
+			wantIP:     "10.1.2.3",
+			wantChain:  []string{"5.5.5.5", "not-an-ip", "10.1.2.3", "127.0.0.1"},
+		},
+		{
+			name:       "client-sent forwarded is ignored behind an x-forwarded-for proxy",
+			remoteAddr: "127.0.0.1:5000",
+			headers:    http.Header{"Forwarded": {"for=9.9.9.9"}, "X-Forwarded-For": {"5.5.5.5"}},
+			wantIP:     "5.5.5.5",
+			wantChain:  []string{"5.5.5.5", "127.0.0.1"},
+		},
+		{
+			name:       "client-sent x-real-ip is ignored behind an x-forwarded-for proxy",
+			remoteAddr: "127.0.0.1:5000",
+			headers:    http.Header{"X-Real-Ip": {"9.9.9.9"}},
+			wantIP:     "127.0.0.1",
+			wantChain:  []string{"127.0.0.1"},
+		},
+		{
+			name:       "forwarded when configured",
+			remoteAddr: "127.0.0.1:5000",
+			header:     IPHeaderForwarded,
+			headers:    http.Header{"Forwarded": {`for="[2001:db8::1]:4711"`}, "X-Forwarded-For": {"9.9.9.9"}},
+			wantIP:     "2001:db8::1",
+			wantChain:  []string{"[2001:db8::1]:4711", "127.0.0.1"},
+		},
+		{
+			name:       "obfuscated forwarded node ends the walk",
+			remoteAddr: "127.0.0.1:5000",
+			header:     IPHeaderForwarded,
+			headers:    http.Header{"Forwarded": {"for=5.5.5.5, for=_hidden"}},
+			wantIP:     "127.0.0.1",
+			wantChain:  []string{"5.5.5.5", "_hidden", "127.0.0.1"},
+		},
+		{
+			name:       "forwarded without for= falls back to the peer",
+			remoteAddr: "127.0.0.1:5000",
+			header:     IPHeaderForwarded,
+			headers:    http.Header{"Forwarded": {"proto=https"}, "X-Forwarded-For": {"9.9.9.9"}},
+			wantIP:     "127.0.0.1",
+			wantChain:  []string{"127.0.0.1"},
+		},
+		{
+			name:       "x-real-ip when configured",
+			remoteAddr: "127.0.0.1:5000",
+			header:     IPHeaderXRealIP,
+			headers:    http.Header{"X-Real-Ip": {"5.5.5.5"}, "X-Forwarded-For": {"9.9.9.9"}},
+			wantIP:     "5.5.5.5",
+			wantChain:  []string{"5.5.5.5", "127.0.0.1"},
+		},
+		{
+			name:       "repeated identical x-real-ip",
+			remoteAddr: "127.0.0.1:5000",
+			header:     IPHeaderXRealIP,
+			headers:    http.Header{"X-Real-Ip": {"5.5.5.5", "5.5.5.5"}},
+			wantIP:     "5.5.5.5",
+			wantChain:  []string{"5.5.5.5", "127.0.0.1"},
+		},
+		{
+			name:       "conflicting x-real-ip falls back to the peer",
+			remoteAddr: "127.0.0.1:5000",
+			header:     IPHeaderXRealIP,
+			headers:    http.Header{"X-Real-Ip": {"9.9.9.9", "5.5.5.5"}},
+			wantIP:     "127.0.0.1",
+			wantChain:  []string{"127.0.0.1"},
+		},
+		{
+			name:       "malformed x-real-ip falls back to the peer",
+			remoteAddr: "127.0.0.1:5000",
+			header:     IPHeaderXRealIP,
+			headers:    http.Header{"X-Real-Ip": {"not-an-ip"}},
+			wantIP:     "127.0.0.1",
+			wantChain:  []string{"127.0.0.1"},
+		},
+		{
+			name:       "ipv4-mapped peer is unmapped",
+			remoteAddr: "[::ffff:127.0.0.1]:5000",
+			headers:    http.Header{"X-Forwarded-For": {"5.5.5.5"}},
+			wantIP:     "5.5.5.5",
+			wantChain:  []string{"5.5.5.5", "127.0.0.1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Config{TrustedProxies: trusted, ClientIPHeader: tt.header}
+			r := &http.Request{RemoteAddr: tt.remoteAddr, Header: tt.headers}
+			ip, chain := c.ClientIPChain(r)
+			if ip != tt.wantIP {
+				t.Errorf("ip = %q, want %q", ip, tt.wantIP)
+			}
+			if !slices.Equal(chain, tt.wantChain) {
+				t.Errorf("chain = %q, want %q", chain, tt.wantChain)
+			}
+		})
+	}
+}
+
+func TestParseIPHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    IPHeader
+		wantErr bool
+	}{
+		{name: "X-Forwarded-For", want: IPHeaderXForwardedFor},
+		{name: "forwarded", want: IPHeaderForwarded},
+		{name: " X-Real-IP ", want: IPHeaderXRealIP},
+		{name: "True-Client-IP", wantErr: true},
+		{name: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseIPHeader(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseIPHeader(%q) = %q, %v; want %q, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	got, err := ParseTrustedProxies([]string{"10.1.2.3/8", " 192.0.2.1 ", "", "::ffff:198.51.100.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.0.2.1/32"),
+		netip.MustParsePrefix("198.51.100.1/32"),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("ParseTrustedProxies = %v, want %v", got, want)
+	}
+	if _, err := ParseTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("ParseTrustedProxies accepted an invalid prefix")
+	}
+}
+
+// TestConflictDoesNotPickClientIP checks that headers CheckIPHeaders finds
+// in conflict never resolve to the address the client chose.
+func TestConflictDoesNotPickClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")}
+	tests := []struct {
+		name    string
+		header  IPHeader
+		headers http.Header
+		want    string
+	}{
+		{"forwarded against x-forwarded-for", IPHeaderXForwardedFor, http.Header{"Forwarded": {"for=9.9.9.9"}, "X-Forwarded-For": {"5.5.5.5"}}, "5.5.5.5"},
+		{"x-real-ip outside the chain", IPHeaderXForwardedFor, http.Header{"X-Real-Ip": {"9.9.9.9"}, "X-Forwarded-For": {"5.5.5.5"}}, "5.5.5.5"},
+		{"x-forwarded-for against forwarded", IPHeaderForwarded, http.Header{"Forwarded": {"for=5.5.5.5"}, "X-Forwarded-For": {"9.9.9.9"}}, "5.5.5.5"},
+		{"conflicting x-real-ip", IPHeaderXRealIP, http.Header{"X-Real-Ip": {"9.9.9.9", "5.5.5.5"}}, "127.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if check := CheckIPHeaders(tt.headers); !check.Conflict {
+				t.Fatalf("CheckIPHeaders found no conflict in %v", tt.headers)
+			}
+			c := Config{TrustedProxies: trusted, ClientIPHeader: tt.header}
+			if got := c.ClientIP(&http.Request{RemoteAddr: "127.0.0.1:5000", Header: tt.headers}); got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckIPHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  http.Header
+		checked  bool
+		conflict bool
+	}{
+		{"none", http.Header{}, false, false},
+		{"single chain", http.Header{"X-Forwarded-For": {"5.5.5.5, 10.0.0.1"}}, true, false},
+		{"x-real-ip inside the chain", http.Header{"X-Forwarded-For": {"5.5.5.5, 10.0.0.1"}, "X-Real-Ip": {"10.0.0.1"}}, true, false},
+		{"chains share a hop", http.Header{"X-Forwarded-For": {"5.5.5.5"}, "Forwarded": {"for=5.5.5.5"}}, true, false},
+		{"repeated with different values", http.Header{"X-Forwarded-For": {"5.5.5.5", "6.6.6.6"}}, true, true},
+		{"x-real-ip not an address", http.Header{"X-Real-Ip": {"localhost"}}, true, true},
+		{"address headers disagree", http.Header{"X-Real-Ip": {"5.5.5.5"}, "Cf-Connecting-Ip": {"6.6.6.6"}}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := CheckIPHeaders(tt.headers)
+			if check.Checked != tt.checked || check.Conflict != tt.conflict {
+				t.Errorf("CheckIPHeaders = checked %v, conflict %v (%s); want %v, %v", check.Checked, check.Conflict, check.Reason, tt.checked, tt.conflict)
+			}
+		})
+	}
+}
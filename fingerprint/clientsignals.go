@@ -0,0 +1,128 @@
+package fingerprint
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Bounds on ClientSignals, which come from untrusted client JavaScript.
+const (
+	maxClientSignalLen = 256
+	maxClientFonts     = 200
+	maxClientFontLen   = 64
+)
+
+var (
+	screenPattern   = regexp.MustCompile(`^\d{1,5}x\d{1,5}(x\d{1,2})?$`)
+	timezonePattern = regexp.MustCompile(`^[A-Za-z0-9/_+-]{1,64}$`)
+)
+
+// ClientSignals are fingerprint signals that only JavaScript running in
+// the browser can collect, such as how it renders a canvas. They are
+// combined with the server-side signals by GenerateComposite.
+type ClientSignals struct {
+	// Canvas is a hash of a rendered test canvas
+	Canvas string `json:"canvas,omitempty"`
+	// WebGLVendor and WebGLRenderer are the unmasked GPU strings
+	WebGLVendor   string `json:"webgl_vendor,omitempty"`
+	WebGLRenderer string `json:"webgl_renderer,omitempty"`
+	// Audio is a hash of a rendered test audio signal
+	Audio string `json:"audio,omitempty"`
+	// Fonts lists the detected installed fonts, in any order
+	Fonts []string `json:"fonts,omitempty"`
+	// Screen is the screen size and color depth, as in "1920x1080x24"
+	Screen string `json:"screen,omitempty"`
+	// Timezone is the IANA time zone name, as in "Europe/Berlin"
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// Validate checks that s holds at least one signal and that every value is
+// well formed and within bounds.
+func (s ClientSignals) Validate() error {
+	for name, value := range map[string]string{
+		"canvas":         s.Canvas,
+		"webgl_vendor":   s.WebGLVendor,
+		"webgl_renderer": s.WebGLRenderer,
+		"audio":          s.Audio,
+	} {
+		if len(value) > maxClientSignalLen {
+			return fmt.Errorf("%s exceeds %d bytes", name, maxClientSignalLen)
+		}
+	}
+	if len(s.Fonts) > maxClientFonts {
+		return fmt.Errorf("fonts lists more than %d entries", maxClientFonts)
+	}
+	for _, font := range s.Fonts {
+		if font == "" || len(font) > maxClientFontLen {
+			return fmt.Errorf("font names must be 1 to %d bytes", maxClientFontLen)
+		}
+	}
+	if s.Screen != "" && !screenPattern.MatchString(s.Screen) {
+		return errors.New(`screen must look like "1920x1080" or "1920x1080x24"`)
+	}
+	if s.Timezone != "" && !timezonePattern.MatchString(s.Timezone) {
+		return errors.New("timezone must be an IANA time zone name")
+	}
+	if len(s.components()) == 0 {
+		return errors.New("no client signals given")
+	}
+	return nil
+}
+
+// components returns the ordered key:value parts of s, skipping empty
+// signals.
+func (s ClientSignals) components() []string {
+	var parts componentList
+	s.writeComponents(&parts)
+	return parts
+}
+
+// writeComponents writes the components of s to w, skipping empty
+// signals. Fonts are sorted and deduplicated, since detection order
+// varies.
+func (s ClientSignals) writeComponents(w componentWriter) {
+	add := func(name, value string) {
+		if value != "" {
+			w.component(name, value)
+		}
+	}
+	add("canvas", s.Canvas)
+	add("webgl-vendor", s.WebGLVendor)
+	add("webgl-renderer", s.WebGLRenderer)
+	add("audio", s.Audio)
+	fonts := slices.Compact(slices.Sorted(slices.Values(s.Fonts)))
+	add("fonts", strings.Join(fonts, ","))
+	add("screen", s.Screen)
+	add("timezone", s.Timezone)
+}
+
+// GenerateComposite returns a fingerprint combining the stable server-side
+// signals of data, as used by GenerateStable, with browser-collected
+// signals. The volatile server-side signals, such as the client IP, are
+// left out so the composite identifies the browser across sessions.
+func GenerateComposite(data Data, signals ClientSignals) string {
+	return defaultConfig.GenerateComposite(data, signals)
+}
+
+// GenerateComposite returns the composite fingerprint of data and signals
+// using the configured hash algorithm.
+func (c *Config) GenerateComposite(data Data, signals ClientSignals) string {
+	e := newEncoder()
+	defer e.free()
+	c.stableComponents(data, e)
+	signals.writeComponents(e)
+	return c.hash(e.buf)
+}
+
+// CompositeComponents returns the ordered key:value parts that feed the
+// composite fingerprint: the stable components followed by the client
+// signals.
+func (c *Config) CompositeComponents(data Data, signals ClientSignals) []string {
+	var parts componentList
+	c.stableComponents(data, &parts)
+	signals.writeComponents(&parts)
+	return parts
+}
@@ -0,0 +1,144 @@
+package fingerprint
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// componentWeights rates how strongly each fingerprint component
+// identifies a device. Implementation-level signals that rarely change
+// weigh the most; per-request headers that change between page loads weigh
+// the least. Components not listed, including most headers, weigh 1.
+var componentWeights = map[string]float64{
+	"ja3":               3,
+	"ja4":               3,
+	"supported-groups":  2,
+	"tls-extensions":    1,
+	"h2":                3,
+	"client-cert":       3,
+	"ua":                2,
+	"header-order":      2,
+	"tls":               1.5,
+	"cipher":            1.5,
+	"alpn":              0.5,
+	"accept":            1.5,
+	"accept-lang":       1.5,
+	"accept-enc":        1.5,
+	"accept-charset":    1.5,
+	"ip":                1,
+	"protocol":          0.5,
+	"method":            0.25,
+	"port":              0.25,
+	"path":              0.25,
+	"query-keys":        0.25,
+	"cookie-names":      0.5,
+	"cache-control":     0.25,
+	"pragma":            0.25,
+	"priority":          0.25,
+	"referer":           0.25,
+	"if-none-match":     0.25,
+	"if-modified-since": 0.25,
+	"date":              0.25,
+	"range":             0.25,
+	"content-type":      0.25,
+}
+
+// clientHintWeight applies to the Sec-Ch-Ua-* client hints, which are as
+// stable as the User-Agent they describe.
+const clientHintWeight = 1.5
+
+// Comparison is the result of comparing two fingerprints component by
+// component.
+type Comparison struct {
+	// Score is the weighted share of components with equal values, from
+	// 0 (nothing in common) to 1 (identical).
+	Score float64
+
+	// Differences lists the components whose values differ or that only
+	// one side has, sorted by name.
+	Differences []Difference
+}
+
+// Difference is a component that does not match between two fingerprints.
+// A missing component has an empty value.
+type Difference struct {
+	Component string  `json:"component"`
+	A         string  `json:"a"`
+	B         string  `json:"b"`
+	Weight    float64 `json:"weight"`
+}
+
+// Compare scores how similar two fingerprints are, so a device can be
+// recognized after a minor change, such as a browser update that altered
+// one header, gives it a new fingerprint hash.
+func Compare(a, b Data) Comparison {
+	return defaultConfig.Compare(a, b)
+}
+
+// Compare scores the similarity of two fingerprints over the components
+// this configuration hashes.
+func (c *Config) Compare(a, b Data) Comparison {
+	return CompareComponents(c.Components(a), c.Components(b))
+}
+
+// CompareComponents is Compare over the key:value components of two
+// fingerprints, as Config.Components returns them, such as components
+// stored when the fingerprints were seen.
+func CompareComponents(a, b []string) Comparison {
+	componentsA := componentMap(a)
+	componentsB := componentMap(b)
+
+	names := make(map[string]bool, len(componentsA))
+	for name := range componentsA {
+		names[name] = true
+	}
+	for name := range componentsB {
+		names[name] = true
+	}
+
+	var comparison Comparison
+	var total, matched float64
+	for name := range names {
+		weight := ComponentWeight(name)
+		total += weight
+
+		valueA, okA := componentsA[name]
+		valueB, okB := componentsB[name]
+		if okA && okB && valueA == valueB {
+			matched += weight
+			continue
+		}
+		comparison.Differences = append(comparison.Differences,
+			Difference{Component: name, A: valueA, B: valueB, Weight: weight})
+	}
+
+	sort.Slice(comparison.Differences, func(i, j int) bool {
+		return comparison.Differences[i].Component < comparison.Differences[j].Component
+	})
+	if total > 0 {
+		comparison.Score = math.Round(matched/total*1000) / 1000
+	}
+	return comparison
+}
+
+// ComponentWeight returns the weight Compare gives to the named component.
+func ComponentWeight(name string) float64 {
+	if weight, ok := componentWeights[name]; ok {
+		return weight
+	}
+	if strings.HasPrefix(name, "sec-ch-ua") {
+		return clientHintWeight
+	}
+	return 1
+}
+
+// componentMap splits key:value components into a map keyed by name.
+func componentMap(components []string) map[string]string {
+	m := make(map[string]string, len(components))
+	for _, component := range components {
+		name, value, _ := strings.Cut(component, ":")
+		m[name] = value
+	}
+	return m
+}
@@ -0,0 +1,73 @@
+package fingerprint
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DeviceProfile consolidates the device-related client hints. Chromium
+// sends the modern Sec-Ch-* headers; older versions and some proxies send
+// the legacy DPR, Width, Viewport-Width, and Device-Memory headers instead.
+// Where both forms exist the modern header wins.
+type DeviceProfile struct {
+	Platform        string  `json:"platform,omitempty"`
+	PlatformVersion string  `json:"platform_version,omitempty"`
+	Architecture    string  `json:"architecture,omitempty"`
+	Model           string  `json:"model,omitempty"`
+	Bitness         string  `json:"bitness,omitempty"`
+	Mobile          *bool   `json:"mobile,omitempty"`
+	DeviceMemory    float64 `json:"device_memory,omitempty"`
+	DPR             float64 `json:"dpr,omitempty"`
+	ViewportWidth   int     `json:"viewport_width,omitempty"`
+	ViewportHeight  int     `json:"viewport_height,omitempty"`
+	Width           int     `json:"width,omitempty"`
+}
+
+// ParseDeviceProfile builds a DeviceProfile from fingerprinted headers,
+// keyed by lower-cased name as in Data.Headers. Values that do not parse
+// are ignored, so a malformed modern header falls back to its legacy
+// equivalent. ok is false when no device hint is present.
+func ParseDeviceProfile(headers map[string]string) (profile DeviceProfile, ok bool) {
+	str := func(dst *string, name string) {
+		if value := unquote(strings.TrimSpace(headers[name])); value != "" {
+			*dst = value
+		}
+	}
+	float := func(dst *float64, names ...string) {
+		for _, name := range names {
+			value, err := strconv.ParseFloat(strings.TrimSpace(headers[name]), 64)
+			if err == nil && value > 0 {
+				*dst = value
+				return
+			}
+		}
+	}
+	integer := func(dst *int, names ...string) {
+		for _, name := range names {
+			value, err := strconv.Atoi(strings.TrimSpace(headers[name]))
+			if err == nil && value > 0 {
+				*dst = value
+				return
+			}
+		}
+	}
+
+	str(&profile.Platform, "sec-ch-ua-platform")
+	str(&profile.PlatformVersion, "sec-ch-ua-platform-version")
+	str(&profile.Architecture, "sec-ch-ua-arch")
+	str(&profile.Model, "sec-ch-ua-model")
+	str(&profile.Bitness, "sec-ch-ua-bitness")
+	// Sec-Ch-Ua-Mobile is a structured header boolean, ?0 or ?1
+	switch headers["sec-ch-ua-mobile"] {
+	case "?0", "?1":
+		mobile := headers["sec-ch-ua-mobile"] == "?1"
+		profile.Mobile = &mobile
+	}
+	float(&profile.DeviceMemory, "sec-ch-device-memory", "device-memory")
+	float(&profile.DPR, "sec-ch-dpr", "dpr")
+	integer(&profile.ViewportWidth, "sec-ch-viewport-width", "viewport-width")
+	integer(&profile.ViewportHeight, "sec-ch-viewport-height")
+	integer(&profile.Width, "sec-ch-width", "width")
+
+	return profile, profile != DeviceProfile{}
+}
@@ -0,0 +1,146 @@
+package fingerprint
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"hash"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// componentWriter receives fingerprint components as they are produced,
+// so the same code can list them or hash them without building strings.
+type componentWriter interface {
+	component(key, value string)
+}
+
+// componentList collects components in their key:value form.
+type componentList []string
+
+func (l *componentList) component(key, value string) {
+	*l = append(*l, key+":"+value)
+}
+
+// maxPooledBuffer bounds the buffers returned to the pools, so one huge
+// request does not pin its buffer for the life of the process.
+const maxPooledBuffer = 64 << 10
+
+var encoderPool = sync.Pool{
+	New: func() any { return &encoder{buf: make([]byte, 0, 1024)} },
+}
+
+// encoder serializes the schema version and components for hashing, each
+// prefixed with its length in bytes and followed by "|", so no value can
+// be mistaken for a delimiter. Joined on "|" alone, a header "a" of
+// "1|b:2" and headers "a" of "1" and "b" of "2" would both read "a:1|b:2"
+// and hash identically.
+type encoder struct {
+	buf []byte
+}
+
+// newEncoder returns a pooled encoder holding the schema version. It must
+// be released with free.
+func newEncoder() *encoder {
+	e := encoderPool.Get().(*encoder)
+	e.buf = e.buf[:0]
+	e.part(versionPrefix)
+	return e
+}
+
+func (e *encoder) free() {
+	if cap(e.buf) <= maxPooledBuffer {
+		encoderPool.Put(e)
+	}
+}
+
+// part writes a component already in key:value form.
+func (e *encoder) part(part string) {
+	e.buf = strconv.AppendInt(e.buf, int64(len(part)), 10)
+	e.buf = append(e.buf, ':')
+	e.buf = append(e.buf, part...)
+	e.buf = append(e.buf, '|')
+}
+
+// component writes key:value exactly as part would.
+func (e *encoder) component(key, value string) {
+	e.buf = strconv.AppendInt(e.buf, int64(len(key)+1+len(value)), 10)
+	e.buf = append(e.buf, ':')
+	e.buf = append(e.buf, key...)
+	e.buf = append(e.buf, ':')
+	e.buf = append(e.buf, value...)
+	e.buf = append(e.buf, '|')
+}
+
+// hasher is a reusable digest with scratch space for its sum and the
+// versioned hex encoding of it.
+type hasher struct {
+	hash.Hash
+	sum []byte
+	out []byte
+}
+
+// hasherPool pools the hashers of one algorithm and salt.
+type hasherPool struct {
+	algorithm HashAlgorithm
+	salt      string
+	pool      sync.Pool
+}
+
+func newHasherPool(algorithm HashAlgorithm, salt string) *hasherPool {
+	p := &hasherPool{algorithm: algorithm, salt: salt}
+	p.pool.New = func() any {
+		h := algorithm.New()
+		if salt != "" {
+			h = hmac.New(algorithm.New, []byte(salt))
+		}
+		return &hasher{Hash: h}
+	}
+	return p
+}
+
+var (
+	// unsaltedPools serve the supported algorithms without a salt
+	unsaltedPools = map[HashAlgorithm]*hasherPool{
+		"":         newHasherPool("", ""),
+		HashSHA256: newHasherPool(HashSHA256, ""),
+		HashSHA1:   newHasherPool(HashSHA1, ""),
+		HashMD5:    newHasherPool(HashMD5, ""),
+		HashXXHash: newHasherPool(HashXXHash, ""),
+	}
+
+	// lastPool serves the most recently used salted configuration. A
+	// deployment uses one salt, so other salts only cost a new pool when
+	// they alternate.
+	lastPool atomic.Pointer[hasherPool]
+)
+
+func (c *Config) hashers() *hasherPool {
+	if c.Salt == "" {
+		if p, ok := unsaltedPools[c.Hash]; ok {
+			return p
+		}
+	}
+	if p := lastPool.Load(); p != nil && p.algorithm == c.Hash && p.salt == c.Salt {
+		return p
+	}
+	p := newHasherPool(c.Hash, c.Salt)
+	lastPool.Store(p)
+	return p
+}
+
+// digest hashes input with the configured algorithm, keyed by the salt
+// when one is set, and returns the versioned hex digest.
+func (c *Config) digest(input []byte) string {
+	pool := c.hashers()
+	h := pool.pool.Get().(*hasher)
+	defer pool.pool.Put(h)
+
+	h.Reset()
+	h.Write(input)
+	h.sum = h.Sum(h.sum[:0])
+	h.out = append(h.out[:0], versionPrefix...)
+	h.out = append(h.out, ':')
+	h.out = hex.AppendEncode(h.out, h.sum)
+	return string(h.out)
+}
@@ -0,0 +1,645 @@
+// Package fingerprint derives deterministic fingerprints from HTTP requests
+// using the client IP address, request headers, and connection metadata.
+package fingerprint
+
+import (
+	"mime"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// Data holds the request characteristics that feed a fingerprint.
+type Data struct {
+	IPAddress     string
+	UserAgent     string
+	AcceptLang    string
+	AcceptEnc     string
+	Accept        string
+	Headers       map[string]string
+	RemoteAddr    string
+	XForwardedFor string
+	XRealIP       string
+	Method        string
+	Protocol      string
+	TLSVersion    string
+	CipherSuite   string
+	ALPN          string
+	JA3           string
+	JA4           string
+	H2Fingerprint string
+	HeaderOrder   []string
+
+	// TLSExtensions and SupportedGroups are the extensions and supported
+	// groups of the ClientHello in the order sent, without GREASE values.
+	// They are hashed when Config.TLSOrder is enabled.
+	TLSExtensions   []uint16
+	SupportedGroups []uint16
+
+	// WebSocketKey is the format of the Sec-WebSocket-Key of a WebSocket
+	// upgrade; see webSocketKeyFormat
+	WebSocketKey string
+
+	// ConnectProtocol is the protocol an extended CONNECT request
+	// bootstraps over HTTP/2 or HTTP/3, such as "websocket"
+	ConnectProtocol string
+
+	// IPHeaderConflict is set when the headers carrying the client IP
+	// contradict each other; see CheckIPHeaders
+	IPHeaderConflict bool
+
+	// Transport is the transport signature of the hop-by-hop headers, set
+	// when Config.TransportSignal is enabled and the request did not come
+	// through a trusted proxy; see transportSignature
+	Transport string
+
+	// Client certificate presented over mutual TLS, if any
+	ClientCertThumbprint string
+	ClientCertSubject    string
+	ClientCertIssuer     string
+
+	// Body framing signals, set when Config.BodySignals is enabled
+	TransferEncoding  string
+	HasContentLength  bool
+	MultipartBoundary string
+	Port              string
+
+	// Request target, set when Config.IncludePath and
+	// Config.IncludeQueryKeys are enabled. QueryKeys holds the sorted,
+	// distinct query parameter names; values are dropped.
+	RequestPath string
+	QueryKeys   []string
+
+	// CookieNames holds the sorted, distinct names of the request's
+	// cookies, set when Config.IncludeCookieNames is enabled. Cookie
+	// values are never captured.
+	CookieNames []string
+}
+
+// Config controls how fingerprint data is extracted from requests. The zero
+// value is ready to use and trusts no proxies.
+type Config struct {
+	// TrustedProxies lists the networks whose ClientIPHeader is believed
+	// when resolving the client IP.
+	TrustedProxies []netip.Prefix
+
+	// ClientIPHeader is the header the trusted proxies write the client IP
+	// to; the other forwarding headers are ignored. It is
+	// IPHeaderXForwardedFor when empty.
+	ClientIPHeader IPHeader
+
+	// Headers lists the request headers that feed the fingerprint. The
+	// names returned by DefaultHeaders are used when it is empty.
+	Headers []string
+
+	// ExcludeHeaders names headers, matched case-insensitively, that are
+	// left out of every hash and of the header order hashed with them,
+	// including User-Agent, Accept, Accept-Language, and Accept-Encoding.
+	// They are still extracted into Data, so they stay available for
+	// logging, bot scoring, and enrichment.
+	ExcludeHeaders []string
+
+	// ProxyHeaders names headers, matched case-insensitively, that proxies,
+	// CDNs, and tracing systems add to requests on their way in, such as
+	// Via, CF-Ray, and X-Amzn-Trace-Id. Their values depend on the edge
+	// node or are unique per request, so hashing them would split one
+	// client into a fingerprint per CDN node. They are excluded like
+	// ExcludeHeaders. The names returned by DefaultProxyHeaders are used
+	// when it is nil; set it to an empty slice to hash them.
+	ProxyHeaders []string
+
+	// NormalizeHeaders rewrites semantically equivalent header values,
+	// such as differently spaced or ordered Accept-Encoding lists, into
+	// one form before hashing. See NormalizeHeader.
+	NormalizeHeaders bool
+
+	// CanonicalNegotiation rewrites the Accept, Accept-Encoding, and
+	// Accept-Charset headers into the weight-sorted form returned by
+	// CanonicalPreferences before hashing, so clients that accept the same
+	// content match however they spell and order it.
+	CanonicalNegotiation bool
+
+	// CanonicalHeaders rewrites every hashed header into the canonical
+	// form of CanonicalHeader, and lowercases the names in the header
+	// order, so requests that differ only in how a client or proxy
+	// formatted names and values hash the same. It subsumes
+	// NormalizeHeaders and CanonicalNegotiation.
+	CanonicalHeaders bool
+
+	// BodySignals adds how the request body is framed, such as chunked
+	// transfer coding and the multipart boundary style, to the
+	// fingerprint. The body itself is never read.
+	BodySignals bool
+
+	// SecFetchSignal adds the Sec-Fetch-Site, -Mode, -User, and -Dest
+	// headers as one sec-fetch component, normalized and marked with
+	// whether CheckSecFetch finds them consistent, so a client sending an
+	// impossible combination does not share a fingerprint with a browser
+	// that otherwise looks the same.
+	SecFetchSignal bool
+
+	// TransportSignal replaces the Connection, TE, Keep-Alive, and
+	// Proxy-Connection headers, and any header Connection names, with one
+	// transport component that keeps the order and letter case of the
+	// Connection options, which tell HTTP libraries apart. Requests from
+	// trusted proxies leave it out: proxies drop and rewrite these headers
+	// per hop, so they would only fingerprint the proxy.
+	TransportSignal bool
+
+	// TLSOrder adds the ordered TLS extensions and supported groups of the
+	// ClientHello as the tls-extensions and supported-groups components.
+	// JA3 hashes the same lists, but a client that copies a JA3 string can
+	// still get their order wrong, and tools that spoof JA4, which sorts
+	// the extensions, rarely reproduce it. Chrome permutes its extensions
+	// on every connection, so the order splits Chrome visitors.
+	TLSOrder bool
+
+	// Hash is the digest used for fingerprint hashes. SHA-256 is used
+	// when it is empty.
+	Hash HashAlgorithm
+
+	// Salt, when set, keys the digest as an HMAC, so the same client gets
+	// unrelated fingerprints under different salts and fingerprints cannot
+	// be correlated across deployments. Changing it changes every
+	// fingerprint.
+	Salt string
+
+	// Cache, when set, remembers recent hashes so repeated identical
+	// requests skip the digest.
+	Cache *HashCache
+
+	// ExcludeIP leaves the client IP out of the fingerprint, so it stays
+	// the same when a device changes networks. The IP is still resolved
+	// into Data.IPAddress for logging and enrichment.
+	ExcludeIP bool
+
+	// IncludePath adds the request path to the fingerprint, for
+	// deployments that want each endpoint fingerprinted separately.
+	IncludePath bool
+
+	// IncludeQueryKeys adds the query parameter names, sorted and without
+	// their values, to the fingerprint.
+	IncludeQueryKeys bool
+
+	// IncludeCookieNames adds the names of the request's cookies, sorted
+	// and without their values, to the fingerprint. Sites and frameworks
+	// set characteristic cookies, so the names tell returning browsers
+	// apart without the session identifiers their values hold.
+	IncludeCookieNames bool
+}
+
+var defaultConfig Config
+
+// FromRequest extracts the fingerprint data from r using the default
+// configuration and returns it together with the generated fingerprint hash.
+func FromRequest(r *http.Request) (Data, string) {
+	return defaultConfig.FromRequest(r)
+}
+
+// FromRequest extracts the fingerprint data from r and returns it together
+// with the generated fingerprint hash.
+func (c *Config) FromRequest(r *http.Request) (Data, string) {
+	// Extract additional signals
+	method, protocol, tlsVersion, port := extractAdditionalSignals(r)
+
+	// WebSocket upgrades also fingerprint their handshake headers
+	headerNames := c.Headers
+	upgrade := IsWebSocketUpgrade(r)
+	if upgrade {
+		headerNames = withWebSocketHeaders(headerNames)
+	}
+
+	// Extract fingerprint data
+	data := Data{
+		IPAddress:     c.ClientIP(r),
+		UserAgent:     r.Header.Get("User-Agent"),
+		AcceptLang:    r.Header.Get("Accept-Language"),
+		AcceptEnc:     r.Header.Get("Accept-Encoding"),
+		Accept:        r.Header.Get("Accept"),
+		Headers:       ExtractHeaders(r, headerNames),
+		RemoteAddr:    r.RemoteAddr,
+		XForwardedFor: r.Header.Get("X-Forwarded-For"),
+		XRealIP:       r.Header.Get("X-Real-IP"),
+		Method:        method,
+		Protocol:      protocol,
+		TLSVersion:    tlsVersion,
+		Port:          port,
+
+		ConnectProtocol:  extendedConnectProtocol(r),
+		IPHeaderConflict: CheckIPHeaders(r.Header).Conflict,
+	}
+
+	data.CipherSuite, data.ALPN = extractTLSDetails(r)
+	data.ClientCertThumbprint, data.ClientCertSubject, data.ClientCertIssuer = extractClientCert(r)
+	if upgrade {
+		data.WebSocketKey = webSocketKeyFormat(r.Header.Get("Sec-WebSocket-Key"))
+	}
+
+	canonicalizeBrands(&data)
+	if c.NormalizeHeaders {
+		normalizeData(&data)
+	}
+	if c.CanonicalNegotiation {
+		canonicalizeData(&data)
+	}
+
+	if hello := ClientHelloFromContext(r.Context()); hello != nil {
+		data.JA3 = hello.JA3()
+		data.JA4 = hello.JA4()
+		data.TLSExtensions = hello.ExtensionOrder()
+		data.SupportedGroups = hello.SupportedGroups()
+	}
+	data.H2Fingerprint = HTTP2FingerprintFromContext(r.Context())
+	data.HeaderOrder = HeaderOrderFromContext(r.Context())
+	if c.CanonicalHeaders {
+		canonicalizeHeaders(&data)
+	}
+	if c.IncludePath {
+		data.RequestPath = r.URL.Path
+	}
+	if c.IncludeQueryKeys {
+		data.QueryKeys = queryKeys(r.URL.Query())
+	}
+	if c.IncludeCookieNames {
+		data.CookieNames = cookieNames(r)
+	}
+	if c.TransportSignal {
+		data.Transport = requestTransport(r, c.TrustedProxies)
+	}
+	if c.BodySignals {
+		data.TransferEncoding, data.HasContentLength, data.MultipartBoundary = extractBodySignals(r)
+		// The random boundary would otherwise make every multipart
+		// request's Content-Type, and so its fingerprint, unique
+		if contentType, ok := data.Headers["content-type"]; ok && data.MultipartBoundary != "" {
+			// The hashed value may have been normalized since
+			// extractBodySignals parsed the header, so it is checked again
+			if mediaType, params, err := mime.ParseMediaType(contentType); err == nil && strings.HasPrefix(mediaType, "multipart/") {
+				params["boundary"] = data.MultipartBoundary
+				data.Headers["content-type"] = mime.FormatMediaType(mediaType, params)
+			}
+		}
+	}
+
+	return data, c.Generate(data)
+}
+
+// FromData prepares data that was not captured from a live request, such
+// as signals parsed from archived access logs, the way FromRequest would:
+// headers outside the configured set are dropped and values are normalized
+// when enabled. Header names in data.Headers must be lower-cased unless
+// CanonicalHeaders is set, which folds their case. It returns the prepared
+// data together with its fingerprint hash.
+func (c *Config) FromData(data Data) (Data, string) {
+	source := data.Headers
+	if c.CanonicalHeaders {
+		source = foldHeaderNames(source)
+	}
+
+	names := c.Headers
+	upgrade := isWebSocketUpgrade(func(name string) string {
+		return source[strings.ToLower(name)]
+	})
+	if upgrade {
+		if data.WebSocketKey == "" {
+			data.WebSocketKey = webSocketKeyFormat(source["sec-websocket-key"])
+		}
+		names = withWebSocketHeaders(names)
+	}
+	if len(names) == 0 {
+		names = defaultHeaders
+	}
+
+	headers := make(map[string]string, len(names))
+	for _, name := range names {
+		key := strings.ToLower(name)
+		// net/http moves Host out of Request.Header, so live requests
+		// never fingerprint it
+		if key == "host" {
+			continue
+		}
+		if value := source[key]; value != "" {
+			headers[key] = value
+		}
+	}
+	data.Headers = headers
+	// Repeated header lines only survive in a conflict the caller found
+	if !data.IPHeaderConflict {
+		ipHeaders := make(http.Header)
+		for _, name := range slices.Concat(ipChainHeaders, ipAddressHeaders) {
+			if value := source[strings.ToLower(name)]; value != "" {
+				ipHeaders.Set(name, value)
+			}
+		}
+		data.IPHeaderConflict = CheckIPHeaders(ipHeaders).Conflict
+	}
+
+	if !c.IncludePath {
+		data.RequestPath = ""
+	}
+	if c.IncludeQueryKeys {
+		data.QueryKeys = slices.Compact(slices.Sorted(slices.Values(data.QueryKeys)))
+	} else {
+		data.QueryKeys = nil
+	}
+	if c.IncludeCookieNames {
+		data.CookieNames = slices.Compact(slices.Sorted(slices.Values(data.CookieNames)))
+	} else {
+		data.CookieNames = nil
+	}
+	switch {
+	case !c.TransportSignal:
+		data.Transport = ""
+	case data.Transport == "":
+		remote, err := parseHostAddr(data.RemoteAddr)
+		if err != nil || !isTrusted(remote, c.TrustedProxies) {
+			data.Transport = transportSignature(func(name string) string { return source[name] })
+		}
+	}
+
+	canonicalizeBrands(&data)
+	if c.NormalizeHeaders {
+		normalizeData(&data)
+	}
+	if c.CanonicalNegotiation {
+		canonicalizeData(&data)
+	}
+	if c.CanonicalHeaders {
+		canonicalizeHeaders(&data)
+	}
+
+	return data, c.Generate(data)
+}
+
+// Generate returns the hex-encoded SHA-256 fingerprint of data, computed
+// over the components returned by Components.
+func Generate(data Data) string {
+	return defaultConfig.Generate(data)
+}
+
+// Generate returns the hex-encoded fingerprint of data using the
+// configured hash algorithm.
+func (c *Config) Generate(data Data) string {
+	e := newEncoder()
+	defer e.free()
+	c.components(data, e)
+	return c.hash(e.buf)
+}
+
+// Components returns the ordered key:value parts that feed the fingerprint
+// hash. It covers every captured signal: the client IP, method, protocol,
+// TLS version, cipher suite, ALPN, client certificate, JA3/JA4, TLS
+// extension and supported group order, HTTP/2 fingerprint, header order,
+// WebSocket key format, extended CONNECT protocol, transport signature,
+// body framing, Host port, User-Agent, Accept, Accept-Language,
+// Accept-Encoding, and all other extracted headers, including volatile
+// ones such as Cache-Control, Pragma, Priority, If-None-Match, Referer,
+// and Date.
+func Components(data Data) []string {
+	return defaultConfig.Components(data)
+}
+
+// Components returns the components of data that feed the fingerprint
+// hash, leaving out the client IP when ExcludeIP is set.
+func (c *Config) Components(data Data) []string {
+	var parts componentList
+	c.components(data, &parts)
+	return parts
+}
+
+// components writes the components of data, in the order Components
+// returns them, to w.
+func (c *Config) components(data Data, w componentWriter) {
+	// Add IP address
+	if !c.ExcludeIP {
+		w.component("ip", data.IPAddress)
+	}
+
+	// Add request metadata
+	w.component("method", data.Method)
+	w.component("protocol", NormalizeProtocol(data.Protocol))
+	if data.TLSVersion != "" {
+		w.component("tls", data.TLSVersion)
+	}
+	if data.CipherSuite != "" {
+		w.component("cipher", data.CipherSuite)
+	}
+	if data.ALPN != "" {
+		w.component("alpn", data.ALPN)
+	}
+	if data.ClientCertThumbprint != "" {
+		w.component("client-cert", data.ClientCertThumbprint)
+	}
+	if data.JA3 != "" {
+		w.component("ja3", data.JA3)
+	}
+	if data.JA4 != "" {
+		w.component("ja4", data.JA4)
+	}
+	if c.TLSOrder && len(data.TLSExtensions) > 0 {
+		w.component("tls-extensions", joinUint16(data.TLSExtensions, "-"))
+	}
+	if c.TLSOrder && len(data.SupportedGroups) > 0 {
+		w.component("supported-groups", joinUint16(data.SupportedGroups, "-"))
+	}
+	if data.H2Fingerprint != "" {
+		w.component("h2", data.H2Fingerprint)
+	}
+	if order := c.hashedOrder(data.HeaderOrder); len(order) > 0 {
+		w.component("header-order", HeaderOrderHash(order))
+	}
+	if data.WebSocketKey != "" {
+		w.component("ws-key", data.WebSocketKey)
+	}
+	if data.ConnectProtocol != "" {
+		w.component("connect-protocol", data.ConnectProtocol)
+	}
+	if data.Transport != "" {
+		w.component("transport", data.Transport)
+	}
+	if data.TransferEncoding != "" {
+		w.component("transfer-encoding", data.TransferEncoding)
+	}
+	if data.HasContentLength {
+		w.component("content-length", "present")
+	}
+	if data.MultipartBoundary != "" {
+		w.component("multipart-boundary", data.MultipartBoundary)
+	}
+	if c.SecFetchSignal {
+		if f, ok := secFetch(data); ok {
+			w.component("sec-fetch", f.component())
+		}
+	}
+	if data.Port != "" {
+		w.component("port", data.Port)
+	}
+	if data.RequestPath != "" {
+		w.component("path", data.RequestPath)
+	}
+	if len(data.QueryKeys) > 0 {
+		w.component("query-keys", strings.Join(data.QueryKeys, ","))
+	}
+	if len(data.CookieNames) > 0 {
+		w.component("cookie-names", strings.Join(data.CookieNames, ","))
+	}
+
+	// Add main headers
+	c.mainHeaders(data, w)
+
+	// The transport component stands in for the hop-by-hop headers
+	var hopByHop []string
+	if c.TransportSignal {
+		hopByHop = connectionHeaders(data.Headers["connection"])
+	}
+
+	// Add other headers in sorted order for consistency. The backing
+	// array keeps the keys of a typical request off the heap.
+	var keys [32]string
+	headerKeys := keys[:0]
+	for key := range data.Headers {
+		if key != "user-agent" && key != "accept" && key != "accept-language" && key != "accept-encoding" &&
+			!slices.Contains(hopByHop, key) && !c.excludes(key) {
+			headerKeys = append(headerKeys, key)
+		}
+	}
+	slices.Sort(headerKeys)
+
+	for _, key := range headerKeys {
+		w.component(key, data.Headers[key])
+	}
+}
+
+// mainHeaders writes the User-Agent, Accept, Accept-Language, and
+// Accept-Encoding components that are not excluded to w.
+func (c *Config) mainHeaders(data Data, w componentWriter) {
+	if !c.excludes("user-agent") {
+		w.component("ua", data.UserAgent)
+	}
+	if !c.excludes("accept") {
+		w.component("accept", data.Accept)
+	}
+	if !c.excludes("accept-language") {
+		w.component("accept-lang", data.AcceptLang)
+	}
+	if !c.excludes("accept-encoding") {
+		w.component("accept-enc", data.AcceptEnc)
+	}
+}
+
+// excludes reports whether the header name is in ExcludeHeaders or the
+// proxy headers.
+func (c *Config) excludes(name string) bool {
+	for _, excluded := range c.ExcludeHeaders {
+		if strings.EqualFold(excluded, name) {
+			return true
+		}
+	}
+	proxyHeaders := c.ProxyHeaders
+	if proxyHeaders == nil {
+		proxyHeaders = defaultProxyHeaders
+	}
+	for _, proxy := range proxyHeaders {
+		if strings.EqualFold(proxy, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// hashedOrder returns order without the excluded headers.
+func (c *Config) hashedOrder(order []string) []string {
+	if !slices.ContainsFunc(order, c.excludes) {
+		return order
+	}
+	return slices.DeleteFunc(slices.Clone(order), c.excludes)
+}
+
+// GenerateStable returns a fingerprint built only from low-volatility
+// signals, so it stays the same across a browsing session even when the
+// full fingerprint changes. It is built from:
+//
+//   - User-Agent, Accept, Accept-Language, Accept-Encoding, Accept-Charset
+//   - the Sec-Ch-Ua-* client hint headers
+//   - the TLS version, cipher suite, and ALPN protocol
+//   - the client certificate thumbprint, when one was presented
+//   - the JA3/JA4 and HTTP/2 fingerprints
+//
+// The client IP, port, method, and per-request headers such as
+// Cache-Control, Pragma, If-None-Match, Referer, and Date are excluded.
+func GenerateStable(data Data) string {
+	return defaultConfig.GenerateStable(data)
+}
+
+// GenerateStable returns the stable fingerprint of data using the
+// configured hash algorithm.
+func (c *Config) GenerateStable(data Data) string {
+	e := newEncoder()
+	defer e.free()
+	c.stableComponents(data, e)
+	return c.hash(e.buf)
+}
+
+// stableComponents writes the components that feed the stable
+// fingerprint to w.
+func (c *Config) stableComponents(data Data, w componentWriter) {
+	c.mainHeaders(data, w)
+	if charset := data.Headers["accept-charset"]; charset != "" && !c.excludes("accept-charset") {
+		w.component("accept-charset", charset)
+	}
+
+	var keys [16]string
+	hintKeys := keys[:0]
+	for key := range data.Headers {
+		if strings.HasPrefix(key, "sec-ch-ua") && !c.excludes(key) {
+			hintKeys = append(hintKeys, key)
+		}
+	}
+	slices.Sort(hintKeys)
+
+	for _, key := range hintKeys {
+		w.component(key, data.Headers[key])
+	}
+
+	if data.TLSVersion != "" {
+		w.component("tls", data.TLSVersion)
+	}
+	if data.CipherSuite != "" {
+		w.component("cipher", data.CipherSuite)
+	}
+	if data.ALPN != "" {
+		w.component("alpn", data.ALPN)
+	}
+	if data.ClientCertThumbprint != "" {
+		w.component("client-cert", data.ClientCertThumbprint)
+	}
+	if data.JA3 != "" {
+		w.component("ja3", data.JA3)
+	}
+	if data.JA4 != "" {
+		w.component("ja4", data.JA4)
+	}
+	if data.H2Fingerprint != "" {
+		w.component("h2", data.H2Fingerprint)
+	}
+}
+
+// hashParts returns the fingerprint hash of parts, which are already in
+// key:value form.
+func (c *Config) hashParts(parts []string) string {
+	e := newEncoder()
+	defer e.free()
+	for _, part := range parts {
+		e.part(part)
+	}
+	return c.hash(e.buf)
+}
+
+// hash returns the digest of input, as encoded by an encoder, in hex and
+// prefixed with the schema version. input must not be retained.
+func (c *Config) hash(input []byte) string {
+	if c.Cache != nil {
+		return c.Cache.hash(c, input)
+	}
+	return c.digest(input)
+}
@@ -0,0 +1,170 @@
+package fingerprint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testRequest returns a browser-like request from 203.0.113.7, changed by
+// edit when it is not nil.
+func testRequest(edit func(r *http.Request)) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+	r.RemoteAddr = "203.0.113.7:61000"
+	r.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0")
+	r.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	r.Header.Set("Accept-Language", "en-US,en;q=0.5")
+	r.Header.Set("Accept-Encoding", "gzip, deflate, br")
+	if edit != nil {
+		edit(r)
+	}
+	return r
+}
+
+func TestGenerateStability(t *testing.T) {
+	var base Config
+	baseData, baseHash := base.FromRequest(testRequest(nil))
+	baseStable := base.GenerateStable(baseData)
+
+	tests := []struct {
+		name       string
+		config     Config
+		edit       func(r *http.Request)
+		sameFull   bool
+		sameStable bool
+	}{
+		{name: "identical request", sameFull: true, sameStable: true},
+		{
+			name:       "cache control",
+			edit:       func(r *http.Request) { r.Header.Set("Cache-Control", "no-cache") },
+			sameStable: true,
+		},
+		{
+			name:       "volatile header",
+			edit:       func(r *http.Request) { r.Header.Set("Referer", "https://example.com/a") },
+			sameStable: true,
+		},
+		{
+			name:       "client IP",
+			edit:       func(r *http.Request) { r.RemoteAddr = "198.51.100.1:61000" },
+			sameStable: true,
+		},
+		{
+			name: "user agent",
+			edit: func(r *http.Request) { r.Header.Set("User-Agent", "curl/8.5.0") },
+		},
+		{name: "hash algorithm", config: Config{Hash: HashSHA1}},
+		{name: "salt", config: Config{Salt: "deployment-a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, hash := tt.config.FromRequest(testRequest(tt.edit))
+			stable := tt.config.GenerateStable(data)
+			if (hash == baseHash) != tt.sameFull {
+				t.Errorf("fingerprint %s, base %s; want same %v", hash, baseHash, tt.sameFull)
+			}
+			if (stable == baseStable) != tt.sameStable {
+				t.Errorf("stable fingerprint %s, base %s; want same %v", stable, baseStable, tt.sameStable)
+			}
+		})
+	}
+}
+
+// TestExcludeIPIgnoresAddress checks that with ExcludeIP the full
+// fingerprint no longer depends on the client address.
+func TestExcludeIPIgnoresAddress(t *testing.T) {
+	c := Config{ExcludeIP: true}
+	_, a := c.FromRequest(testRequest(nil))
+	_, b := c.FromRequest(testRequest(func(r *http.Request) { r.RemoteAddr = "198.51.100.1:61000" }))
+	if a != b {
+		t.Errorf("fingerprints %s and %s differ with ExcludeIP", a, b)
+	}
+}
+
+// TestComponentEncoding checks that values containing the component
+// delimiter cannot produce the same fingerprint as separate components.
+func TestComponentEncoding(t *testing.T) {
+	var c Config
+	joined := Data{Headers: map[string]string{"a": "1|b:2"}}
+	split := Data{Headers: map[string]string{"a": "1", "b": "2"}}
+	if c.Generate(joined) == c.Generate(split) {
+		t.Error("a header holding the delimiter hashes like two headers")
+	}
+}
+
+// TestProxyHeadersExcluded checks that headers a CDN adds per request,
+// such as CF-Ray, do not split one client into several fingerprints.
+func TestProxyHeadersExcluded(t *testing.T) {
+	ray := func(id string) func(r *http.Request) {
+		return func(r *http.Request) {
+			r.Header.Set("CF-Ray", id)
+			r.Header.Set("Via", "1.1 "+id+".cloudflare.net")
+		}
+	}
+	tests := []struct {
+		name   string
+		config Config
+		same   bool
+	}{
+		{name: "default", same: true},
+		{name: "proxy headers hashed", config: Config{ProxyHeaders: []string{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, a := tt.config.FromRequest(testRequest(ray("8a1b2c3d4e5f6a7b-AKL")))
+			_, b := tt.config.FromRequest(testRequest(ray("9f8e7d6c5b4a3f2e-SYD")))
+			if (a == b) != tt.same {
+				t.Errorf("fingerprints %s and %s; want same %v", a, b, tt.same)
+			}
+		})
+	}
+}
+
+func TestSchemaVersionPrefix(t *testing.T) {
+	tests := []struct {
+		algorithm HashAlgorithm
+		hexLen    int
+	}{
+		{HashSHA256, 64},
+		{HashSHA1, 40},
+		{HashMD5, 32},
+		{HashXXHash, 16},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.algorithm), func(t *testing.T) {
+			c := Config{Hash: tt.algorithm}
+			data, hash := c.FromRequest(testRequest(nil))
+			for _, fp := range []string{hash, c.GenerateStable(data)} {
+				version, digest, ok := SplitVersion(fp)
+				if !ok || version != SchemaVersion || len(digest) != tt.hexLen {
+					t.Errorf("SplitVersion(%q) = %d, %q, %v; want version %d and %d hex digits", fp, version, digest, ok, SchemaVersion, tt.hexLen)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitVersion(t *testing.T) {
+	tests := []struct {
+		fingerprint string
+		version     int
+		digest      string
+		ok          bool
+	}{
+		{"v" + strconv.Itoa(SchemaVersion) + ":3f1a", SchemaVersion, "3f1a", true},
+		{"v1:3f1a", 1, "3f1a", true},
+		{"3f1a", 0, "", false},
+		{"v0:3f1a", 0, "", false},
+		{"vx:3f1a", 0, "", false},
+		{"v1:", 0, "", false},
+		{strings.Repeat("f", 64), 0, "", false},
+	}
+	for _, tt := range tests {
+		version, digest, ok := SplitVersion(tt.fingerprint)
+		if version != tt.version || digest != tt.digest || ok != tt.ok {
+			t.Errorf("SplitVersion(%q) = %d, %q, %v; want %d, %q, %v", tt.fingerprint, version, digest, ok, tt.version, tt.digest, tt.ok)
+		}
+	}
+}
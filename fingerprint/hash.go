@@ -0,0 +1,59 @@
+package fingerprint
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// HashAlgorithm names the digest used to turn fingerprint components into
+// a hash.
+type HashAlgorithm string
+
+// Supported hash algorithms. SHA-256 is the default; MD5, SHA-1, and
+// xxHash (XXH64) give shorter hashes or match existing systems, but are
+// not collision resistant against deliberate attacks.
+const (
+	HashSHA256 HashAlgorithm = "sha256"
+	HashSHA1   HashAlgorithm = "sha1"
+	HashMD5    HashAlgorithm = "md5"
+	HashXXHash HashAlgorithm = "xxhash"
+)
+
+// ParseHashAlgorithm returns the algorithm with the given name.
+func ParseHashAlgorithm(name string) (HashAlgorithm, error) {
+	switch algorithm := HashAlgorithm(name); algorithm {
+	case HashSHA256, HashSHA1, HashMD5, HashXXHash:
+		return algorithm, nil
+	default:
+		return "", fmt.Errorf("unknown hash algorithm %q (want sha256, sha1, md5, or xxhash)", name)
+	}
+}
+
+// New returns a new hasher for the algorithm. The empty algorithm is
+// SHA-256.
+func (a HashAlgorithm) New() hash.Hash {
+	switch a {
+	case HashSHA1:
+		return sha1.New()
+	case HashMD5:
+		return md5.New()
+	case HashXXHash:
+		return xxhash.New()
+	default:
+		return sha256.New()
+	}
+}
+
+// String returns the algorithm name, reporting the empty algorithm as
+// sha256.
+func (a HashAlgorithm) String() string {
+	if a == "" {
+		return string(HashSHA256)
+	}
+	return string(a)
+}
@@ -0,0 +1,41 @@
+package fingerprint
+
+import "testing"
+
+func TestHashAlgorithms(t *testing.T) {
+	seen := make(map[string]HashAlgorithm)
+	for _, algorithm := range []HashAlgorithm{HashSHA256, HashSHA1, HashMD5, HashXXHash} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			c := Config{Hash: algorithm}
+			data, hash := c.FromRequest(testRequest(nil))
+			if again := c.Generate(data); again != hash {
+				t.Errorf("fingerprint changed from %s to %s", hash, again)
+			}
+			if _, again := c.FromRequest(testRequest(nil)); again != hash {
+				t.Errorf("fingerprint of an identical request is %s, want %s", again, hash)
+			}
+			if other, ok := seen[hash]; ok {
+				t.Errorf("fingerprint %s is the same as with %s", hash, other)
+			}
+			seen[hash] = algorithm
+		})
+	}
+
+	var defaults Config
+	if _, got := defaults.FromRequest(testRequest(nil)); seen[got] != HashSHA256 {
+		t.Errorf("default fingerprint %s is not the SHA-256 one", got)
+	}
+}
+
+func TestParseHashAlgorithm(t *testing.T) {
+	for _, name := range []string{"sha256", "sha1", "md5", "xxhash"} {
+		if got, err := ParseHashAlgorithm(name); err != nil || string(got) != name {
+			t.Errorf("ParseHashAlgorithm(%q) = %q, %v", name, got, err)
+		}
+	}
+	for _, name := range []string{"", "SHA256", "sha512"} {
+		if _, err := ParseHashAlgorithm(name); err == nil {
+			t.Errorf("ParseHashAlgorithm(%q) succeeded", name)
+		}
+	}
+}
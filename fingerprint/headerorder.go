@@ -0,0 +1,225 @@
+package fingerprint
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// maxRecordedHead bounds the bytes buffered while looking for the end
+	// of a request head, matching http.DefaultMaxHeaderBytes.
+	maxRecordedHead = http.DefaultMaxHeaderBytes
+
+	// maxQueuedHeads bounds the heads recorded ahead of their handlers.
+	maxQueuedHeads = 16
+)
+
+// recordedHead is the header order of one request read from the wire,
+// along with its request line so it can be matched to its http.Request.
+type recordedHead struct {
+	method string
+	target string
+	order  []string
+}
+
+// orderConn records the header names of each HTTP/1.x request head in the
+// order they arrive. Bodies with a Content-Length are skipped; recording
+// stops for good at anything it cannot frame, such as a chunked body or a
+// protocol upgrade, so it never attributes the wrong order to a request.
+type orderConn struct {
+	net.Conn
+
+	mu       sync.Mutex
+	buf      []byte
+	skip     int64
+	disabled bool
+	heads    []recordedHead
+}
+
+func (c *orderConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.observe(p[:n])
+	}
+	return n, err
+}
+
+func (c *orderConn) observe(p []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(p) > 0 && !c.disabled {
+		if c.skip > 0 {
+			n := min(c.skip, int64(len(p)))
+			c.skip -= n
+			p = p[n:]
+			continue
+		}
+
+		c.buf = append(c.buf, p...)
+		p = nil
+
+		for !c.disabled && c.skip == 0 {
+			// Request heads may be preceded by stray CRLFs (RFC 9112, Section 2.2)
+			c.buf = bytes.TrimLeft(c.buf, "\r\n")
+			end := bytes.Index(c.buf, []byte("\r\n\r\n"))
+			if end < 0 {
+				if len(c.buf) > maxRecordedHead {
+					c.disable()
+				}
+				break
+			}
+			head := c.buf[:end]
+			rest := c.buf[end+4:]
+			c.parseHead(head)
+
+			// Any body bytes already read are consumed from rest first
+			n := min(c.skip, int64(len(rest)))
+			c.skip -= n
+			c.buf = append(c.buf[:0], rest[n:]...)
+		}
+	}
+}
+
+// parseHead records the header order of one request head and sets skip to
+// the length of its body.
+func (c *orderConn) parseHead(head []byte) {
+	lines := strings.Split(string(head), "\r\n")
+	method, rest, _ := strings.Cut(lines[0], " ")
+	target, proto, _ := strings.Cut(rest, " ")
+	if !strings.HasPrefix(proto, "HTTP/1.") || method == http.MethodConnect {
+		c.disable()
+		return
+	}
+
+	var order []string
+	var length int64
+	for _, line := range lines[1:] {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			c.disable()
+			return
+		}
+		order = append(order, name)
+
+		switch {
+		case strings.EqualFold(name, "Content-Length"):
+			n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil || n < 0 {
+				c.disable()
+				return
+			}
+			length = n
+		case strings.EqualFold(name, "Transfer-Encoding"), strings.EqualFold(name, "Upgrade"):
+			c.disable()
+			return
+		}
+	}
+
+	if len(c.heads) == maxQueuedHeads {
+		c.heads = c.heads[1:]
+	}
+	c.heads = append(c.heads, recordedHead{method: method, target: target, order: order})
+	c.skip = length
+}
+
+func (c *orderConn) disable() {
+	c.disabled = true
+	c.buf = nil
+	c.heads = nil
+}
+
+// take returns the recorded header order for r, dropping older heads whose
+// requests never reached the handler.
+func (c *orderConn) take(r *http.Request) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.heads) > 0 {
+		head := c.heads[0]
+		c.heads = c.heads[1:]
+		if head.method == r.Method && head.target == r.RequestURI {
+			return head.order
+		}
+	}
+	return nil
+}
+
+type orderListener struct {
+	net.Listener
+}
+
+func (l orderListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &orderConn{Conn: conn}, nil
+}
+
+type (
+	orderConnContextKey   struct{}
+	headerOrderContextKey struct{}
+)
+
+// CaptureHeaderOrder records the wire order of request headers, which
+// net/http discards by storing headers in a map. It wraps ln, which srv
+// must then serve, and srv's handler, so it must be called after the
+// handler is set. Only plain HTTP/1.x is supported: srv must not terminate
+// TLS itself, since the recording has to happen on the decrypted stream.
+func CaptureHeaderOrder(srv *http.Server, ln net.Listener) net.Listener {
+	connContext := srv.ConnContext
+	srv.ConnContext = func(ctx context.Context, conn net.Conn) context.Context {
+		if oc, ok := conn.(*orderConn); ok {
+			ctx = context.WithValue(ctx, orderConnContextKey{}, oc)
+		}
+		if connContext != nil {
+			return connContext(ctx, conn)
+		}
+		return ctx
+	}
+
+	// Every request must take its recorded head, even ones that are not
+	// fingerprinted, to keep the queue aligned with the connection
+	next := srv.Handler
+	if next == nil {
+		next = http.DefaultServeMux
+	}
+	srv.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if oc, ok := r.Context().Value(orderConnContextKey{}).(*orderConn); ok && r.ProtoMajor == 1 {
+			if order := oc.take(r); order != nil {
+				r = r.WithContext(context.WithValue(r.Context(), headerOrderContextKey{}, order))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+
+	return orderListener{ln}
+}
+
+// HeaderOrderFromContext returns the request header names in the order
+// they were received, as sent on the wire, or nil if the order was not
+// captured.
+func HeaderOrderFromContext(ctx context.Context) []string {
+	order, _ := ctx.Value(headerOrderContextKey{}).([]string)
+	return order
+}
+
+// WithHeaderOrder returns a copy of ctx carrying order as the request's
+// header order, for callers that remove headers from a request before
+// fingerprinting it.
+func WithHeaderOrder(ctx context.Context, order []string) context.Context {
+	return context.WithValue(ctx, headerOrderContextKey{}, order)
+}
+
+// HeaderOrderHash returns a short hash of a header order for use as a
+// fingerprint component. Header name case is kept, since clients differ in
+// it too.
+func HeaderOrderHash(order []string) string {
+	return ja4Hash(strings.Join(order, ","))
+}
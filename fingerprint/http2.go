@@ -0,0 +1,402 @@
+package fingerprint
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// HTTP/2 frame types and flags used by the fingerprint (RFC 9113).
+const (
+	h2FrameHeaders      = 0x1
+	h2FramePriority     = 0x2
+	h2FrameSettings     = 0x4
+	h2FrameWindowUpdate = 0x8
+
+	h2FlagAck      = 0x1
+	h2FlagPadded   = 0x8
+	h2FlagPriority = 0x20
+
+	h2ClientPreface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+	// h2MaxRecorded bounds how many bytes are buffered while waiting for
+	// the first HEADERS frame.
+	h2MaxRecorded = 64 << 10
+)
+
+// h2StaticPseudo maps HPACK static table indices (RFC 7541, Appendix A) to
+// the pseudo-header letter used in the fingerprint.
+var h2StaticPseudo = map[uint64]string{
+	1: "a", // :authority
+	2: "m", // :method GET
+	3: "m", // :method POST
+	4: "p", // :path /
+	5: "p", // :path /index.html
+	6: "s", // :scheme http
+	7: "s", // :scheme https
+}
+
+// h2Conn wraps the TLS connection of an HTTP/2 client and records the
+// frames it sends before its first request: SETTINGS, WINDOW_UPDATE,
+// PRIORITY, and the pseudo-header order of the first HEADERS frame. This
+// is the basis of the Akamai HTTP/2 fingerprint.
+type h2Conn struct {
+	*tls.Conn
+	ctx     context.Context
+	handler http.Handler
+
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	mu          sync.Mutex
+	buf         []byte
+	sawPreface  bool
+	done        bool
+	settings    []string
+	window      uint32
+	priorities  []string
+	pseudo      []string
+	fingerprint string
+}
+
+func (c *h2Conn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.observe(p[:n])
+	}
+	return n, err
+}
+
+func (c *h2Conn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(func() { close(c.closed) })
+	return err
+}
+
+// observe feeds bytes read from the client into the frame parser until
+// the first HEADERS frame has been seen.
+func (c *h2Conn) observe(p []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.done {
+		return
+	}
+	c.buf = append(c.buf, p...)
+
+	if !c.sawPreface {
+		if len(c.buf) < len(h2ClientPreface) {
+			return
+		}
+		if string(c.buf[:len(h2ClientPreface)]) != h2ClientPreface {
+			c.finish()
+			return
+		}
+		c.buf = c.buf[len(h2ClientPreface):]
+		c.sawPreface = true
+	}
+
+	for len(c.buf) >= 9 && !c.done {
+		length := int(c.buf[0])<<16 | int(c.buf[1])<<8 | int(c.buf[2])
+		if len(c.buf) < 9+length {
+			break
+		}
+		frameType, flags := c.buf[3], c.buf[4]
+		streamID := binary.BigEndian.Uint32(c.buf[5:9]) & 0x7fffffff
+		payload := c.buf[9 : 9+length]
+
+		switch frameType {
+		case h2FrameSettings:
+			if flags&h2FlagAck == 0 {
+				for i := 0; i+6 <= len(payload); i += 6 {
+					id := binary.BigEndian.Uint16(payload[i:])
+					value := binary.BigEndian.Uint32(payload[i+2:])
+					c.settings = append(c.settings, fmt.Sprintf("%d:%d", id, value))
+				}
+			}
+		case h2FrameWindowUpdate:
+			if streamID == 0 && len(payload) == 4 {
+				c.window = binary.BigEndian.Uint32(payload) & 0x7fffffff
+			}
+		case h2FramePriority:
+			if len(payload) == 5 {
+				c.priorities = append(c.priorities, h2Priority(streamID, payload))
+			}
+		case h2FrameHeaders:
+			if flags&h2FlagPadded != 0 && len(payload) > 0 {
+				padding := int(payload[0])
+				if padding < len(payload) {
+					payload = payload[1 : len(payload)-padding]
+				}
+			}
+			if flags&h2FlagPriority != 0 && len(payload) >= 5 {
+				payload = payload[5:]
+			}
+			c.pseudo = h2PseudoHeaderOrder(payload)
+			c.finish()
+		}
+
+		if !c.done {
+			c.buf = c.buf[9+length:]
+		}
+	}
+
+	if len(c.buf) > h2MaxRecorded {
+		c.finish()
+	}
+}
+
+// finish stops recording and renders the fingerprint in the Akamai
+// format: settings|window_update|priorities|pseudo_header_order.
+func (c *h2Conn) finish() {
+	c.done = true
+	c.buf = nil
+
+	if len(c.settings) == 0 && len(c.pseudo) == 0 {
+		return
+	}
+
+	window := "00"
+	if c.window != 0 {
+		window = strconv.FormatUint(uint64(c.window), 10)
+	}
+	priorities := "0"
+	if len(c.priorities) > 0 {
+		priorities = strings.Join(c.priorities, ",")
+	}
+
+	c.fingerprint = strings.Join([]string{
+		strings.Join(c.settings, ";"),
+		window,
+		priorities,
+		strings.Join(c.pseudo, ","),
+	}, "|")
+}
+
+func (c *h2Conn) Fingerprint() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fingerprint
+}
+
+// h2Priority renders a PRIORITY frame as stream:exclusive:dependency:weight.
+func h2Priority(streamID uint32, payload []byte) string {
+	dependency := binary.BigEndian.Uint32(payload)
+	exclusive := dependency >> 31
+	return fmt.Sprintf("%d:%d:%d:%d", streamID, exclusive, dependency&0x7fffffff, int(payload[4])+1)
+}
+
+// h2PseudoHeaderOrder returns the pseudo-header letters in the order they
+// appear in an HPACK header block. Only the header names are decoded: on
+// the first request the dynamic table is empty, so pseudo-headers are
+// either static table references or literal names. Decoding stops at the
+// first regular header, since pseudo-headers must come first, or at a
+// Huffman-encoded literal name, which browsers do not use for them.
+func h2PseudoHeaderOrder(block []byte) []string {
+	var order []string
+	for len(block) > 0 {
+		b := block[0]
+		var index uint64
+		var ok bool
+
+		switch {
+		case b&0x80 != 0: // indexed header field
+			index, block, ok = hpackInteger(block, 7)
+			if !ok {
+				return order
+			}
+			letter, pseudo := h2StaticPseudo[index]
+			if !pseudo {
+				return order
+			}
+			order = append(order, letter)
+			continue
+		case b&0xe0 == 0x20: // dynamic table size update
+			if _, block, ok = hpackInteger(block, 5); !ok {
+				return order
+			}
+			continue
+		case b&0xc0 == 0x40: // literal with incremental indexing
+			index, block, ok = hpackInteger(block, 6)
+		default: // literal without indexing or never indexed
+			index, block, ok = hpackInteger(block, 4)
+		}
+		if !ok {
+			return order
+		}
+
+		if index != 0 {
+			letter, pseudo := h2StaticPseudo[index]
+			if !pseudo {
+				return order
+			}
+			order = append(order, letter)
+		} else {
+			var name []byte
+			var huffman bool
+			if name, huffman, block, ok = hpackString(block); !ok || huffman {
+				return order
+			}
+			if len(name) < 2 || name[0] != ':' {
+				return order
+			}
+			order = append(order, string(name[1]))
+		}
+
+		// Skip the header value
+		if _, _, block, ok = hpackString(block); !ok {
+			return order
+		}
+	}
+	return order
+}
+
+// hpackInteger decodes an HPACK integer with an n-bit prefix (RFC 7541,
+// Section 5.1).
+func hpackInteger(b []byte, n uint) (uint64, []byte, bool) {
+	if len(b) == 0 {
+		return 0, b, false
+	}
+	mask := uint64(1)<<n - 1
+	value := uint64(b[0]) & mask
+	b = b[1:]
+	if value < mask {
+		return value, b, true
+	}
+
+	for shift := uint(0); len(b) > 0 && shift < 63; shift += 7 {
+		c := b[0]
+		b = b[1:]
+		value += uint64(c&0x7f) << shift
+		if c&0x80 == 0 {
+			return value, b, true
+		}
+	}
+	return 0, b, false
+}
+
+// hpackString returns the raw bytes of an HPACK string literal and whether
+// they are Huffman encoded (RFC 7541, Section 5.2).
+func hpackString(b []byte) ([]byte, bool, []byte, bool) {
+	if len(b) == 0 {
+		return nil, false, b, false
+	}
+	huffman := b[0]&0x80 != 0
+	length, rest, ok := hpackInteger(b, 7)
+	if !ok || uint64(len(rest)) < length {
+		return nil, false, b, false
+	}
+	return rest[:length], huffman, rest[length:], true
+}
+
+type h2ContextKey struct{}
+
+// HTTP2FingerprintFromContext returns the HTTP/2 fingerprint of the
+// connection that ctx belongs to, or "" for non-HTTP/2 requests.
+func HTTP2FingerprintFromContext(ctx context.Context) string {
+	if conn, ok := ctx.Value(h2ContextKey{}).(*h2Conn); ok {
+		return conn.Fingerprint()
+	}
+	return ""
+}
+
+// InstallHTTP2Capture enables HTTP/2 fingerprinting on srv, which must
+// serve TLS. The standard library does not expose HTTP/2 frames, so srv
+// hands negotiated h2 connections to an internal server that speaks HTTP/2
+// over the already-decrypted stream while the frames are recorded. It must
+// be called before srv starts serving and after its timeouts are set.
+func InstallHTTP2Capture(srv *http.Server) {
+	if srv.TLSConfig == nil {
+		srv.TLSConfig = &tls.Config{}
+	}
+	for _, proto := range []string{"h2", "http/1.1"} {
+		if !slices.Contains(srv.TLSConfig.NextProtos, proto) {
+			srv.TLSConfig.NextProtos = append(srv.TLSConfig.NextProtos, proto)
+		}
+	}
+
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+
+	listener := &connListener{conns: make(chan net.Conn), closed: make(chan struct{})}
+	inner := &http.Server{
+		ReadTimeout:       srv.ReadTimeout,
+		ReadHeaderTimeout: srv.ReadHeaderTimeout,
+		WriteTimeout:      srv.WriteTimeout,
+		IdleTimeout:       srv.IdleTimeout,
+		MaxHeaderBytes:    srv.MaxHeaderBytes,
+		HTTP2:             srv.HTTP2,
+		ErrorLog:          srv.ErrorLog,
+		Protocols:         &protocols,
+		// Requests see the outer connection's context, so values such
+		// as the captured ClientHello remain available to handlers
+		ConnContext: func(_ context.Context, conn net.Conn) context.Context {
+			h2 := conn.(*h2Conn)
+			return context.WithValue(h2.ctx, h2ContextKey{}, h2)
+		},
+		// The outer handler fills in Request.TLS and RemoteAddr from
+		// the TLS connection before calling srv's handler
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Context().Value(h2ContextKey{}).(*h2Conn).handler.ServeHTTP(w, r)
+		}),
+	}
+	go inner.Serve(listener)
+	srv.RegisterOnShutdown(func() {
+		listener.Close()
+		inner.Shutdown(context.Background())
+	})
+
+	if srv.TLSNextProto == nil {
+		srv.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+	srv.TLSNextProto["h2"] = func(_ *http.Server, tlsConn *tls.Conn, h http.Handler) {
+		ctx := context.Background()
+		if bc, ok := h.(interface{ BaseContext() context.Context }); ok {
+			ctx = bc.BaseContext()
+		}
+
+		conn := &h2Conn{Conn: tlsConn, ctx: ctx, handler: h, closed: make(chan struct{})}
+		select {
+		case listener.conns <- conn:
+		case <-listener.closed:
+			return
+		}
+
+		// srv closes the connection when this function returns, so wait
+		// for the internal server to finish with it
+		<-conn.closed
+	}
+}
+
+// connListener is a net.Listener that yields connections handed to it by
+// InstallHTTP2Capture.
+type connListener struct {
+	conns     chan net.Conn
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *connListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *connListener) Addr() net.Addr {
+	return &net.TCPAddr{}
+}
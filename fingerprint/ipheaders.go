@@ -0,0 +1,130 @@
+package fingerprint
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// The headers proxies and CDNs use to pass on the client IP: chain headers
+// carry a hop per proxy, address headers one address.
+var (
+	ipChainHeaders   = []string{"Forwarded", "X-Forwarded-For"}
+	ipAddressHeaders = []string{"X-Real-IP", "True-Client-IP", "CF-Connecting-IP", "X-Client-IP", "X-Cluster-Client-IP"}
+)
+
+// addressListHeaders carry comma-separated IP addresses: the chain and
+// address headers, and the copy of X-Forwarded-For some ingress
+// controllers keep. Forwarded and Via also carry addresses, among other
+// parameters.
+var addressListHeaders = slices.Concat([]string{"X-Forwarded-For", "X-Original-Forwarded-For"}, ipAddressHeaders)
+
+// AddressListHeaders returns the names of the headers whose values are
+// comma-separated IP addresses, with or without ports, such as
+// X-Forwarded-For and CF-Connecting-IP. Forwarded and Via also carry
+// addresses, mixed with other parameters.
+func AddressListHeaders() []string {
+	return slices.Clone(addressListHeaders)
+}
+
+// IPHeaderCheck is the result of CheckIPHeaders.
+type IPHeaderCheck struct {
+	// Checked is false when the request sent no IP header
+	Checked  bool
+	Conflict bool
+	// Reason explains a conflict
+	Reason string
+	// Values maps the name of each IP header sent to its values, one per
+	// header line
+	Values map[string][]string
+}
+
+// CheckIPHeaders reports whether the headers of a request that carry the
+// client IP contradict each other. Proxies each write one view of the same
+// connection, so disagreement means a client wrote some of them itself to
+// spoof its address. The headers conflict when:
+//
+//   - one is sent more than once with different values, such as two
+//     X-Forwarded-For chains
+//   - a single-address header such as X-Real-IP is not an IP address
+//   - X-Forwarded-For and Forwarded name no hop in common
+//   - a single-address header names a hop missing from those chains, or,
+//     without a chain, a different address than another one
+//
+// A proxy setting X-Real-IP to its own peer, a hop inside the chain, is
+// consistent. The headers are checked wherever the request came from.
+// A conflict never lets the client choose its address: Config.ClientIP
+// only reads the header the trusted proxies write, walks a chain from the
+// proxy's end, and falls back to the peer address when that header is a
+// single address sent with different values.
+func CheckIPHeaders(header http.Header) IPHeaderCheck {
+	names := slices.Concat(ipChainHeaders, ipAddressHeaders)
+	values := make(map[string][]string)
+	for _, name := range names {
+		if v := header.Values(name); len(v) > 0 {
+			values[name] = v
+		}
+	}
+	if len(values) == 0 {
+		return IPHeaderCheck{}
+	}
+	check := IPHeaderCheck{Checked: true, Values: values}
+	conflict := func(format string, args ...any) IPHeaderCheck {
+		check.Conflict, check.Reason = true, fmt.Sprintf(format, args...)
+		return check
+	}
+
+	for _, name := range names {
+		v := values[name]
+		if len(v) > 1 && slices.ContainsFunc(v[1:], func(value string) bool { return value != v[0] }) {
+			return conflict("%s sent %d times with different values", name, len(v))
+		}
+	}
+
+	forwarded := parseHops(forwardedChain(header))
+	xff := parseHops(xForwardedForChain(header))
+	if len(forwarded) > 0 && len(xff) > 0 && !slices.ContainsFunc(xff, func(hop netip.Addr) bool {
+		return slices.Contains(forwarded, hop)
+	}) {
+		return conflict("X-Forwarded-For and Forwarded share no hop")
+	}
+	chain := append(forwarded, xff...)
+
+	var first string
+	var firstAddr netip.Addr
+	for _, name := range ipAddressHeaders {
+		v := values[name]
+		if len(v) == 0 {
+			continue
+		}
+		value := strings.TrimSpace(v[0])
+		addr, err := parseHostAddr(value)
+		switch {
+		case err != nil:
+			return conflict("%s %q is not an IP address", name, value)
+		case len(chain) > 0:
+			if !slices.Contains(chain, addr) {
+				return conflict("%s %s is not in the forwarding chain", name, addr)
+			}
+		case first == "":
+			first, firstAddr = name, addr
+		case addr != firstAddr:
+			return conflict("%s %s and %s %s disagree", first, firstAddr, name, addr)
+		}
+	}
+	return check
+}
+
+// parseHops returns the addresses of a forwarding chain, skipping hops
+// that are obfuscated, unknown, or malformed.
+func parseHops(chain []string) []netip.Addr {
+	var hops []netip.Addr
+	for _, hop := range chain {
+		if addr, err := parseHostAddr(hop); err == nil {
+			hops = append(hops, addr)
+		}
+	}
+	return hops
+}
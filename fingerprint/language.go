@@ -0,0 +1,108 @@
+package fingerprint
+
+import (
+	"slices"
+	"strings"
+)
+
+// Language is one entry of an Accept-Language header.
+type Language struct {
+	Tag     string  `json:"tag"`
+	Quality float64 `json:"q"`
+}
+
+// ParseAcceptLanguage parses an Accept-Language header (RFC 9110, Section
+// 12.5.4) and returns its languages ordered by descending quality, keeping
+// header order between equal weights. Tags are normalized to the usual
+// case, such as en-US and zh-Hant-TW. Malformed entries and entries with
+// q=0 are dropped.
+func ParseAcceptLanguage(header string) []Language {
+	var languages []Language
+	for _, entry := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(entry, ";")
+		tag = strings.TrimSpace(tag)
+		if !validLanguageRange(tag) {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(name), "q") {
+				continue
+			}
+			quality = parseQuality(value)
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		languages = append(languages, Language{Tag: normalizeLanguageTag(tag), Quality: quality})
+	}
+
+	slices.SortStableFunc(languages, func(a, b Language) int {
+		switch {
+		case a.Quality > b.Quality:
+			return -1
+		case a.Quality < b.Quality:
+			return 1
+		default:
+			return 0
+		}
+	})
+	return languages
+}
+
+// PreferredLanguage returns the highest-weighted language in an
+// Accept-Language header, ignoring the "*" wildcard, or "" if there is none.
+func PreferredLanguage(header string) string {
+	for _, lang := range ParseAcceptLanguage(header) {
+		if lang.Tag != "*" {
+			return lang.Tag
+		}
+	}
+	return ""
+}
+
+// validLanguageRange reports whether s is "*" or a sequence of 1-8
+// character alphanumeric subtags separated by hyphens, with an alphabetic
+// first subtag.
+func validLanguageRange(s string) bool {
+	if s == "*" {
+		return true
+	}
+	if s == "" {
+		return false
+	}
+	for i, subtag := range strings.Split(s, "-") {
+		if len(subtag) == 0 || len(subtag) > 8 {
+			return false
+		}
+		for _, c := range subtag {
+			alpha := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+			digit := c >= '0' && c <= '9'
+			if !alpha && !(digit && i > 0) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// normalizeLanguageTag applies the BCP 47 case conventions: lowercase
+// language, title-case four-letter scripts, and uppercase two-letter regions.
+func normalizeLanguageTag(tag string) string {
+	subtags := strings.Split(strings.ToLower(tag), "-")
+	for i := 1; i < len(subtags); i++ {
+		switch len(subtags[i]) {
+		case 1:
+			// Extensions and private use subtags keep lowercase
+			return strings.Join(subtags, "-")
+		case 2:
+			subtags[i] = strings.ToUpper(subtags[i])
+		case 4:
+			subtags[i] = strings.ToUpper(subtags[i][:1]) + subtags[i][1:]
+		}
+	}
+	return strings.Join(subtags, "-")
+}
@@ -0,0 +1,44 @@
+package fingerprint
+
+import (
+	"context"
+	"net/http"
+)
+
+// Result is the fingerprint Middleware computed for a request.
+type Result struct {
+	Data        Data
+	Fingerprint string
+}
+
+type resultContextKey struct{}
+
+// Middleware fingerprints every request with the default configuration
+// before calling next, which can read the result with FromContext.
+func Middleware(next http.Handler) http.Handler {
+	return defaultConfig.Middleware(next)
+}
+
+// Middleware fingerprints every request using c before calling next, which
+// can read the result with FromContext. It fits any router that accepts
+// func(http.Handler) http.Handler middleware, such as chi and gorilla/mux.
+func (c *Config) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, hash := c.FromRequest(r)
+		ctx := NewContext(r.Context(), Result{Data: data, Fingerprint: hash})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// NewContext returns a copy of ctx carrying result, for callers that
+// compute fingerprints themselves.
+func NewContext(ctx context.Context, result Result) context.Context {
+	return context.WithValue(ctx, resultContextKey{}, result)
+}
+
+// FromContext returns the fingerprint stored in ctx by Middleware, and
+// false if there is none.
+func FromContext(ctx context.Context) (Result, bool) {
+	result, ok := ctx.Value(resultContextKey{}).(Result)
+	return result, ok
+}
@@ -0,0 +1,98 @@
+package fingerprint
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Preference is one entry of a content negotiation header: Accept,
+// Accept-Encoding, or Accept-Charset. Value is lower-cased and keeps any
+// parameters other than q, as in "application/signed-exchange;v=b3".
+type Preference struct {
+	Value   string  `json:"value"`
+	Quality float64 `json:"q"`
+}
+
+// ParsePreferences parses a content negotiation header (RFC 9110, Section
+// 12.4.2) and returns its entries ordered by descending quality, keeping
+// header order between equal weights, in the same way ParseAcceptLanguage
+// does. Entries with a malformed or out-of-range q-value, such as q=2 or
+// q=abc, and entries with q=0 are dropped.
+func ParsePreferences(header string) []Preference {
+	var prefs []Preference
+	for _, element := range splitQuoted(header, ',') {
+		value, params, _ := cutQuoted(element, ';')
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range splitQuoted(params, ';') {
+			name, arg, _ := cutQuoted(param, '=')
+			name = strings.TrimSpace(name)
+			switch {
+			case name == "":
+			case strings.EqualFold(name, "q"):
+				quality = parseQuality(arg)
+			default:
+				value += ";" + lowerUnquoted(name+"="+strings.TrimSpace(arg))
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		prefs = append(prefs, Preference{Value: value, Quality: quality})
+	}
+
+	slices.SortStableFunc(prefs, func(a, b Preference) int {
+		return cmp.Compare(b.Quality, a.Quality)
+	})
+	return prefs
+}
+
+// CanonicalPreferences returns a content negotiation header in a canonical
+// form that depends only on what the client accepts: entries are parsed as
+// by ParsePreferences, ordered by descending quality and then by value, and
+// written without whitespace or a redundant q=1, as in
+// "br,gzip,deflate;q=0.5". Entries ParsePreferences drops are left out.
+func CanonicalPreferences(header string) string {
+	prefs := ParsePreferences(header)
+	slices.SortStableFunc(prefs, func(a, b Preference) int {
+		return cmp.Or(cmp.Compare(b.Quality, a.Quality), strings.Compare(a.Value, b.Value))
+	})
+
+	elements := make([]string, len(prefs))
+	for i, pref := range prefs {
+		elements[i] = pref.Value
+		if pref.Quality != 1 {
+			elements[i] += ";q=" + strconv.FormatFloat(pref.Quality, 'f', -1, 64)
+		}
+	}
+	return strings.Join(elements, ",")
+}
+
+// canonicalizeData applies CanonicalPreferences to the content negotiation
+// headers in data.
+func canonicalizeData(data *Data) {
+	data.Accept = CanonicalPreferences(data.Accept)
+	data.AcceptEnc = CanonicalPreferences(data.AcceptEnc)
+	for _, name := range []string{"accept", "accept-encoding", "accept-charset"} {
+		if value, ok := data.Headers[name]; ok {
+			data.Headers[name] = CanonicalPreferences(value)
+		}
+	}
+}
+
+// parseQuality parses a q-value, returning -1 when it is malformed or
+// outside 0 to 1.
+func parseQuality(value string) float64 {
+	q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || !(q >= 0 && q <= 1) {
+		return -1
+	}
+	return q
+}
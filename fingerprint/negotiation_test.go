@@ -0,0 +1,80 @@
+package fingerprint
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParsePreferences(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []Preference
+	}{
+		{
+			name:   "ordered by quality",
+			header: "text/html;q=0.8, application/json, */*;q=0.1",
+			want:   []Preference{{"application/json", 1}, {"text/html", 0.8}, {"*/*", 0.1}},
+		},
+		{
+			name:   "equal weights keep header order",
+			header: "gzip, deflate, br",
+			want:   []Preference{{"gzip", 1}, {"deflate", 1}, {"br", 1}},
+		},
+		{
+			name:   "parameters other than q are kept",
+			header: `application/signed-exchange;v=b3;q=0.7, Text/HTML`,
+			want:   []Preference{{"text/html", 1}, {"application/signed-exchange;v=b3", 0.7}},
+		},
+		{
+			name:   "q above 1 is dropped",
+			header: "gzip;q=2, br",
+			want:   []Preference{{"br", 1}},
+		},
+		{
+			name:   "non-numeric q is dropped",
+			header: "utf-8, iso-8859-1;q=abc",
+			want:   []Preference{{"utf-8", 1}},
+		},
+		{
+			name:   "q=0 is dropped",
+			header: "identity;q=0, gzip;Q=0.5",
+			want:   []Preference{{"gzip", 0.5}},
+		},
+		{
+			name:   "negative and empty q are dropped",
+			header: "a;q=-0.5, b;q=, c",
+			want:   []Preference{{"c", 1}},
+		},
+		{
+			name:   "empty elements are skipped",
+			header: " , gzip,, ",
+			want:   []Preference{{"gzip", 1}},
+		},
+		{name: "empty header"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParsePreferences(tt.header); !slices.Equal(got, tt.want) {
+				t.Errorf("ParsePreferences(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalPreferences(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"gzip, deflate, br", "br,deflate,gzip"},
+		{"br;q=1.0,gzip ,  deflate;q=0.50", "br,gzip,deflate;q=0.5"},
+		{"gzip;q=2, br;q=abc, identity;q=0", ""},
+		{"*/*;q=0.8, text/html", "text/html,*/*;q=0.8"},
+	}
+	for _, tt := range tests {
+		if got := CanonicalPreferences(tt.header); got != tt.want {
+			t.Errorf("CanonicalPreferences(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package fingerprint
+
+import (
+	"sort"
+	"strings"
+)
+
+// normalizeRule describes how a list-valued header may be rewritten
+// without changing its meaning. Every normalized header has whitespace
+// around its ",", ";", and "=" separators removed.
+type normalizeRule struct {
+	// lower lowercases the value outside quoted strings, for headers whose
+	// tokens are case-insensitive
+	lower bool
+	// sort orders the list elements, for headers whose order carries no
+	// meaning
+	sort bool
+}
+
+// headerNormalization lists the headers rewritten when normalization is
+// enabled. Headers not listed are hashed exactly as sent.
+var headerNormalization = map[string]normalizeRule{
+	"accept":                      {lower: true},
+	"accept-charset":              {lower: true},
+	"accept-encoding":             {lower: true, sort: true},
+	"accept-language":             {lower: true},
+	"cache-control":               {lower: true, sort: true},
+	"connection":                  {lower: true, sort: true},
+	"pragma":                      {lower: true, sort: true},
+	"te":                          {lower: true, sort: true},
+	"sec-ch-ua":                   {},
+	"sec-ch-ua-full-version-list": {},
+}
+
+// NormalizeHeader returns value rewritten into a canonical form when the
+// named header has semantically equivalent spellings, so that for example
+// "gzip, deflate, br" and "br,gzip,deflate" hash the same. Other headers
+// are returned unchanged. Header names are matched case-insensitively.
+func NormalizeHeader(name, value string) string {
+	rule, ok := headerNormalization[strings.ToLower(name)]
+	if !ok {
+		return value
+	}
+
+	var elements []string
+	for _, element := range splitQuoted(value, ',') {
+		var params []string
+		for _, param := range splitQuoted(element, ';') {
+			key, val, hasValue := cutQuoted(param, '=')
+			param = strings.TrimSpace(key)
+			if hasValue {
+				param += "=" + strings.TrimSpace(val)
+			}
+			if param != "" {
+				params = append(params, param)
+			}
+		}
+		if len(params) == 0 {
+			continue
+		}
+
+		element = strings.Join(params, ";")
+		if rule.lower {
+			element = lowerUnquoted(element)
+		}
+		elements = append(elements, element)
+	}
+
+	if rule.sort {
+		sort.Strings(elements)
+	}
+	return strings.Join(elements, ", ")
+}
+
+// normalizeData applies NormalizeHeader to the headers in data.
+func normalizeData(data *Data) {
+	data.Accept = NormalizeHeader("Accept", data.Accept)
+	data.AcceptEnc = NormalizeHeader("Accept-Encoding", data.AcceptEnc)
+	data.AcceptLang = NormalizeHeader("Accept-Language", data.AcceptLang)
+	for name, value := range data.Headers {
+		data.Headers[name] = NormalizeHeader(name, value)
+	}
+}
+
+// splitQuoted splits s at sep, ignoring separators inside double-quoted
+// strings.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	for {
+		before, after, found := cutQuoted(s, sep)
+		parts = append(parts, before)
+		if !found {
+			return parts
+		}
+		s = after
+	}
+}
+
+// cutQuoted is strings.Cut for a single-byte separator that skips
+// separators inside double-quoted strings, honoring backslash escapes.
+func cutQuoted(s string, sep byte) (string, string, bool) {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c == sep:
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+// lowerUnquoted lowercases s except inside double-quoted strings, whose
+// contents may be case-sensitive.
+func lowerUnquoted(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quoted && c == '\\' && i+1 < len(s):
+			b.WriteByte(c)
+			i++
+			c = s[i]
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c >= 'A' && c <= 'Z':
+			c += 'a' - 'A'
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,112 @@
+package fingerprint
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// ProtoCheck is the result of CheckForwardedProto.
+type ProtoCheck struct {
+	// Checked is false when the request sent no X-Forwarded-Proto,
+	// X-Forwarded-Port, or Forwarded proto=
+	Checked  bool
+	Mismatch bool
+	// Reason explains a mismatch
+	Reason string
+}
+
+// CheckForwardedProto compares the scheme and port that the forwarding
+// headers of r claim with the connection r actually arrived on. A trusted
+// proxy in front of the server terminates TLS and listens on its own port,
+// so its headers are believed and never flagged. From any other client
+// they describe a proxy the server does not know about, or are forged, so
+// a scheme or port the connection contradicts is reported as a mismatch.
+//
+// The scheme is taken from the first X-Forwarded-Proto value, or the first
+// Forwarded proto= when there is none, and the port from the first
+// X-Forwarded-Port value. The port is only compared when r carries the
+// local address in its context, as requests from http.Server do.
+func CheckForwardedProto(r *http.Request, trusted []netip.Prefix) ProtoCheck {
+	proto, protoHeader := forwardedProto(r.Header)
+	port := firstValue(r.Header.Get("X-Forwarded-Port"))
+	if proto == "" && port == "" {
+		return ProtoCheck{}
+	}
+	if remote, err := parseHostAddr(r.RemoteAddr); err == nil && isTrusted(remote, trusted) {
+		return ProtoCheck{Checked: true}
+	}
+
+	if proto != "" {
+		actual := "http"
+		if r.TLS != nil {
+			actual = "https"
+		}
+		switch strings.ToLower(proto) {
+		case "http", "ws":
+			proto = "http"
+		case "https", "wss":
+			proto = "https"
+		default:
+			return protoMismatch("unknown %s %q", protoHeader, proto)
+		}
+		if proto != actual {
+			return protoMismatch("%s is %s, but the request arrived over %s", protoHeader, proto, actual)
+		}
+	}
+
+	if port != "" {
+		claimed, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return protoMismatch("invalid X-Forwarded-Port %q", port)
+		}
+		if actual, ok := localPort(r); ok && uint16(claimed) != actual {
+			return protoMismatch("X-Forwarded-Port is %d, but the request arrived on port %d", claimed, actual)
+		}
+	}
+	return ProtoCheck{Checked: true}
+}
+
+func protoMismatch(format string, args ...any) ProtoCheck {
+	return ProtoCheck{Checked: true, Mismatch: true, Reason: fmt.Sprintf(format, args...)}
+}
+
+// forwardedProto returns the claimed scheme and the header it came from.
+func forwardedProto(header http.Header) (proto, name string) {
+	if proto := firstValue(header.Get("X-Forwarded-Proto")); proto != "" {
+		return proto, "X-Forwarded-Proto"
+	}
+	for _, value := range header.Values("Forwarded") {
+		for _, element := range splitQuoted(value, ',') {
+			for _, pair := range splitQuoted(element, ';') {
+				key, val, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				if strings.EqualFold(strings.TrimSpace(key), "proto") {
+					return unquote(strings.TrimSpace(val)), "Forwarded proto="
+				}
+			}
+		}
+	}
+	return "", ""
+}
+
+// firstValue returns the first element of a comma-separated list, trimmed.
+func firstValue(list string) string {
+	first, _, _ := strings.Cut(list, ",")
+	return strings.TrimSpace(first)
+}
+
+// localPort returns the port of the listener r arrived on.
+func localPort(r *http.Request) (uint16, bool) {
+	addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if !ok {
+		return 0, false
+	}
+	addrPort, err := netip.ParseAddrPort(addr.String())
+	if err != nil {
+		return 0, false
+	}
+	return addrPort.Port(), true
+}
@@ -0,0 +1,32 @@
+package fingerprint
+
+import "strings"
+
+// Normalized HTTP protocol versions, as hashed in the protocol component.
+const (
+	ProtocolHTTP10 = "h1.0"
+	ProtocolHTTP11 = "h1.1"
+	ProtocolHTTP2  = "h2"
+	ProtocolHTTP3  = "h3"
+)
+
+// NormalizeProtocol maps a request protocol, as in http.Request.Proto, onto
+// one of ProtocolHTTP10, ProtocolHTTP11, ProtocolHTTP2, or ProtocolHTTP3,
+// so the spellings servers and log formats use, such as "HTTP/2.0",
+// "HTTP/2", and "h2", hash the same. The ALPN identifiers "http/1.0" and
+// "http/1.1" are accepted too. Any other value is returned lowercased, so
+// unknown protocols stay distinct from each other.
+func NormalizeProtocol(proto string) string {
+	switch p := strings.ToLower(strings.TrimSpace(proto)); p {
+	case "http/1.0", "h1.0":
+		return ProtocolHTTP10
+	case "http/1.1", "h1.1":
+		return ProtocolHTTP11
+	case "http/2.0", "http/2", "h2", "h2c":
+		return ProtocolHTTP2
+	case "http/3.0", "http/3", "h3":
+		return ProtocolHTTP3
+	default:
+		return p
+	}
+}
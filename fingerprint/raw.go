@@ -0,0 +1,57 @@
+package fingerprint
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ReadRawRequest parses a raw HTTP/1.x request, such as a request head
+// saved from the wire for forensics, and returns it with its header order
+// attached to its context, so FromRequest fingerprints the order as
+// CaptureHeaderOrder would for a live request. Bare LF line endings are
+// accepted, and a head missing its terminating blank line is completed.
+// The body, if any, is left unread.
+func ReadRawRequest(raw []byte) (*http.Request, error) {
+	// Request heads may be preceded by stray CRLFs (RFC 9112, Section 2.2)
+	raw = bytes.TrimLeft(raw, "\r\n")
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil, errors.New("empty request")
+	}
+	if !bytes.Contains(raw, []byte("\n\n")) && !bytes.Contains(raw, []byte("\n\r\n")) {
+		raw = slices.Concat(bytes.TrimRight(raw, "\r\n"), []byte("\r\n\r\n"))
+	}
+
+	r, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(raw)))
+	if err != nil {
+		return nil, fmt.Errorf("parse request: %w", err)
+	}
+	if r.ProtoMajor != 1 {
+		return nil, fmt.Errorf("unsupported protocol %s: only HTTP/1.x requests have a text form", r.Proto)
+	}
+	return r.WithContext(WithHeaderOrder(r.Context(), rawHeaderOrder(raw))), nil
+}
+
+// rawHeaderOrder returns the header names of a request head that
+// http.ReadRequest accepted, in the order they appear and as written.
+// Obsolete line folding continues the previous header, so it is skipped.
+func rawHeaderOrder(raw []byte) []string {
+	var order []string
+	lines := strings.Split(string(raw), "\n")
+	for _, line := range lines[1:] {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			break
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		name, _, _ := strings.Cut(line, ":")
+		order = append(order, name)
+	}
+	return order
+}
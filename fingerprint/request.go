@@ -0,0 +1,317 @@
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// defaultHeaders are the headers that are useful for fingerprinting.
+var defaultHeaders = []string{
+	"User-Agent",
+	"Accept",
+	"Accept-Language",
+	"Accept-Encoding",
+	"Accept-Charset",
+	"Connection",
+	"Upgrade-Insecure-Requests",
+	"Sec-Fetch-Site",
+	"Sec-Fetch-Mode",
+	"Sec-Fetch-User",
+	"Sec-Fetch-Dest",
+	"Priority",
+	"Sec-Ch-Ua",
+	"Sec-Ch-Ua-Mobile",
+	"Sec-Ch-Ua-Platform",
+	"Sec-Ch-Ua-Platform-Version",
+	"Sec-Ch-Ua-Arch",
+	"Sec-Ch-Ua-Model",
+	"Sec-Ch-Ua-Bitness",
+	"Sec-Ch-Ua-Full-Version",
+	"Sec-Ch-Ua-Full-Version-List",
+	"Sec-Ch-Ua-Wow64",
+	"Sec-Ch-Viewport-Width",
+	"Sec-Ch-Viewport-Height",
+	"Sec-Ch-Dpr",
+	"Sec-Ch-Device-Memory",
+	"Sec-Ch-Prefers-Color-Scheme",
+	"Sec-Ch-Prefers-Reduced-Motion",
+	"Cache-Control",
+	"Pragma",
+	"DNT",
+	"Referer",
+	"Origin",
+	"Host",
+	"Authorization",
+	"X-Requested-With",
+	"Content-Type",
+	"If-None-Match",
+	"If-Modified-Since",
+	"X-Forwarded-Proto",
+	"X-Forwarded-Port",
+	"CF-Ray",
+	"CF-IPCountry",
+	"CF-Connecting-IP",
+	"True-Client-IP",
+	"X-Client-IP",
+	"X-Cluster-Client-IP",
+	"Forwarded",
+	"Via",
+	"X-Original-Forwarded-For",
+	"CloudFront-Viewer-Country",
+	"X-Amzn-Trace-Id",
+	"Accept-Datetime",
+	"TE",
+	"Expect",
+	"Max-Forwards",
+	"Range",
+	"Warning",
+	"Date",
+	"From",
+	"Save-Data",
+	"Viewport-Width",
+	"Width",
+	"DPR",
+	"Device-Memory",
+	"ECT",
+	"RTT",
+	"Downlink",
+}
+
+// DefaultHeaders returns a copy of the header names extracted when
+// Config.Headers is empty.
+func DefaultHeaders() []string {
+	return append([]string(nil), defaultHeaders...)
+}
+
+// defaultProxyHeaders are the headers left out of the hash when
+// Config.ProxyHeaders is nil: ones a proxy, CDN, or tracing system adds
+// with a value that depends on the edge node or the request rather than
+// the client.
+var defaultProxyHeaders = []string{
+	"Via",
+	"CDN-Loop",
+	"CF-Ray",
+	"X-Amzn-Trace-Id",
+	"X-Cloud-Trace-Context",
+	"X-Azure-Ref",
+	"Akamai-Origin-Hop",
+	"X-Varnish",
+	"Traceparent",
+	"Tracestate",
+	"B3",
+	"X-B3-TraceId",
+	"X-B3-SpanId",
+	"X-B3-ParentSpanId",
+	"X-B3-Sampled",
+	"X-Request-Id",
+	"X-Correlation-Id",
+}
+
+// DefaultProxyHeaders returns a copy of the proxy and trace header names
+// left out of the hash when Config.ProxyHeaders is nil.
+func DefaultProxyHeaders() []string {
+	return append([]string(nil), defaultProxyHeaders...)
+}
+
+// ExtractHeaders returns the named headers present on r, keyed by
+// lower-cased header name. The default header set is used when names is
+// empty.
+func ExtractHeaders(r *http.Request, names []string) map[string]string {
+	// A request sends at most len(r.Header) of the names, usually far
+	// fewer than the default set lists
+	if len(names) == 0 {
+		headers := make(map[string]string, min(len(r.Header), len(defaultHeaderKeys)))
+		for _, name := range defaultHeaderKeys {
+			if values := r.Header[name.canonical]; len(values) > 0 && values[0] != "" {
+				headers[name.lower] = values[0]
+			}
+		}
+		return headers
+	}
+
+	headers := make(map[string]string, min(len(r.Header), len(names)))
+	for _, headerName := range names {
+		if value := r.Header.Get(headerName); value != "" {
+			headers[strings.ToLower(headerName)] = value
+		}
+	}
+	return headers
+}
+
+// headerKey is a header name in the canonical form net/http stores it
+// under and in the lower-case form it is fingerprinted under.
+type headerKey struct {
+	canonical string
+	lower     string
+}
+
+// defaultHeaderKeys are the forms of defaultHeaders, computed once so the
+// default path of ExtractHeaders does not allocate them per request.
+var defaultHeaderKeys = func() []headerKey {
+	keys := make([]headerKey, len(defaultHeaders))
+	for i, name := range defaultHeaders {
+		keys[i] = headerKey{canonical: http.CanonicalHeaderKey(name), lower: strings.ToLower(name)}
+	}
+	return keys
+}()
+
+func extractAdditionalSignals(r *http.Request) (string, string, string, string) {
+	method := r.Method
+	protocol := r.Proto
+	if r.ProtoMajor == 3 && protocol == extendedConnectProtocol(r) {
+		protocol = "HTTP/3.0"
+	}
+
+	// Extract port from Host header. Bare and bracketed IPv6 hosts without
+	// a port fail to split, so only a successfully split port is used.
+	port := ""
+	if _, p, err := net.SplitHostPort(r.Host); err == nil {
+		port = p
+	}
+
+	// Extract TLS version if available
+	tlsVersion := ""
+	if r.TLS != nil {
+		switch r.TLS.Version {
+		case tls.VersionTLS10:
+			tlsVersion = "TLS1.0"
+		case tls.VersionTLS11:
+			tlsVersion = "TLS1.1"
+		case tls.VersionTLS12:
+			tlsVersion = "TLS1.2"
+		case tls.VersionTLS13:
+			tlsVersion = "TLS1.3"
+		default:
+			tlsVersion = "unknown"
+		}
+	}
+
+	return method, protocol, tlsVersion, port
+}
+
+// extendedConnectProtocol returns the :protocol pseudo-header of an
+// extended CONNECT request (RFC 8441, RFC 9220), such as "websocket", or
+// "" for any other request. net/http exposes it over HTTP/2 as a
+// ":protocol" header, while quic-go reports it over HTTP/3 in place of
+// Request.Proto.
+func extendedConnectProtocol(r *http.Request) string {
+	if r.Method != http.MethodConnect {
+		return ""
+	}
+	if protocol := r.Header.Get(":protocol"); protocol != "" {
+		return protocol
+	}
+	if r.ProtoMajor == 3 && !strings.HasPrefix(r.Proto, "HTTP/") {
+		return r.Proto
+	}
+	return ""
+}
+
+// extractTLSDetails returns the negotiated cipher suite name and ALPN
+// protocol, or empty strings for plain HTTP requests.
+func extractTLSDetails(r *http.Request) (string, string) {
+	if r.TLS == nil {
+		return "", ""
+	}
+	return tls.CipherSuiteName(r.TLS.CipherSuite), r.TLS.NegotiatedProtocol
+}
+
+// extractClientCert returns the hex-encoded SHA-256 thumbprint, subject,
+// and issuer of the leaf certificate the client presented over mutual TLS,
+// or empty strings when it presented none.
+func extractClientCert(r *http.Request) (string, string, string) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return "", "", ""
+	}
+	cert := r.TLS.PeerCertificates[0]
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:]), cert.Subject.String(), cert.Issuer.String()
+}
+
+// extractBodySignals returns how the request body is framed: its transfer
+// codings, whether a Content-Length header was sent, and the shape of its
+// multipart boundary. Only headers are inspected, so the body is left
+// unread for the handler.
+func extractBodySignals(r *http.Request) (string, bool, string) {
+	transferEncoding := strings.Join(r.TransferEncoding, ",")
+	hasContentLength := r.Header.Get("Content-Length") != ""
+
+	boundary := ""
+	if mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil &&
+		strings.HasPrefix(mediaType, "multipart/") {
+		boundary = boundaryShape(params["boundary"])
+	}
+
+	return transferEncoding, hasContentLength, boundary
+}
+
+// boundaryShape reduces a multipart boundary to the pattern its client
+// generates, keeping the literal prefix and replacing the random part with
+// its character class and length. For example, Chrome's
+// "----WebKitFormBoundaryABcd1234EFgh5678" becomes
+// "----WebKitFormBoundary{alnum:16}", and curl's 24 dashes followed by 16
+// hex digits becomes "------------------------{hex:16}".
+func boundaryShape(boundary string) string {
+	if boundary == "" {
+		return ""
+	}
+
+	i := strings.IndexFunc(boundary, isAlphanumericRune)
+	if i < 0 {
+		return boundary
+	}
+	prefix, random := boundary[:i], boundary[i:]
+	if j := strings.Index(strings.ToLower(random), "boundary"); j >= 0 {
+		prefix += random[:j+len("boundary")]
+		random = random[j+len("boundary"):]
+	}
+
+	class := "alnum"
+	switch {
+	case random == "":
+		return prefix
+	case strings.Trim(random, "0123456789") == "":
+		class = "digits"
+	case strings.Trim(random, "0123456789abcdef") == "" || strings.Trim(random, "0123456789ABCDEF") == "":
+		class = "hex"
+	case strings.IndexFunc(random, func(r rune) bool { return !isAlphanumericRune(r) }) >= 0:
+		class = "mixed"
+	}
+	return fmt.Sprintf("%s{%s:%d}", prefix, class, len(random))
+}
+
+func isAlphanumericRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
+
+// cookieNames returns the distinct names of the cookies r sends, sorted.
+// Values are discarded as soon as the header is parsed.
+func cookieNames(r *http.Request) []string {
+	cookies := r.Cookies()
+	if len(cookies) == 0 {
+		return nil
+	}
+	names := make([]string, len(cookies))
+	for i, cookie := range cookies {
+		names[i] = cookie.Name
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// queryKeys returns the distinct parameter names of query, sorted.
+func queryKeys(query url.Values) []string {
+	if len(query) == 0 {
+		return nil
+	}
+	return slices.Sorted(maps.Keys(query))
+}
@@ -0,0 +1,179 @@
+package fingerprint
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtractPort(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "example.com:8080", want: "8080"},
+		{host: "example.com"},
+		{host: "127.0.0.1:80", want: "80"},
+		{host: "127.0.0.1"},
+		{host: "[::1]:443", want: "443"},
+		{host: "[::1]"},
+		{host: "::1"},
+		{host: "2001:db8::1"},
+		{host: "[fe80::1%eth0]:8443", want: "8443"},
+		{host: "fe80::1%eth0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			var c Config
+			r := &http.Request{Method: http.MethodGet, Host: tt.host, RemoteAddr: "192.0.2.1:5000", Header: http.Header{}}
+			if data, _ := c.FromRequest(r); data.Port != tt.want {
+				t.Errorf("port of %q = %q, want %q", tt.host, data.Port, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractTLSDetails(t *testing.T) {
+	var data Data
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var c Config
+		data, _ = c.FromRequest(r)
+	})
+	h2 := httptest.NewUnstartedServer(handler)
+	h2.EnableHTTP2 = true
+	h2.StartTLS()
+	defer h2.Close()
+
+	tests := []struct {
+		name     string
+		srv      *httptest.Server
+		wantALPN string
+	}{
+		// The test client offers no ALPN protocols without HTTP/2
+		{name: "HTTP/1.1", srv: httptest.NewTLSServer(handler)},
+		{name: "HTTP/2", srv: h2, wantALPN: "h2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tt.srv.Close()
+			resp, err := tt.srv.Client().Get(tt.srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			want := tls.CipherSuiteName(resp.TLS.CipherSuite)
+			if data.CipherSuite != want || strings.HasPrefix(want, "0x") {
+				t.Errorf("cipher suite = %q, want %q", data.CipherSuite, want)
+			}
+			if data.ALPN != tt.wantALPN {
+				t.Errorf("ALPN = %q, want %q", data.ALPN, tt.wantALPN)
+			}
+			components := Components(data)
+			if !slices.Contains(components, "cipher:"+want) {
+				t.Errorf("components %q do not include the cipher suite", components)
+			}
+			if hasALPN := slices.Contains(components, "alpn:h2"); hasALPN != (tt.wantALPN != "") {
+				t.Errorf("components %q: ALPN included %v", components, hasALPN)
+			}
+		})
+	}
+
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	resp, err := plain.Client().Get(plain.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if data.CipherSuite != "" || data.ALPN != "" {
+		t.Errorf("cipher suite %q and ALPN %q on plain HTTP", data.CipherSuite, data.ALPN)
+	}
+}
+
+// newTestCert returns a certificate for commonName signed by parent, or
+// self-signed when parent is nil.
+func newTestCert(t *testing.T, commonName string, parent *tls.Certificate) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, any(key)
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestExtractClientCert(t *testing.T) {
+	ca := newTestCert(t, "test-ca", nil)
+	clientCert := newTestCert(t, "test-client", &ca)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+
+	var data Data
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var c Config
+		data, _ = c.FromRequest(r)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	srv.StartTLS()
+	defer srv.Close()
+
+	client := srv.Client()
+	if resp, err := client.Get(srv.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("request without a client certificate succeeded")
+	}
+
+	client.Transport.(*http.Transport).TLSClientConfig.Certificates = []tls.Certificate{clientCert}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	sum := sha256.Sum256(clientCert.Leaf.Raw)
+	thumbprint := hex.EncodeToString(sum[:])
+	if data.ClientCertThumbprint != thumbprint {
+		t.Errorf("thumbprint = %q, want %q", data.ClientCertThumbprint, thumbprint)
+	}
+	if data.ClientCertSubject != "CN=test-client" || data.ClientCertIssuer != "CN=test-ca" {
+		t.Errorf("subject %q, issuer %q; want CN=test-client, CN=test-ca", data.ClientCertSubject, data.ClientCertIssuer)
+	}
+	if !slices.Contains(Components(data), "client-cert:"+thumbprint) {
+		t.Errorf("components %q do not include the client certificate", Components(data))
+	}
+}
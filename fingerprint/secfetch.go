@@ -0,0 +1,119 @@
+package fingerprint
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Values browsers send in the Fetch Metadata headers
+// (https://www.w3.org/TR/fetch-metadata/).
+var (
+	secFetchSites = []string{"cross-site", "same-origin", "same-site", "none"}
+	secFetchModes = []string{"cors", "navigate", "no-cors", "same-origin", "websocket"}
+	secFetchDests = []string{
+		"audio", "audioworklet", "document", "embed", "empty", "fencedframe", "font", "frame",
+		"iframe", "image", "json", "manifest", "object", "paintworklet", "report", "script",
+		"serviceworker", "sharedworker", "style", "track", "video", "webidentity", "websocket",
+		"worker", "xslt",
+	}
+
+	// navigationDests are the destinations only navigate requests load;
+	// plugin content in embed and object elements may be navigated to too
+	navigationDests = []string{"document", "fencedframe", "frame", "iframe"}
+	pluginDests     = []string{"embed", "object"}
+)
+
+// secFetchHeaders holds the Fetch Metadata headers of a request, trimmed
+// and lower-cased.
+type secFetchHeaders struct {
+	site, mode, user, dest string
+}
+
+// secFetch returns the Fetch Metadata headers of data, and whether any
+// was sent.
+func secFetch(data Data) (secFetchHeaders, bool) {
+	get := func(name string) string {
+		return strings.ToLower(strings.TrimSpace(data.Headers[name]))
+	}
+	f := secFetchHeaders{
+		site: get("sec-fetch-site"),
+		mode: get("sec-fetch-mode"),
+		user: get("sec-fetch-user"),
+		dest: get("sec-fetch-dest"),
+	}
+	return f, f != secFetchHeaders{}
+}
+
+// SecFetchCheck is the result of CheckSecFetch.
+type SecFetchCheck struct {
+	// Checked is false when the request sent no Sec-Fetch-* header, as
+	// older browsers, plain HTTP origins, and most HTTP libraries do
+	Checked bool
+	Valid   bool
+	// Reason explains an invalid combination
+	Reason string
+}
+
+// CheckSecFetch reports whether the Sec-Fetch-Site, Sec-Fetch-Mode,
+// Sec-Fetch-User, and Sec-Fetch-Dest headers of data form a combination a
+// browser can send. Browsers derive all four from how the request was
+// initiated, so automation that copies some of them, or fills them in by
+// hand, tends to produce impossible ones: Sec-Fetch-User on a fetch, a
+// navigation to an image, or Sec-Fetch-Site: none on a subresource.
+func CheckSecFetch(data Data) SecFetchCheck {
+	f, ok := secFetch(data)
+	if !ok {
+		return SecFetchCheck{}
+	}
+	if reason := f.invalid(); reason != "" {
+		return SecFetchCheck{Checked: true, Reason: reason}
+	}
+	return SecFetchCheck{Checked: true, Valid: true}
+}
+
+// invalid returns why f is not a combination a browser sends, or "".
+func (f secFetchHeaders) invalid() string {
+	for _, h := range []struct{ name, value string }{
+		{"Sec-Fetch-Site", f.site}, {"Sec-Fetch-Mode", f.mode}, {"Sec-Fetch-Dest", f.dest},
+	} {
+		if h.value == "" {
+			return fmt.Sprintf("%s is missing; browsers send Sec-Fetch-Site, Sec-Fetch-Mode, and Sec-Fetch-Dest together", h.name)
+		}
+	}
+	switch {
+	case !slices.Contains(secFetchSites, f.site):
+		return fmt.Sprintf("unknown Sec-Fetch-Site %q", f.site)
+	case !slices.Contains(secFetchModes, f.mode):
+		return fmt.Sprintf("unknown Sec-Fetch-Mode %q", f.mode)
+	case !slices.Contains(secFetchDests, f.dest):
+		return fmt.Sprintf("unknown Sec-Fetch-Dest %q", f.dest)
+	case f.user != "" && f.user != "?1":
+		return fmt.Sprintf("Sec-Fetch-User is %q, but browsers only send ?1", f.user)
+	case f.user != "" && f.mode != "navigate":
+		return fmt.Sprintf("Sec-Fetch-User is only sent on navigations, not in %s mode", f.mode)
+	case f.mode == "navigate" && !slices.Contains(navigationDests, f.dest) && !slices.Contains(pluginDests, f.dest):
+		return fmt.Sprintf("navigations load a document or frame, not the %s destination", f.dest)
+	case slices.Contains(navigationDests, f.dest) && f.mode != "navigate":
+		return fmt.Sprintf("the %s destination is only loaded by navigations, not in %s mode", f.dest, f.mode)
+	case (f.mode == "websocket") != (f.dest == "websocket") && f.dest != "empty":
+		return fmt.Sprintf("Sec-Fetch-Mode %s does not match Sec-Fetch-Dest %s", f.mode, f.dest)
+	case f.site == "none" && f.mode != "navigate":
+		return fmt.Sprintf("Sec-Fetch-Site: none is only sent on user-initiated navigations, not in %s mode", f.mode)
+	case f.mode == "same-origin" && f.site != "same-origin":
+		return fmt.Sprintf("same-origin mode requests cannot be %s", f.site)
+	}
+	return ""
+}
+
+// component returns the sec-fetch component value: the four headers
+// joined with commas, with a missing Sec-Fetch-User as "?0", prefixed
+// with whether they are consistent, as in "valid:none,navigate,?1,document".
+func (f secFetchHeaders) component() string {
+	validity := "valid"
+	if f.invalid() != "" {
+		validity = "invalid"
+	}
+	return validity + ":" + f.site + "," + f.mode + "," + cmp.Or(f.user, "?0") + "," + f.dest
+}
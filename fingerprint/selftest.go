@@ -0,0 +1,186 @@
+package fingerprint
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+)
+
+// SelfTestFixture is a known request with the fingerprints the zero Config
+// must produce for it under the current SchemaVersion. The expected hashes
+// change only when SchemaVersion does; a mismatch means the hashing logic,
+// component order, or serialization changed without a version bump.
+type SelfTestFixture struct {
+	Name              string
+	Fingerprint       string
+	StableFingerprint string
+
+	// Request builds a fresh copy of the request, since FromRequest may
+	// be given the same fixture more than once.
+	Request func() *http.Request
+}
+
+// selfTestFixtures cover plain and TLS requests, HTTP/1.0 through HTTP/2,
+// captured header order, a non-default port, GREASE brands, Priority, and
+// proxy headers that the zero Config does not trust or hash.
+var selfTestFixtures = []SelfTestFixture{
+	{
+		Name:              "curl",
+		Fingerprint:       "v7:e247074ad3c95e14b56315dbaced73616d6490475e44066235211b838bdb8b21",
+		StableFingerprint: "v7:9e103b67f4f24af36df5f68cebab4450a8317ccaec51a53fd5ea2cfa3261a1e3",
+		Request: func() *http.Request {
+			return selfTestRequest(http.MethodGet, "HTTP/1.1", "localhost:8080", "127.0.0.1:53124", nil, map[string]string{
+				"User-Agent": "curl/8.5.0",
+				"Accept":     "*/*",
+			}, []string{"Host", "User-Agent", "Accept"})
+		},
+	},
+	{
+		Name:              "chrome-h2",
+		Fingerprint:       "v7:10a92cf2e6e472d1174be70f13c26333402d60a533458582939846b8c5b2123c",
+		StableFingerprint: "v7:79c899915d80f097aba1adb1e226746909348b98b0fe06f3b139cc0a038bae99",
+		Request: func() *http.Request {
+			state := &tls.ConnectionState{
+				Version:            tls.VersionTLS13,
+				CipherSuite:        tls.TLS_AES_128_GCM_SHA256,
+				NegotiatedProtocol: "h2",
+				HandshakeComplete:  true,
+			}
+			return selfTestRequest(http.MethodGet, "HTTP/2.0", "example.com", "203.0.113.7:61000", state, map[string]string{
+				"Sec-Ch-Ua":                 `"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"`,
+				"Sec-Ch-Ua-Mobile":          "?0",
+				"Sec-Ch-Ua-Platform":        `"Windows"`,
+				"Upgrade-Insecure-Requests": "1",
+				"User-Agent":                "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+				"Accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8",
+				"Sec-Fetch-Site":            "none",
+				"Sec-Fetch-Mode":            "navigate",
+				"Sec-Fetch-User":            "?1",
+				"Sec-Fetch-Dest":            "document",
+				"Accept-Encoding":           "gzip, deflate, br",
+				"Accept-Language":           "en-US,en;q=0.9",
+				"Priority":                  "u=0, i",
+			}, nil)
+		},
+	},
+	{
+		Name:              "firefox-tls12",
+		Fingerprint:       "v7:e2dd40ee31402ab5ee14a58a971b5b7288ee57d0f9a9a8b7ed61735882a23442",
+		StableFingerprint: "v7:e24199593228b9fc2b95c82eca5952542985d6ce969c01abf145a97df11d89fb",
+		Request: func() *http.Request {
+			state := &tls.ConnectionState{
+				Version:            tls.VersionTLS12,
+				CipherSuite:        tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+				NegotiatedProtocol: "http/1.1",
+				HandshakeComplete:  true,
+			}
+			return selfTestRequest(http.MethodGet, "HTTP/1.1", "example.com:8443", "[2001:db8::1]:443", state, map[string]string{
+				"User-Agent":                "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+				"Accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8",
+				"Accept-Language":           "en-US,en;q=0.5",
+				"Accept-Encoding":           "gzip, deflate, br",
+				"Connection":                "keep-alive",
+				"Upgrade-Insecure-Requests": "1",
+				"Sec-Fetch-Dest":            "document",
+				"Sec-Fetch-Mode":            "navigate",
+				"Sec-Fetch-Site":            "none",
+				"Sec-Fetch-User":            "?1",
+			}, []string{"Host", "User-Agent", "Accept", "Accept-Language", "Accept-Encoding", "Connection", "Upgrade-Insecure-Requests", "Sec-Fetch-Dest", "Sec-Fetch-Mode", "Sec-Fetch-Site", "Sec-Fetch-User"})
+		},
+	},
+	{
+		Name:              "proxied-http10",
+		Fingerprint:       "v7:c66672ac833cb0bcb8db383c67b7882a3462a8722c104b47119683bc0ad76f79",
+		StableFingerprint: "v7:b78362dfa9d041ee5c587b4df2fb08c4f3e80faca17c87b1124a83477c1719c8",
+		Request: func() *http.Request {
+			return selfTestRequest(http.MethodPost, "HTTP/1.0", "api.example.com", "10.0.0.2:40000", nil, map[string]string{
+				"User-Agent":      "python-requests/2.31.0",
+				"Accept":          "*/*",
+				"Accept-Encoding": "gzip, deflate",
+				"Connection":      "keep-alive",
+				"Content-Type":    "application/json",
+				"X-Forwarded-For": "198.51.100.23",
+				"X-Real-IP":       "198.51.100.23",
+				"Via":             "1.1 varnish",
+				"CF-Ray":          "8a1b2c3d4e5f6a7b-AMS",
+				"X-Amzn-Trace-Id": "Root=1-65a1b2c3-0123456789abcdef01234567",
+			}, nil)
+		},
+	},
+}
+
+// SelfTestFixtures returns a copy of the built-in self-test fixtures.
+func SelfTestFixtures() []SelfTestFixture {
+	return append([]SelfTestFixture(nil), selfTestFixtures...)
+}
+
+func selfTestRequest(method, proto, host, remoteAddr string, state *tls.ConnectionState, headers map[string]string, order []string) *http.Request {
+	major, minor, _ := http.ParseHTTPVersion(proto)
+	header := make(http.Header, len(headers))
+	for name, value := range headers {
+		header.Set(name, value)
+	}
+	r := &http.Request{
+		Method:     method,
+		URL:        &url.URL{Path: "/"},
+		Proto:      proto,
+		ProtoMajor: major,
+		ProtoMinor: minor,
+		Header:     header,
+		Host:       host,
+		RemoteAddr: remoteAddr,
+		RequestURI: "/",
+		TLS:        state,
+	}
+	if order != nil {
+		r = r.WithContext(WithHeaderOrder(r.Context(), order))
+	}
+	return r
+}
+
+// SelfTestResult is the outcome of one fixture.
+type SelfTestResult struct {
+	Name string
+	// Passed is set when every run produced the expected fingerprints
+	Passed bool
+
+	Fingerprint               string
+	ExpectedFingerprint       string
+	StableFingerprint         string
+	ExpectedStableFingerprint string
+}
+
+// selfTestRuns is how many times each fixture is fingerprinted, so results
+// that vary between runs, such as from map iteration order, fail.
+const selfTestRuns = 3
+
+// SelfTest fingerprints every built-in fixture with the zero Config and
+// compares the results with their expected values. It does not depend on
+// the configuration of the caller, so it reports whether this build hashes
+// the way every other build of the same SchemaVersion does.
+func SelfTest() []SelfTestResult {
+	var c Config
+	results := make([]SelfTestResult, 0, len(selfTestFixtures))
+	for _, fixture := range selfTestFixtures {
+		result := SelfTestResult{
+			Name:                      fixture.Name,
+			Passed:                    true,
+			ExpectedFingerprint:       fixture.Fingerprint,
+			ExpectedStableFingerprint: fixture.StableFingerprint,
+		}
+		for range selfTestRuns {
+			data, hash := c.FromRequest(fixture.Request())
+			stable := c.GenerateStable(data)
+			mismatch := hash != fixture.Fingerprint || stable != fixture.StableFingerprint
+			// The first mismatch is the one reported
+			if result.Fingerprint == "" || mismatch && result.Passed {
+				result.Fingerprint, result.StableFingerprint = hash, stable
+			}
+			if mismatch {
+				result.Passed = false
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,42 @@
+package fingerprint
+
+import (
+	"testing"
+)
+
+// TestSelfTestFixtures hashes the fixtures SelfTest and
+// /fingerprint/self-test use, so a change that alters fingerprints without
+// a SchemaVersion bump fails here before it ships.
+func TestSelfTestFixtures(t *testing.T) {
+	seen := make(map[string]string)
+	for _, fixture := range SelfTestFixtures() {
+		t.Run(fixture.Name, func(t *testing.T) {
+			var c Config
+			data, hash := c.FromRequest(fixture.Request())
+			if hash != fixture.Fingerprint {
+				t.Errorf("fingerprint = %s, want %s", hash, fixture.Fingerprint)
+			}
+			if stable := c.GenerateStable(data); stable != fixture.StableFingerprint {
+				t.Errorf("stable fingerprint = %s, want %s", stable, fixture.StableFingerprint)
+			}
+			if other, ok := seen[fixture.Fingerprint]; ok {
+				t.Errorf("fingerprint is the same as fixture %s", other)
+			}
+			seen[fixture.Fingerprint] = fixture.Name
+		})
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	results := SelfTest()
+	if len(results) != len(selfTestFixtures) {
+		t.Fatalf("SelfTest returned %d results for %d fixtures", len(results), len(selfTestFixtures))
+	}
+	for _, result := range results {
+		if !result.Passed {
+			t.Errorf("%s: got %s and %s, want %s and %s", result.Name,
+				result.Fingerprint, result.StableFingerprint,
+				result.ExpectedFingerprint, result.ExpectedStableFingerprint)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package fingerprint
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// ShortIDEncoding names how a short ID is written.
+type ShortIDEncoding string
+
+// Supported short ID encodings. Base62 uses 0-9, a-z, and A-Z, so it packs
+// about 5.95 bits into each character against 4 for hex, and stays safe in
+// URLs and file names.
+const (
+	ShortIDHex    ShortIDEncoding = "hex"
+	ShortIDBase62 ShortIDEncoding = "base62"
+)
+
+// DefaultShortIDLength is the short ID length, in characters, used when
+// none is given.
+const DefaultShortIDLength = 12
+
+// MinShortIDBits is the fewest digest bits a short ID may keep. By the
+// birthday bound, n fingerprints collide with probability about
+// n²/2^(bits+1): at 40 bits, one in a hundred for 150,000 fingerprints.
+const MinShortIDBits = 40
+
+// ShortIDFormat derives short IDs: the first Length characters of the
+// fingerprint digest in the given encoding. Short IDs suit display and
+// logs; they collide far sooner than full fingerprints, so they should
+// not be used as keys.
+type ShortIDFormat struct {
+	Encoding ShortIDEncoding
+	Length   int
+}
+
+// NewShortIDFormat returns the format with the given encoding name and
+// length, refusing lengths that keep fewer than MinShortIDBits.
+func NewShortIDFormat(encoding string, length int) (ShortIDFormat, error) {
+	f := ShortIDFormat{Encoding: ShortIDEncoding(encoding), Length: length}
+	switch f.Encoding {
+	case ShortIDHex, ShortIDBase62:
+	default:
+		return ShortIDFormat{}, fmt.Errorf("unknown short ID encoding %q (want hex or base62)", encoding)
+	}
+	if minLength := f.Encoding.minLength(); length < minLength {
+		return ShortIDFormat{}, fmt.Errorf("short ID length %d is below the minimum of %d for %s, which keeps %d bits", length, minLength, encoding, MinShortIDBits)
+	}
+	return f, nil
+}
+
+// bitsPerChar returns the bits each character of the encoding carries.
+func (e ShortIDEncoding) bitsPerChar() float64 {
+	if e == ShortIDBase62 {
+		return math.Log2(62)
+	}
+	return 4
+}
+
+func (e ShortIDEncoding) minLength() int {
+	return int(math.Ceil(MinShortIDBits / e.bitsPerChar()))
+}
+
+// ShortID returns the short ID of a versioned fingerprint, or "" when
+// fingerprint is not a hex digest with a version prefix. The version is
+// left out, and digests shorter than Length, such as 16-character xxHash
+// ones, are returned whole.
+func (f ShortIDFormat) ShortID(fingerprint string) string {
+	_, digest, ok := strings.Cut(fingerprint, ":")
+	if !ok {
+		return ""
+	}
+	sum, err := hex.DecodeString(digest)
+	if err != nil || len(sum) == 0 {
+		return ""
+	}
+
+	id := digest
+	if f.Encoding == ShortIDBase62 {
+		id = base62(sum)
+	}
+	return id[:min(f.Length, len(id))]
+}
+
+// base62 encodes sum as a big-endian number, padded with leading zeros to
+// the length every digest of its size takes, so a short ID is always a
+// prefix of the same width.
+func base62(sum []byte) string {
+	text := new(big.Int).SetBytes(sum).Text(62)
+	width := int(math.Ceil(float64(len(sum)*8) / math.Log2(62)))
+	return strings.Repeat("0", width-len(text)) + text
+}
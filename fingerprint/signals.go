@@ -0,0 +1,28 @@
+package fingerprint
+
+import "strings"
+
+// SignalCount returns the number of meaningful signals in data besides the
+// User-Agent: the Accept, Accept-Language, and Accept-Encoding headers and
+// any Sec-Ch-* and Sec-Fetch-* headers. An Accept of */*, which HTTP
+// libraries send by default, does not count. A request with few signals,
+// such as a bare curl, produces a fingerprint shared by every client of the
+// same tool.
+func SignalCount(data Data) int {
+	var n int
+	if data.Accept != "" && data.Accept != "*/*" {
+		n++
+	}
+	if data.AcceptLang != "" {
+		n++
+	}
+	if data.AcceptEnc != "" {
+		n++
+	}
+	for name, value := range data.Headers {
+		if value != "" && (strings.HasPrefix(name, "sec-ch-") || strings.HasPrefix(name, "sec-fetch-")) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,117 @@
+package fingerprint
+
+import (
+	"math"
+	"strings"
+)
+
+// Tier groups fingerprint components of similar weight. Each tier is
+// hashed on its own, so a change to a volatile component only changes the
+// sub-hash of its tier and leaves those of the stable tiers intact.
+type Tier string
+
+// Tiers, from the most to the least identifying.
+const (
+	// TierHigh holds components weighing 2 or more, such as the
+	// User-Agent, JA3, JA4, and the header order.
+	TierHigh Tier = "high"
+	// TierMedium holds components weighing at least 1, such as the TLS
+	// version, cipher suite, content negotiation headers, client hints,
+	// client IP, and most other headers.
+	TierMedium Tier = "medium"
+	// TierLow holds components weighing less than 1, such as the method,
+	// port, path, and per-request headers like Cache-Control and Referer.
+	TierLow Tier = "low"
+)
+
+// tiers lists the tiers in the order they are combined, with the lowest
+// component weight each holds and its share of the tier score.
+var tiers = []struct {
+	tier      Tier
+	minWeight float64
+	share     float64
+}{
+	{TierHigh, 2, 0.6},
+	{TierMedium, 1, 0.3},
+	{TierLow, 0, 0.1},
+}
+
+// TierHash is the sub-hash of one tier.
+type TierHash struct {
+	Tier Tier `json:"tier"`
+	// Share is the tier's share of the tier score
+	Share float64 `json:"share"`
+	Hash  string  `json:"hash"`
+	// Components names the components the tier was hashed from
+	Components []string `json:"components"`
+}
+
+// Tiered is a fingerprint built from per-tier sub-hashes.
+type Tiered struct {
+	// Hash combines the tier hashes in tier order
+	Hash  string     `json:"hash"`
+	Tiers []TierHash `json:"tiers"`
+}
+
+// ComponentTier returns the tier of the named component, according to its
+// ComponentWeight.
+func ComponentTier(name string) Tier {
+	weight := ComponentWeight(name)
+	for _, t := range tiers {
+		if weight >= t.minWeight {
+			return t.tier
+		}
+	}
+	return TierLow
+}
+
+// GenerateTiered hashes the components of data per tier using the default
+// configuration.
+func GenerateTiered(data Data) Tiered {
+	return defaultConfig.GenerateTiered(data)
+}
+
+// GenerateTiered hashes the components of data, as returned by
+// Components, separately for each tier, keeping their order within a tier,
+// and combines the tier hashes into one. A tier without components still
+// gets a hash, of no components, so every Tiered has all three tiers.
+func (c *Config) GenerateTiered(data Data) Tiered {
+	parts := make(map[Tier][]string, len(tiers))
+	for _, component := range c.Components(data) {
+		name, _, _ := strings.Cut(component, ":")
+		tier := ComponentTier(name)
+		parts[tier] = append(parts[tier], component)
+	}
+
+	var tiered Tiered
+	combined := make([]string, 0, len(tiers))
+	for _, t := range tiers {
+		hash := c.hashParts(parts[t.tier])
+		names := make([]string, len(parts[t.tier]))
+		for i, component := range parts[t.tier] {
+			names[i], _, _ = strings.Cut(component, ":")
+		}
+		tiered.Tiers = append(tiered.Tiers, TierHash{Tier: t.tier, Share: t.share, Hash: hash, Components: names})
+		combined = append(combined, string(t.tier)+":"+hash)
+	}
+	tiered.Hash = c.hashParts(combined)
+	return tiered
+}
+
+// CompareTiered returns the summed share of the tiers whose hashes a and b
+// agree on, from 0 to 1. The high tier holds the largest share, so a change
+// to a per-request header lowers the score far less than a different
+// User-Agent or JA3 does. Both must have been generated with the same
+// configuration.
+func CompareTiered(a, b Tiered) float64 {
+	var score float64
+	for _, ta := range a.Tiers {
+		for _, tb := range b.Tiers {
+			if ta.Tier == tb.Tier && ta.Hash == tb.Hash {
+				score += ta.Share
+			}
+		}
+	}
+	// Rounding keeps 0.6+0.3+0.1 at exactly 1
+	return math.Round(score*1000) / 1000
+}
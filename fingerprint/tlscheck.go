@@ -0,0 +1,144 @@
+package fingerprint
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TLSExpectation describes the TLS a generation of a browser negotiates.
+// A browser whose User-Agent falls in the version range but whose TLS
+// falls outside the bounds is probably spoofing its User-Agent.
+type TLSExpectation struct {
+	// Browser and, when set, Engine must match the parsed User-Agent.
+	// Engine separates, for example, Chrome on iOS, which uses the Apple
+	// TLS stack, from Chrome elsewhere.
+	Browser string
+	Engine  string
+
+	// FromMajor and ToMajor bound the browser major version, inclusive;
+	// zero leaves that end open.
+	FromMajor, ToMajor int
+
+	// MinTLS and MaxTLS bound the highest TLS version the client
+	// supports, as "TLS1.2" or "TLS1.3"; empty leaves that end open.
+	MinTLS, MaxTLS string
+
+	// RequireAEAD expects a GCM or ChaCha20-Poly1305 cipher suite on TLS
+	// 1.2, which every browser of the generation offers and the server
+	// prefers.
+	RequireAEAD bool
+}
+
+// TLSExpectations is the table CheckTLS consults, first match wins.
+// Programs may add entries before serving requests.
+var TLSExpectations = []TLSExpectation{
+	// Chromium 70 shipped final TLS 1.3; Chrome 84 removed TLS 1.0 and 1.1
+	{Browser: "Chrome", Engine: "Blink", FromMajor: 70, MinTLS: "TLS1.3"},
+	{Browser: "Chrome", Engine: "Blink", ToMajor: 69, MaxTLS: "TLS1.2", RequireAEAD: true},
+	{Browser: "Edge", Engine: "Blink", FromMajor: 79, MinTLS: "TLS1.3"},
+	{Browser: "Opera", Engine: "Blink", FromMajor: 57, MinTLS: "TLS1.3"},
+	{Browser: "Opera", Engine: "Blink", ToMajor: 56, MaxTLS: "TLS1.2"},
+	{Browser: "Samsung Internet", Engine: "Blink", FromMajor: 10, MinTLS: "TLS1.3"},
+	// Firefox 63 shipped final TLS 1.3
+	{Browser: "Firefox", Engine: "Gecko", FromMajor: 63, MinTLS: "TLS1.3"},
+	{Browser: "Firefox", Engine: "Gecko", ToMajor: 62, MaxTLS: "TLS1.2", RequireAEAD: true},
+	// Safari 14 enabled TLS 1.3 by default; Safari 11 and older predate it
+	{Browser: "Safari", FromMajor: 14, MinTLS: "TLS1.3"},
+	{Browser: "Safari", ToMajor: 11, MaxTLS: "TLS1.2"},
+	// Internet Explorer never supported TLS 1.3
+	{Browser: "Internet Explorer", MaxTLS: "TLS1.2"},
+}
+
+// TLSCheck is the result of CheckTLS.
+type TLSCheck struct {
+	// Checked is false when there was nothing to compare: no TLS details,
+	// as behind a TLS-terminating proxy, or no matching expectation.
+	Checked  bool
+	Mismatch bool
+	// Reason explains a mismatch
+	Reason string
+}
+
+// CheckTLS cross-checks the browser claimed by the User-Agent against the
+// TLS the client used, per TLSExpectations. The highest version the client
+// supports is taken from data.JA4 when the ClientHello was captured, and
+// from the negotiated version otherwise; the server supports TLS 1.3, so
+// the two agree unless the client caps its version.
+func CheckTLS(data Data) TLSCheck {
+	version := clientTLSVersion(data)
+	if version == 0 {
+		return TLSCheck{}
+	}
+
+	ua := ParseUserAgent(data.UserAgent)
+	if ua.Browser == "" || ua.Device == DeviceBot {
+		return TLSCheck{}
+	}
+	major := majorVersion(ua.BrowserVersion)
+
+	for _, e := range TLSExpectations {
+		if e.Browser != ua.Browser || e.Engine != "" && e.Engine != ua.Engine ||
+			e.FromMajor > 0 && major < e.FromMajor || e.ToMajor > 0 && major > e.ToMajor {
+			continue
+		}
+
+		claimed := fmt.Sprintf("%s %d", ua.Browser, major)
+		switch lo, hi := tlsVersionNumber(e.MinTLS), tlsVersionNumber(e.MaxTLS); {
+		case lo > 0 && version < lo:
+			return TLSCheck{Checked: true, Mismatch: true,
+				Reason: fmt.Sprintf("%s supports %s, but the client's highest TLS version is %s", claimed, e.MinTLS, tlsVersionName(version))}
+		case hi > 0 && version > hi:
+			return TLSCheck{Checked: true, Mismatch: true,
+				Reason: fmt.Sprintf("%s does not support %s, which the client used", claimed, tlsVersionName(version))}
+		case e.RequireAEAD && data.TLSVersion == "TLS1.2" && data.CipherSuite != "" && !isAEADSuite(data.CipherSuite):
+			return TLSCheck{Checked: true, Mismatch: true,
+				Reason: fmt.Sprintf("%s offers AEAD cipher suites, but the client negotiated %s", claimed, data.CipherSuite)}
+		}
+		return TLSCheck{Checked: true}
+	}
+	return TLSCheck{}
+}
+
+// clientTLSVersion returns the highest TLS version the client supports as
+// a number such as 13 for TLS 1.3, or 0 if unknown.
+func clientTLSVersion(data Data) int {
+	// JA4 starts with the protocol and the highest supported version,
+	// as in "t13d1516h2_..."
+	if len(data.JA4) >= 3 && data.JA4[0] == 't' {
+		switch data.JA4[1:3] {
+		case "13":
+			return 13
+		case "12":
+			return 12
+		case "11":
+			return 11
+		case "10":
+			return 10
+		}
+	}
+	return tlsVersionNumber(data.TLSVersion)
+}
+
+// tlsVersionNumber converts "TLS1.2" to 12, or returns 0.
+func tlsVersionNumber(name string) int {
+	switch name {
+	case "TLS1.0":
+		return 10
+	case "TLS1.1":
+		return 11
+	case "TLS1.2":
+		return 12
+	case "TLS1.3":
+		return 13
+	}
+	return 0
+}
+
+func tlsVersionName(version int) string {
+	return fmt.Sprintf("TLS1.%d", version-10)
+}
+
+// isAEADSuite reports whether the named cipher suite is an AEAD one.
+func isAEADSuite(name string) bool {
+	return strings.Contains(name, "_GCM_") || strings.Contains(name, "CHACHA20_POLY1305")
+}
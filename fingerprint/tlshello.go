@@ -0,0 +1,322 @@
+package fingerprint
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ClientHello holds the fields of a TLS ClientHello message that are used
+// for TLS fingerprinting. List fields preserve the order sent by the client.
+type ClientHello struct {
+	Version           uint16
+	CipherSuites      []uint16
+	Extensions        []uint16
+	SupportedCurves   []tls.CurveID
+	SupportedPoints   []uint8
+	SupportedVersions []uint16
+	SignatureSchemes  []tls.SignatureScheme
+	ALPN              []string
+	ServerName        string
+}
+
+func newClientHello(chi *tls.ClientHelloInfo) *ClientHello {
+	hello := &ClientHello{
+		CipherSuites:      append([]uint16(nil), chi.CipherSuites...),
+		Extensions:        append([]uint16(nil), chi.Extensions...),
+		SupportedCurves:   append([]tls.CurveID(nil), chi.SupportedCurves...),
+		SupportedPoints:   append([]uint8(nil), chi.SupportedPoints...),
+		SupportedVersions: append([]uint16(nil), chi.SupportedVersions...),
+		SignatureSchemes:  append([]tls.SignatureScheme(nil), chi.SignatureSchemes...),
+		ALPN:              append([]string(nil), chi.SupportedProtos...),
+		ServerName:        chi.ServerName,
+	}
+
+	// crypto/tls does not expose the legacy record version, so derive it
+	// from the advertised versions. Clients offering TLS 1.3 must send
+	// TLS 1.2 in the legacy field (RFC 8446, Section 4.1.2).
+	for _, v := range hello.SupportedVersions {
+		if !isGREASE(v) && v > hello.Version {
+			hello.Version = v
+		}
+	}
+	if hello.Version > tls.VersionTLS12 {
+		hello.Version = tls.VersionTLS12
+	}
+
+	return hello
+}
+
+// JA3String returns the JA3 fingerprint string for the ClientHello:
+// SSLVersion,Ciphers,Extensions,EllipticCurves,EllipticCurvePointFormats.
+// GREASE values are excluded as required by the JA3 specification.
+func (h *ClientHello) JA3String() string {
+	curves := make([]uint16, 0, len(h.SupportedCurves))
+	for _, curve := range h.SupportedCurves {
+		curves = append(curves, uint16(curve))
+	}
+	points := make([]uint16, 0, len(h.SupportedPoints))
+	for _, point := range h.SupportedPoints {
+		points = append(points, uint16(point))
+	}
+
+	return strings.Join([]string{
+		strconv.Itoa(int(h.Version)),
+		joinUint16(h.CipherSuites, "-"),
+		joinUint16(h.Extensions, "-"),
+		joinUint16(curves, "-"),
+		joinUint16(points, "-"),
+	}, ",")
+}
+
+// ExtensionOrder returns the extensions of the ClientHello in the order
+// the client sent them, without GREASE values. JA3 hashes the same list;
+// JA4 sorts it.
+func (h *ClientHello) ExtensionOrder() []uint16 {
+	return withoutGREASE(h.Extensions)
+}
+
+// SupportedGroups returns the supported groups of the ClientHello, the
+// elliptic curves and key exchange groups it offers, in the client's order
+// of preference and without GREASE values.
+func (h *ClientHello) SupportedGroups() []uint16 {
+	groups := make([]uint16, 0, len(h.SupportedCurves))
+	for _, curve := range h.SupportedCurves {
+		groups = append(groups, uint16(curve))
+	}
+	return withoutGREASE(groups)
+}
+
+// JA3 returns the hex-encoded MD5 hash of the JA3 fingerprint string.
+func (h *ClientHello) JA3() string {
+	sum := md5.Sum([]byte(h.JA3String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// JA4 returns the JA4 fingerprint of the ClientHello in the form
+// a_b_c, where a describes the protocol, TLS version, SNI presence, cipher
+// and extension counts, and first ALPN value; b is the truncated SHA-256 of
+// the sorted cipher suites; and c is the truncated SHA-256 of the sorted
+// extensions (excluding SNI and ALPN) followed by the signature algorithms
+// in their original order.
+func (h *ClientHello) JA4() string {
+	var ciphers, extensions []string
+	for _, c := range h.CipherSuites {
+		if !isGREASE(c) {
+			ciphers = append(ciphers, fmt.Sprintf("%04x", c))
+		}
+	}
+
+	extensionCount := 0
+	for _, e := range h.Extensions {
+		if isGREASE(e) {
+			continue
+		}
+		extensionCount++
+		// SNI and ALPN are counted but not hashed
+		if e != 0x0000 && e != 0x0010 {
+			extensions = append(extensions, fmt.Sprintf("%04x", e))
+		}
+	}
+
+	sni := "i"
+	if h.ServerName != "" {
+		sni = "d"
+	}
+
+	a := fmt.Sprintf("t%s%s%02d%02d%s",
+		ja4Version(h), sni, min(len(ciphers), 99), min(extensionCount, 99), ja4ALPN(h.ALPN))
+
+	sort.Strings(ciphers)
+	b := ja4Hash(strings.Join(ciphers, ","))
+
+	sort.Strings(extensions)
+	c := strings.Join(extensions, ",")
+	if len(h.SignatureSchemes) > 0 {
+		schemes := make([]string, 0, len(h.SignatureSchemes))
+		for _, scheme := range h.SignatureSchemes {
+			schemes = append(schemes, fmt.Sprintf("%04x", uint16(scheme)))
+		}
+		c += "_" + strings.Join(schemes, ",")
+	}
+	if len(extensions) == 0 {
+		c = ""
+	}
+
+	return a + "_" + b + "_" + ja4Hash(c)
+}
+
+// ja4Version returns the two-character TLS version used by JA4, taken
+// from the highest supported_versions entry.
+func ja4Version(h *ClientHello) string {
+	version := h.Version
+	for _, v := range h.SupportedVersions {
+		if !isGREASE(v) && v > version {
+			version = v
+		}
+	}
+
+	switch version {
+	case tls.VersionTLS13:
+		return "13"
+	case tls.VersionTLS12:
+		return "12"
+	case tls.VersionTLS11:
+		return "11"
+	case tls.VersionTLS10:
+		return "10"
+	case 0x0300: // SSL 3.0
+		return "s3"
+	default:
+		return "00"
+	}
+}
+
+// ja4ALPN returns the first and last characters of the first ALPN value,
+// or "00" when the client sent no ALPN extension. Values that do not start
+// and end with an alphanumeric character are represented by the first and
+// last hex digits of the value instead.
+func ja4ALPN(protos []string) string {
+	if len(protos) == 0 || protos[0] == "" {
+		return "00"
+	}
+
+	proto := protos[0]
+	first, last := proto[0], proto[len(proto)-1]
+	if !isAlphanumeric(first) || !isAlphanumeric(last) {
+		encoded := hex.EncodeToString([]byte(proto))
+		return encoded[:1] + encoded[len(encoded)-1:]
+	}
+	return string([]byte{first, last})
+}
+
+func isAlphanumeric(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// ja4Hash returns the first 12 hex characters of the SHA-256 of s, or
+// twelve zeros when s is empty.
+func ja4Hash(s string) string {
+	if s == "" {
+		return "000000000000"
+	}
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// isGREASE reports whether v is a GREASE value (RFC 8701).
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
+
+// withoutGREASE returns a copy of values without the GREASE values.
+func withoutGREASE(values []uint16) []uint16 {
+	kept := make([]uint16, 0, len(values))
+	for _, v := range values {
+		if !isGREASE(v) {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// joinUint16 joins the non-GREASE values in decimal form using sep.
+func joinUint16(values []uint16, sep string) string {
+	var b strings.Builder
+	for _, v := range values {
+		if isGREASE(v) {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(strconv.Itoa(int(v)))
+	}
+	return b.String()
+}
+
+// HelloCapture records the TLS ClientHello of each connection accepted by
+// an http.Server so that request handlers can fingerprint it. The standard
+// library does not expose the ClientHello to handlers, so the capture hooks
+// tls.Config.GetConfigForClient and associates the result with the
+// connection through http.Server.ConnContext.
+type HelloCapture struct {
+	hellos sync.Map // net.Conn -> *ClientHello
+}
+
+// NewHelloCapture returns an empty HelloCapture.
+func NewHelloCapture() *HelloCapture {
+	return &HelloCapture{}
+}
+
+type helloContextKey struct{}
+
+type helloRef struct {
+	capture *HelloCapture
+	conn    net.Conn
+}
+
+// Install wires the capture into srv. It must be called before the server
+// starts serving TLS. Existing GetConfigForClient, ConnContext, and
+// ConnState hooks are preserved and called after the capture hooks.
+func (c *HelloCapture) Install(srv *http.Server) {
+	if srv.TLSConfig == nil {
+		srv.TLSConfig = &tls.Config{}
+	}
+
+	getConfig := srv.TLSConfig.GetConfigForClient
+	srv.TLSConfig.GetConfigForClient = func(chi *tls.ClientHelloInfo) (*tls.Config, error) {
+		// Keep the first ClientHello if a HelloRetryRequest triggers another
+		c.hellos.LoadOrStore(chi.Conn, newClientHello(chi))
+		if getConfig != nil {
+			return getConfig(chi)
+		}
+		return nil, nil
+	}
+
+	connContext := srv.ConnContext
+	srv.ConnContext = func(ctx context.Context, conn net.Conn) context.Context {
+		if tlsConn, ok := conn.(*tls.Conn); ok {
+			ctx = context.WithValue(ctx, helloContextKey{}, helloRef{capture: c, conn: tlsConn.NetConn()})
+		}
+		if connContext != nil {
+			return connContext(ctx, conn)
+		}
+		return ctx
+	}
+
+	connState := srv.ConnState
+	srv.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateClosed || state == http.StateHijacked {
+			if tlsConn, ok := conn.(*tls.Conn); ok {
+				c.hellos.Delete(tlsConn.NetConn())
+			}
+		}
+		if connState != nil {
+			connState(conn, state)
+		}
+	}
+}
+
+// ClientHelloFromContext returns the ClientHello captured for the
+// connection that ctx belongs to, or nil if none was captured.
+func ClientHelloFromContext(ctx context.Context) *ClientHello {
+	ref, ok := ctx.Value(helloContextKey{}).(helloRef)
+	if !ok {
+		return nil
+	}
+	hello, ok := ref.capture.hellos.Load(ref.conn)
+	if !ok {
+		return nil
+	}
+	return hello.(*ClientHello)
+}
@@ -0,0 +1,180 @@
+package fingerprint
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+// ja3Example is the ClientHello of the example in the JA3 README
+// (github.com/salesforce/ja3).
+func ja3Example() *ClientHello {
+	return &ClientHello{
+		Version:         tls.VersionTLS10,
+		CipherSuites:    []uint16{47, 53, 5, 10, 49161, 49162, 49171, 49172, 50, 56, 19, 4},
+		Extensions:      []uint16{0, 10, 11},
+		SupportedCurves: []tls.CurveID{23, 24, 25},
+		SupportedPoints: []uint8{0},
+	}
+}
+
+func TestJA3(t *testing.T) {
+	const (
+		wantString = "769,47-53-5-10-49161-49162-49171-49172-50-56-19-4,0-10-11,23-24-25,0"
+		wantHash   = "ada70206e40642a3e4461f35503241d5"
+	)
+
+	greased := ja3Example()
+	greased.CipherSuites = append([]uint16{0x0a0a}, greased.CipherSuites...)
+	greased.Extensions = append([]uint16{0x1a1a}, append(greased.Extensions, 0xfafa)...)
+	greased.SupportedCurves = append([]tls.CurveID{0x2a2a}, greased.SupportedCurves...)
+
+	tests := []struct {
+		name  string
+		hello *ClientHello
+	}{
+		{name: "published example", hello: ja3Example()},
+		{name: "GREASE values", hello: greased},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hello.JA3String(); got != wantString {
+				t.Errorf("JA3String() = %q, want %q", got, wantString)
+			}
+			if got := tt.hello.JA3(); got != wantHash {
+				t.Errorf("JA3() = %q, want %q", got, wantHash)
+			}
+		})
+	}
+}
+
+func TestJA3EmptyLists(t *testing.T) {
+	hello := &ClientHello{Version: tls.VersionTLS12, CipherSuites: []uint16{0x1301}}
+	if got, want := hello.JA3String(), "771,4865,,,"; got != want {
+		t.Errorf("JA3String() = %q, want %q", got, want)
+	}
+}
+
+func TestIsGREASE(t *testing.T) {
+	for _, v := range []uint16{0x0a0a, 0x1a1a, 0x2a2a, 0x7a7a, 0xdada, 0xfafa} {
+		if !isGREASE(v) {
+			t.Errorf("isGREASE(%#04x) = false, want true", v)
+		}
+	}
+	for _, v := range []uint16{0x0000, 0x0a1a, 0x1a0a, 0x0b0b, 0x1301, 0xc02b} {
+		if isGREASE(v) {
+			t.Errorf("isGREASE(%#04x) = true, want false", v)
+		}
+	}
+}
+
+func TestNewClientHelloVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []uint16
+		want     uint16
+	}{
+		{name: "TLS 1.3 reports the legacy TLS 1.2", versions: []uint16{0x3a3a, tls.VersionTLS13, tls.VersionTLS12}, want: tls.VersionTLS12},
+		{name: "TLS 1.1 and 1.0", versions: []uint16{tls.VersionTLS11, tls.VersionTLS10}, want: tls.VersionTLS11},
+		{name: "GREASE only", versions: []uint16{0x3a3a}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hello := newClientHello(&tls.ClientHelloInfo{SupportedVersions: tt.versions})
+			if hello.Version != tt.want {
+				t.Errorf("Version = %#04x, want %#04x", hello.Version, tt.want)
+			}
+		})
+	}
+}
+
+// ja4Example is the Chrome ClientHello of the worked example in the JA4
+// technical details (github.com/FoxIO-LLC/ja4), with the cipher suites and
+// extensions in the order Chrome sends them.
+func ja4Example() *ClientHello {
+	return &ClientHello{
+		Version: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			0x1301, 0x1302, 0x1303, 0xc02b, 0xc02f, 0xc02c, 0xc030, 0xcca9,
+			0xcca8, 0xc013, 0xc014, 0x009c, 0x009d, 0x002f, 0x0035,
+		},
+		Extensions: []uint16{
+			0x001b, 0x0000, 0x0033, 0x0010, 0x4469, 0x0017, 0x002d, 0x000d,
+			0x0005, 0x0023, 0x0012, 0x002b, 0xff01, 0x000b, 0x000a, 0x0015,
+		},
+		SupportedVersions: []uint16{tls.VersionTLS13, tls.VersionTLS12},
+		SignatureSchemes: []tls.SignatureScheme{
+			0x0403, 0x0804, 0x0401, 0x0503, 0x0805, 0x0501, 0x0806, 0x0601,
+		},
+		ALPN:       []string{"h2", "http/1.1"},
+		ServerName: "www.example.com",
+	}
+}
+
+func TestJA4(t *testing.T) {
+	greased := ja4Example()
+	greased.CipherSuites = append([]uint16{0x4a4a}, greased.CipherSuites...)
+	greased.Extensions = append([]uint16{0x5a5a}, append(greased.Extensions, 0x6a6a)...)
+	greased.SupportedVersions = append([]uint16{0x7a7a}, greased.SupportedVersions...)
+
+	noSNI := ja4Example()
+	noSNI.ServerName = ""
+
+	http11 := ja4Example()
+	http11.ALPN = []string{"http/1.1"}
+
+	noALPN := ja4Example()
+	noALPN.ALPN = nil
+
+	binaryALPN := ja4Example()
+	binaryALPN.ALPN = []string{"\xab\xcd"}
+
+	// Only SNI and ALPN, which are counted but never hashed
+	onlyUnhashed := ja4Example()
+	onlyUnhashed.Extensions = []uint16{0x0000, 0x0010}
+
+	noCiphers := ja4Example()
+	noCiphers.CipherSuites = nil
+
+	tests := []struct {
+		name  string
+		hello *ClientHello
+		want  string
+	}{
+		{name: "published example", hello: ja4Example(), want: "t13d1516h2_8daaf6152771_e5627efa2ab1"},
+		{name: "GREASE values", hello: greased, want: "t13d1516h2_8daaf6152771_e5627efa2ab1"},
+		{name: "no SNI", hello: noSNI, want: "t13i1516h2_8daaf6152771_e5627efa2ab1"},
+		{name: "HTTP/1.1 ALPN", hello: http11, want: "t13d1516h1_8daaf6152771_e5627efa2ab1"},
+		{name: "no ALPN", hello: noALPN, want: "t13d151600_8daaf6152771_e5627efa2ab1"},
+		{name: "non-alphanumeric ALPN", hello: binaryALPN, want: "t13d1516ad_8daaf6152771_e5627efa2ab1"},
+		{name: "no hashed extensions", hello: onlyUnhashed, want: "t13d1502h2_8daaf6152771_000000000000"},
+		{name: "no cipher suites", hello: noCiphers, want: "t13d0016h2_000000000000_e5627efa2ab1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hello.JA4(); got != tt.want {
+				t.Errorf("JA4() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJA4Version(t *testing.T) {
+	tests := []struct {
+		version  uint16
+		versions []uint16
+		want     string
+	}{
+		{version: tls.VersionTLS12, versions: []uint16{0x8a8a, tls.VersionTLS13}, want: "13"},
+		{version: tls.VersionTLS12, want: "12"},
+		{version: tls.VersionTLS11, want: "11"},
+		{version: tls.VersionTLS10, want: "10"},
+		{version: 0x0300, want: "s3"},
+		{version: 0x0200, want: "00"},
+	}
+	for _, tt := range tests {
+		hello := &ClientHello{Version: tt.version, SupportedVersions: tt.versions}
+		if got := ja4Version(hello); got != tt.want {
+			t.Errorf("ja4Version(%#04x, %x) = %q, want %q", tt.version, tt.versions, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package fingerprint
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// tokenVersion prefixes every token, so the format can change without old
+// tokens being misread.
+const tokenVersion = "fpt1"
+
+// Errors returned by VerifyToken.
+var (
+	ErrTokenMalformed = errors.New("malformed fingerprint token")
+	ErrTokenSignature = errors.New("invalid fingerprint token signature")
+	ErrTokenExpired   = errors.New("fingerprint token expired")
+)
+
+// Token is a fingerprint vouched for by whoever holds the secret it was
+// signed with, so services behind the one that computed it can trust it
+// without recomputing it.
+type Token struct {
+	Fingerprint string
+	IssuedAt    time.Time
+	ExpiresAt   time.Time
+	// Metadata holds further signed fields, such as the stable fingerprint
+	// or bot score, as encoding/json decodes them into an any
+	Metadata map[string]any
+}
+
+type tokenPayload struct {
+	Fingerprint string         `json:"fp"`
+	IssuedAt    int64          `json:"iat"`
+	ExpiresAt   int64          `json:"exp"`
+	Metadata    map[string]any `json:"meta,omitempty"`
+}
+
+// SignToken encodes t as a compact, URL-safe token signed with
+// HMAC-SHA256 under secret:
+//
+//	"fpt1." base64url(JSON payload) "." base64url(mac)
+//
+// The payload is signed, not encrypted, so anyone holding the token can
+// read it. Times are kept to the second.
+func SignToken(t Token, secret []byte) (string, error) {
+	if len(secret) == 0 {
+		return "", errors.New("empty token secret")
+	}
+	payload, err := json.Marshal(tokenPayload{
+		Fingerprint: t.Fingerprint,
+		IssuedAt:    t.IssuedAt.Unix(),
+		ExpiresAt:   t.ExpiresAt.Unix(),
+		Metadata:    t.Metadata,
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := tokenVersion + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signToken(signed, secret)), nil
+}
+
+// VerifyToken checks that token was signed with secret and has not
+// expired, and returns its content. It returns ErrTokenMalformed,
+// ErrTokenSignature, or ErrTokenExpired on failure; the content of an
+// authentic but expired token is returned along with ErrTokenExpired.
+func VerifyToken(token string, secret []byte) (Token, error) {
+	return VerifyTokenAt(token, secret, time.Now())
+}
+
+// VerifyTokenAt is VerifyToken with now as the current time.
+func VerifyTokenAt(token string, secret []byte, now time.Time) (Token, error) {
+	signed, encMAC, ok := cutLast(token, ".")
+	if !ok {
+		return Token{}, ErrTokenMalformed
+	}
+	version, encPayload, ok := strings.Cut(signed, ".")
+	if !ok || version != tokenVersion {
+		return Token{}, ErrTokenMalformed
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encMAC)
+	if err != nil {
+		return Token{}, ErrTokenMalformed
+	}
+	if len(secret) == 0 || !hmac.Equal(mac, signToken(signed, secret)) {
+		return Token{}, ErrTokenSignature
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return Token{}, ErrTokenMalformed
+	}
+	var payload tokenPayload
+	if err := json.Unmarshal(raw, &payload); err != nil || payload.Fingerprint == "" {
+		return Token{}, ErrTokenMalformed
+	}
+	t := Token{
+		Fingerprint: payload.Fingerprint,
+		IssuedAt:    time.Unix(payload.IssuedAt, 0),
+		ExpiresAt:   time.Unix(payload.ExpiresAt, 0),
+		Metadata:    payload.Metadata,
+	}
+	if !now.Before(t.ExpiresAt) {
+		return t, ErrTokenExpired
+	}
+	return t, nil
+}
+
+func signToken(signed string, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return mac.Sum(nil)
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,76 @@
+package fingerprint
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flipTokenByte returns token with the first byte of its part'th
+// dot-separated part inverted, re-encoded so the token still decodes.
+func flipTokenByte(t *testing.T, token string, part int) string {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	raw, err := base64.RawURLEncoding.DecodeString(parts[part])
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[0] ^= 0xff
+	parts[part] = base64.RawURLEncoding.EncodeToString(raw)
+	return strings.Join(parts, ".")
+}
+
+func TestVerifyToken(t *testing.T) {
+	secret := []byte("token-secret")
+	issued := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	want := Token{
+		Fingerprint: "v1:3f1a",
+		IssuedAt:    issued,
+		ExpiresAt:   issued.Add(time.Hour),
+		Metadata:    map[string]any{"stable_fingerprint": "v1:9c2e", "bot_score": float64(10)},
+	}
+	token, err := SignToken(want, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		secret  []byte
+		at      time.Duration
+		wantErr error
+	}{
+		{name: "valid", token: token, secret: secret, at: time.Minute},
+		{name: "flipped payload byte", token: flipTokenByte(t, token, 1), secret: secret, at: time.Minute, wantErr: ErrTokenSignature},
+		{name: "flipped signature byte", token: flipTokenByte(t, token, 2), secret: secret, at: time.Minute, wantErr: ErrTokenSignature},
+		{name: "wrong secret", token: token, secret: []byte("other-secret"), at: time.Minute, wantErr: ErrTokenSignature},
+		{name: "empty secret", token: token, at: time.Minute, wantErr: ErrTokenSignature},
+		{name: "expired", token: token, secret: secret, at: time.Hour, wantErr: ErrTokenExpired},
+		{name: "malformed", token: "not-a-token", secret: secret, wantErr: ErrTokenMalformed},
+		{name: "unknown version", token: "fpt0" + strings.TrimPrefix(token, tokenVersion), secret: secret, wantErr: ErrTokenMalformed},
+		{name: "signature not base64", token: token + "!", secret: secret, wantErr: ErrTokenMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyTokenAt(tt.token, tt.secret, issued.Add(tt.at))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyTokenAt error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrTokenExpired) {
+				return
+			}
+			if got.Fingerprint != want.Fingerprint || !got.IssuedAt.Equal(want.IssuedAt) ||
+				!got.ExpiresAt.Equal(want.ExpiresAt) || !reflect.DeepEqual(got.Metadata, want.Metadata) {
+				t.Errorf("VerifyTokenAt = %+v, want %+v", got, want)
+			}
+		})
+	}
+
+	if _, err := SignToken(want, nil); err == nil {
+		t.Error("SignToken accepted an empty secret")
+	}
+}
@@ -0,0 +1,311 @@
+package fingerprint
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ToolSignature describes the requests an HTTP client tool, such as curl
+// or a language's standard library client, sends when left at its
+// defaults. Every field but Name is optional; fields that are set are
+// checks a request passes or fails.
+type ToolSignature struct {
+	Name string `yaml:"name"`
+	// UserAgent is a regular expression the default User-Agent of the
+	// tool matches.
+	UserAgent string `yaml:"user_agent"`
+	// Headers maps lower-cased names of headers the tool always sends to
+	// regular expressions their whole values match.
+	Headers map[string]string `yaml:"headers"`
+	// Absent lists lower-cased names of headers the tool never sends.
+	Absent []string `yaml:"absent"`
+	// Order is the tool's header order, compared case-insensitively with
+	// Data.HeaderOrder when it was captured.
+	Order []string `yaml:"order"`
+	// Transport is a regular expression the whole Data.Transport
+	// signature matches, checked when it was captured.
+	Transport string `yaml:"transport"`
+}
+
+// Check weights. A matching User-Agent outweighs the header profile, but a
+// request whose User-Agent was changed is still recognized by the headers
+// and order the tool sends, at a lower confidence.
+const (
+	toolUserAgentWeight = 3
+	toolOrderWeight     = 2
+	toolHeaderWeight    = 1
+)
+
+// MinToolConfidence is the lowest confidence a ToolMatch is reported at.
+const MinToolConfidence = 0.5
+
+// defaultToolSignatures are the default request profiles of common HTTP
+// client tools and automation frameworks, most specific first, since the
+// first of equally confident matches wins.
+var defaultToolSignatures = []ToolSignature{
+	{
+		Name:      "headless-chrome",
+		UserAgent: `HeadlessChrome/`,
+		Headers:   map[string]string{"sec-ch-ua": `.*"HeadlessChrome".*`},
+	},
+	{
+		Name:      "curl",
+		UserAgent: `^curl/`,
+		Headers:   map[string]string{"accept": `\*/\*`},
+		Absent:    []string{"accept-language", "accept-encoding", "connection", "sec-fetch-mode"},
+		Order:     []string{"host", "user-agent", "accept"},
+		Transport: `none`,
+	},
+	{
+		Name:      "wget",
+		UserAgent: `^Wget2?/`,
+		Headers:   map[string]string{"accept": `\*/\*`, "accept-encoding": `identity|gzip`, "connection": `(?i)keep-alive`},
+		Absent:    []string{"accept-language", "sec-fetch-mode"},
+		Order:     []string{"host", "user-agent", "accept", "accept-encoding", "connection"},
+		Transport: `connection=keep-alive/title`,
+	},
+	{
+		Name:      "python-requests",
+		UserAgent: `^python-requests/`,
+		Headers:   map[string]string{"accept": `\*/\*`, "accept-encoding": `gzip, deflate(, br)?(, zstd)?`, "connection": `keep-alive`},
+		Absent:    []string{"accept-language", "sec-fetch-mode"},
+		Order:     []string{"host", "user-agent", "accept-encoding", "accept", "connection"},
+		Transport: `connection=keep-alive/lower`,
+	},
+	{
+		Name:      "python-httpx",
+		UserAgent: `^python-httpx/`,
+		Headers:   map[string]string{"accept": `\*/\*`, "accept-encoding": `gzip, deflate(, br)?(, zstd)?`, "connection": `keep-alive`},
+		Absent:    []string{"accept-language", "sec-fetch-mode"},
+		Order:     []string{"host", "accept", "accept-encoding", "connection", "user-agent"},
+	},
+	{
+		Name:      "python-aiohttp",
+		UserAgent: `^Python/[0-9.]+ aiohttp/`,
+		Headers:   map[string]string{"accept": `\*/\*`, "accept-encoding": `gzip, deflate(, br)?`},
+		Absent:    []string{"accept-language", "sec-fetch-mode"},
+	},
+	{
+		Name:      "python-urllib",
+		UserAgent: `^Python-urllib/`,
+		Headers:   map[string]string{"accept-encoding": `identity`, "connection": `close`},
+		Absent:    []string{"accept", "accept-language", "sec-fetch-mode"},
+		Order:     []string{"accept-encoding", "host", "user-agent", "connection"},
+		Transport: `connection=close/lower`,
+	},
+	{
+		Name:      "go-http",
+		UserAgent: `^Go-http-client/`,
+		Headers:   map[string]string{"accept-encoding": `gzip`},
+		Absent:    []string{"accept", "accept-language", "connection", "sec-fetch-mode"},
+		Order:     []string{"host", "user-agent", "accept-encoding"},
+		Transport: `none`,
+	},
+	{
+		Name:      "node-fetch",
+		UserAgent: `^(node|undici)$`,
+		Headers:   map[string]string{"accept": `\*/\*`, "accept-language": `\*`, "sec-fetch-mode": `cors`, "accept-encoding": `gzip, deflate(, br)?`},
+		Order:     []string{"host", "connection", "accept", "accept-language", "sec-fetch-mode", "user-agent", "accept-encoding"},
+	},
+	{
+		Name:      "axios",
+		UserAgent: `^axios/`,
+		Headers:   map[string]string{"accept": `application/json, text/plain, \*/\*`, "accept-encoding": `gzip, compress, deflate, br`},
+		Absent:    []string{"accept-language", "sec-fetch-mode"},
+	},
+	{
+		Name:      "okhttp",
+		UserAgent: `^okhttp/`,
+		Headers:   map[string]string{"accept-encoding": `gzip`, "connection": `(?i)keep-alive`},
+		Absent:    []string{"accept", "accept-language", "sec-fetch-mode"},
+		Order:     []string{"host", "connection", "accept-encoding", "user-agent"},
+		Transport: `connection=keep-alive/title`,
+	},
+	{
+		Name:      "java",
+		UserAgent: `^(Java-http-client|Java)/`,
+		Absent:    []string{"accept-language", "sec-fetch-mode"},
+		// java.net.http offers an h2c upgrade on plain HTTP/1.1
+		Transport: `connection=(keep-alive/lower|upgrade,http2-settings/title)`,
+	},
+	{
+		Name:      "httpie",
+		UserAgent: `^HTTPie/`,
+		Headers:   map[string]string{"accept": `\*/\*`, "accept-encoding": `gzip, deflate(, br)?(, zstd)?`, "connection": `keep-alive`},
+		Absent:    []string{"accept-language", "sec-fetch-mode"},
+	},
+	{
+		Name:      "powershell",
+		UserAgent: `(WindowsPowerShell|PowerShell)/`,
+		Absent:    []string{"accept", "accept-language", "sec-fetch-mode"},
+	},
+	{
+		Name:      "ruby",
+		UserAgent: `^Ruby$`,
+		Headers:   map[string]string{"accept": `\*/\*`, "accept-encoding": `gzip;q=1\.0,deflate;q=0\.6,identity;q=0\.3`},
+		Absent:    []string{"accept-language", "sec-fetch-mode"},
+	},
+	{
+		Name:      "libwww-perl",
+		UserAgent: `^libwww-perl/`,
+		Absent:    []string{"accept", "accept-language", "sec-fetch-mode"},
+	},
+}
+
+// DefaultToolSignatures returns a copy of the built-in tool signatures.
+func DefaultToolSignatures() []ToolSignature {
+	return slices.Clone(defaultToolSignatures)
+}
+
+// ToolMatch is the client tool a request most resembles.
+type ToolMatch struct {
+	// Tool is the Name of the best matching signature, or empty when none
+	// reaches MinToolConfidence
+	Tool string
+	// Confidence is the weighted share of the signature's checks the
+	// request passed, from 0 to 1
+	Confidence float64
+}
+
+// ToolClassifier matches requests against a list of ToolSignatures. It is
+// safe for concurrent use.
+type ToolClassifier struct {
+	tools []compiledTool
+}
+
+type compiledTool struct {
+	name      string
+	userAgent *regexp.Regexp
+	headers   map[string]*regexp.Regexp
+	absent    []string
+	order     []string
+	transport *regexp.Regexp
+}
+
+// NewToolClassifier compiles signatures into a classifier. It fails if a
+// signature has no name or checks, or an invalid regular expression.
+func NewToolClassifier(signatures []ToolSignature) (*ToolClassifier, error) {
+	c := &ToolClassifier{tools: make([]compiledTool, 0, len(signatures))}
+	for _, sig := range signatures {
+		if sig.Name == "" {
+			return nil, fmt.Errorf("tool signature without a name")
+		}
+		if sig.UserAgent == "" && len(sig.Headers) == 0 && len(sig.Absent) == 0 && len(sig.Order) == 0 && sig.Transport == "" {
+			return nil, fmt.Errorf("tool signature %s has no checks", sig.Name)
+		}
+		tool := compiledTool{name: sig.Name, headers: make(map[string]*regexp.Regexp, len(sig.Headers))}
+		var err error
+		if sig.UserAgent != "" {
+			if tool.userAgent, err = regexp.Compile(sig.UserAgent); err != nil {
+				return nil, fmt.Errorf("tool signature %s: user agent: %w", sig.Name, err)
+			}
+		}
+		for name, pattern := range sig.Headers {
+			if tool.headers[strings.ToLower(name)], err = regexp.Compile(`^(?:` + pattern + `)$`); err != nil {
+				return nil, fmt.Errorf("tool signature %s: header %s: %w", sig.Name, name, err)
+			}
+		}
+		for _, name := range sig.Absent {
+			tool.absent = append(tool.absent, strings.ToLower(name))
+		}
+		for _, name := range sig.Order {
+			tool.order = append(tool.order, strings.ToLower(name))
+		}
+		if sig.Transport != "" {
+			if tool.transport, err = regexp.Compile(`^(?:` + sig.Transport + `)$`); err != nil {
+				return nil, fmt.Errorf("tool signature %s: transport: %w", sig.Name, err)
+			}
+		}
+		c.tools = append(c.tools, tool)
+	}
+	return c, nil
+}
+
+var defaultToolClassifier, _ = NewToolClassifier(defaultToolSignatures)
+
+// ClassifyTool matches data against the built-in tool signatures.
+func ClassifyTool(data Data) ToolMatch {
+	return defaultToolClassifier.Classify(data)
+}
+
+// Classify returns the signature data matches with the highest confidence,
+// the first one listed on a tie. A check on a header that was not
+// fingerprinted, or on the header order or transport signature when it was
+// not captured, is left out rather than failed.
+func (c *ToolClassifier) Classify(data Data) ToolMatch {
+	var best ToolMatch
+	for _, tool := range c.tools {
+		if confidence := tool.confidence(data); confidence > best.Confidence {
+			best = ToolMatch{Tool: tool.name, Confidence: confidence}
+		}
+	}
+	if best.Confidence < MinToolConfidence {
+		return ToolMatch{}
+	}
+	return best
+}
+
+func (t compiledTool) confidence(data Data) float64 {
+	var passed, total float64
+	check := func(weight float64, ok bool) {
+		total += weight
+		if ok {
+			passed += weight
+		}
+	}
+
+	if t.userAgent != nil {
+		check(toolUserAgentWeight, data.UserAgent != "" && t.userAgent.MatchString(data.UserAgent))
+	}
+	for name, pattern := range t.headers {
+		if value, known := toolHeader(data, name); known {
+			check(toolHeaderWeight, value != "" && pattern.MatchString(value))
+		}
+	}
+	for _, name := range t.absent {
+		if value, known := toolHeader(data, name); known {
+			check(toolHeaderWeight, value == "")
+		}
+	}
+	if len(t.order) > 0 && len(data.HeaderOrder) > 0 {
+		check(toolOrderWeight, slices.EqualFunc(t.order, data.HeaderOrder, strings.EqualFold))
+	}
+	if t.transport != nil && data.Transport != "" {
+		check(toolHeaderWeight, t.transport.MatchString(data.Transport))
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return math.Round(passed/total*100) / 100
+}
+
+// toolHeader returns the value of the named header in data, and whether
+// its presence is known: either it is fingerprinted, or the header order
+// was captured and lists every header the request sent. A header known
+// only from the order reports a placeholder value.
+func toolHeader(data Data, name string) (string, bool) {
+	switch name {
+	case "user-agent":
+		return data.UserAgent, true
+	case "accept":
+		return data.Accept, true
+	case "accept-language":
+		return data.AcceptLang, true
+	case "accept-encoding":
+		return data.AcceptEnc, true
+	}
+	if value, ok := data.Headers[name]; ok {
+		return value, true
+	}
+	if len(data.HeaderOrder) == 0 {
+		return "", false
+	}
+	if slices.ContainsFunc(data.HeaderOrder, func(header string) bool { return strings.EqualFold(header, name) }) {
+		return "?", true
+	}
+	return "", true
+}
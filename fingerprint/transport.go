@@ -0,0 +1,125 @@
+package fingerprint
+
+import (
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// hopByHopHeaders are the headers that describe one connection rather than
+// the request (RFC 9110, section 7.6.1). A proxy drops them, along with
+// every header Connection names, before forwarding, and HTTP/2 and HTTP/3
+// forbid all of them but TE: trailers.
+var hopByHopHeaders = []string{"connection", "keep-alive", "proxy-connection", "te"}
+
+// requestTransport returns the transport signature of r, or "" when r
+// arrived from a trusted proxy, whose hop-by-hop headers describe its own
+// connection to the server rather than the client's.
+func requestTransport(r *http.Request, trusted []netip.Prefix) string {
+	if remote, err := parseHostAddr(r.RemoteAddr); err == nil && isTrusted(remote, trusted) {
+		return ""
+	}
+	return transportSignature(func(name string) string {
+		return strings.Join(r.Header.Values(name), ", ")
+	})
+}
+
+// transportSignature folds the hop-by-hop headers that get returns, by
+// lower-cased name, into one value: the Connection options in the order
+// sent along with their letter case, the TE codings, whether Keep-Alive
+// was sent, and the Proxy-Connection options, for example
+//
+//	connection=keep-alive,upgrade/title;te=trailers
+//
+// Clients that send none of them, as curl and Go do over HTTP/1.1, get
+// "none". HTTP libraries differ in all of these where browsers agree. The
+// letter case is kept even when NormalizeHeaders folds it out of the
+// Connection header itself.
+func transportSignature(get func(name string) string) string {
+	var parts []string
+	if connection := get("connection"); connection != "" {
+		parts = append(parts, "connection="+transportTokens(connection)+"/"+letterCase(connection))
+	}
+	if te := get("te"); te != "" {
+		parts = append(parts, "te="+transportTokens(te))
+	}
+	if get("keep-alive") != "" {
+		parts = append(parts, "keep-alive")
+	}
+	if proxyConnection := get("proxy-connection"); proxyConnection != "" {
+		parts = append(parts, "proxy-connection="+transportTokens(proxyConnection))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ";")
+}
+
+// transportTokens lower-cases the elements of a comma-separated list and
+// strips their whitespace, keeping their order.
+func transportTokens(list string) string {
+	var tokens []string
+	for token := range strings.SplitSeq(list, ",") {
+		token = strings.ToLower(strings.Join(strings.Fields(token), ""))
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return strings.Join(tokens, ",")
+}
+
+// letterCase names how value is capitalized: "lower", "upper", "title"
+// when every word starts with a capital, as in Keep-Alive, or "mixed".
+func letterCase(value string) string {
+	switch {
+	case value == strings.ToLower(value):
+		return "lower"
+	case value == strings.ToUpper(value):
+		return "upper"
+	}
+	words := strings.FieldsFunc(value, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if first := rune(word[0]); unicode.IsLetter(first) && !unicode.IsUpper(first) {
+			return "mixed"
+		}
+	}
+	return "title"
+}
+
+// transportPart returns the value of the named part of a transport
+// signature, or "" when it has none. Parts without a value, such as
+// keep-alive, return their name.
+func transportPart(signature, name string) string {
+	for part := range strings.SplitSeq(signature, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if key == name {
+			if !ok {
+				return key
+			}
+			return value
+		}
+	}
+	return ""
+}
+
+// connectionHeaders returns the lower-cased names of the hop-by-hop
+// headers of a request whose Connection header is connection:
+// hopByHopHeaders and the headers connection names. The result must not
+// be modified.
+func connectionHeaders(connection string) []string {
+	names := hopByHopHeaders
+	for token := range strings.SplitSeq(transportTokens(connection), ",") {
+		switch token {
+		case "", "close", "upgrade":
+		default:
+			if !slices.Contains(names, token) {
+				names = append(slices.Clip(names), token)
+			}
+		}
+	}
+	return names
+}
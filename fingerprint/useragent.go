@@ -0,0 +1,156 @@
+package fingerprint
+
+import (
+	"regexp"
+	"strings"
+)
+
+// UserAgent is the structured form of a User-Agent header.
+type UserAgent struct {
+	Browser        string `json:"browser,omitempty"`
+	BrowserVersion string `json:"browser_version,omitempty"`
+	OS             string `json:"os,omitempty"`
+	OSVersion      string `json:"os_version,omitempty"`
+	Device         string `json:"device,omitempty"`
+	Engine         string `json:"engine,omitempty"`
+}
+
+// Device types reported in UserAgent.Device.
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+)
+
+type uaRule struct {
+	name    string
+	pattern *regexp.Regexp
+	engine  string
+}
+
+// botRules identify crawlers and automated clients. They are checked
+// before browsers because many bots embed a browser token.
+var botRules = []uaRule{
+	{"Googlebot", regexp.MustCompile(`Googlebot(?:-\w+)?/(\d[\w.]*)`), ""},
+	{"Bingbot", regexp.MustCompile(`bingbot/(\d[\w.]*)`), ""},
+	{"DuckDuckBot", regexp.MustCompile(`DuckDuckBot(?:-\w+)?/(\d[\w.]*)`), ""},
+	{"YandexBot", regexp.MustCompile(`YandexBot/(\d[\w.]*)`), ""},
+	{"Baiduspider", regexp.MustCompile(`Baiduspider(?:-\w+)?/(\d[\w.]*)`), ""},
+	{"Applebot", regexp.MustCompile(`Applebot/(\d[\w.]*)`), ""},
+	{"facebookexternalhit", regexp.MustCompile(`facebookexternalhit/(\d[\w.]*)`), ""},
+	{"Twitterbot", regexp.MustCompile(`Twitterbot/(\d[\w.]*)`), ""},
+	{"GPTBot", regexp.MustCompile(`GPTBot/(\d[\w.]*)`), ""},
+	{"curl", regexp.MustCompile(`^curl/(\d[\w.]*)`), ""},
+	{"Wget", regexp.MustCompile(`^Wget/(\d[\w.]*)`), ""},
+	{"python-requests", regexp.MustCompile(`^python-requests/(\d[\w.]*)`), ""},
+	{"Go-http-client", regexp.MustCompile(`^Go-http-client/(\d[\w.]*)`), ""},
+	{"HeadlessChrome", regexp.MustCompile(`HeadlessChrome/(\d[\w.]*)`), "Blink"},
+}
+
+// genericBot matches the conventional crawler self-identification tokens.
+var genericBot = regexp.MustCompile(`(?i)bot\b|crawl|spider|slurp`)
+
+// browserRules are checked in order; browsers that build on Chromium or
+// WebKit must come before Chrome and Safari because they include those
+// tokens too.
+var browserRules = []uaRule{
+	{"Edge", regexp.MustCompile(`Edg(?:A|iOS)?/(\d[\w.]*)`), "Blink"},
+	{"Edge", regexp.MustCompile(`Edge/(\d[\w.]*)`), "EdgeHTML"},
+	{"Opera", regexp.MustCompile(`OPR/(\d[\w.]*)`), "Blink"},
+	{"Samsung Internet", regexp.MustCompile(`SamsungBrowser/(\d[\w.]*)`), "Blink"},
+	{"Firefox", regexp.MustCompile(`FxiOS/(\d[\w.]*)`), "WebKit"},
+	{"Firefox", regexp.MustCompile(`Firefox/(\d[\w.]*)`), "Gecko"},
+	{"Chrome", regexp.MustCompile(`CriOS/(\d[\w.]*)`), "WebKit"},
+	{"Chrome", regexp.MustCompile(`Chrome/(\d[\w.]*)`), "Blink"},
+	{"Safari", regexp.MustCompile(`Version/(\d[\w.]*).*Safari/`), "WebKit"},
+	{"Internet Explorer", regexp.MustCompile(`MSIE (\d[\w.]*)`), "Trident"},
+	{"Internet Explorer", regexp.MustCompile(`Trident/.*rv:(\d[\w.]*)`), "Trident"},
+}
+
+var osRules = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"iOS", regexp.MustCompile(`(?:iPhone|CPU) OS (\d+(?:_\d+)*)`)},
+	{"Android", regexp.MustCompile(`Android (\d+(?:\.\d+)*)`)},
+	{"Chrome OS", regexp.MustCompile(`CrOS \S+ (\d+(?:\.\d+)*)`)},
+	{"Windows", regexp.MustCompile(`Windows NT (\d+\.\d+)`)},
+	{"macOS", regexp.MustCompile(`Mac OS X (\d+(?:[_.]\d+)*)`)},
+	{"Linux", regexp.MustCompile(`Linux()`)},
+}
+
+// windowsVersions maps Windows NT kernel versions to marketing names.
+var windowsVersions = map[string]string{
+	"10.0": "10",
+	"6.3":  "8.1",
+	"6.2":  "8",
+	"6.1":  "7",
+	"6.0":  "Vista",
+	"5.1":  "XP",
+}
+
+// ParseUserAgent parses a User-Agent header into browser, OS, device, and
+// engine fields. Unrecognized components are left empty.
+func ParseUserAgent(ua string) UserAgent {
+	var parsed UserAgent
+	if ua == "" {
+		return parsed
+	}
+
+	for _, rule := range botRules {
+		if m := rule.pattern.FindStringSubmatch(ua); m != nil {
+			parsed.Browser, parsed.BrowserVersion, parsed.Engine = rule.name, m[1], rule.engine
+			parsed.Device = DeviceBot
+			break
+		}
+	}
+
+	if parsed.Browser == "" {
+		for _, rule := range browserRules {
+			if m := rule.pattern.FindStringSubmatch(ua); m != nil {
+				parsed.Browser, parsed.BrowserVersion, parsed.Engine = rule.name, m[1], rule.engine
+				break
+			}
+		}
+	}
+
+	for _, rule := range osRules {
+		if m := rule.pattern.FindStringSubmatch(ua); m != nil {
+			parsed.OS = rule.name
+			parsed.OSVersion = strings.ReplaceAll(m[1], "_", ".")
+			break
+		}
+	}
+	switch parsed.OS {
+	case "Windows":
+		if name, ok := windowsVersions[parsed.OSVersion]; ok {
+			parsed.OSVersion = name
+		}
+	case "iOS":
+		// Every browser on iOS uses WebKit regardless of its brand
+		if parsed.Engine != "" {
+			parsed.Engine = "WebKit"
+		}
+	}
+
+	if parsed.Device == "" {
+		parsed.Device = deviceType(ua)
+	}
+
+	return parsed
+}
+
+func deviceType(ua string) string {
+	switch {
+	case genericBot.MatchString(ua):
+		return DeviceBot
+	case strings.Contains(ua, "iPad") || strings.Contains(ua, "Tablet") ||
+		(strings.Contains(ua, "Android") && !strings.Contains(ua, "Mobile")):
+		return DeviceTablet
+	case strings.Contains(ua, "Mobile") || strings.Contains(ua, "iPhone"):
+		return DeviceMobile
+	default:
+		return DeviceDesktop
+	}
+}
@@ -0,0 +1,57 @@
+package fingerprint
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SchemaVersion identifies the set of components that feed fingerprint
+// hashes and the way they are serialized. It is mixed into every hash and
+// prefixed to it, as in "v1:3f1a...", so a consumer holding a stored
+// fingerprint can tell whether a freshly computed one is comparable.
+//
+// The contract is:
+//
+//   - For a given SchemaVersion, hash algorithm, and Config, the same
+//     request signals always produce the same fingerprint. The order of
+//     components within the hashed string is fixed (see Components and
+//     GenerateStable), and headers are always sorted by name.
+//   - Any change that can alter the fingerprint of an unchanged request,
+//     such as adding, removing, renaming, or reordering a component or
+//     changing how a value is normalized, must increment SchemaVersion in
+//     the same change, and update the expected hashes of the
+//     SelfTestFixtures.
+//   - Opt-in signals controlled by Config, such as BodySignals, are part of
+//     the schema: enabling one changes fingerprints without a version
+//     bump, just as changing the header list does.
+//
+// Versions:
+//
+//	1  initial versioned schema
+//	2  WebSocket upgrades add their handshake headers and ws-key
+//	3  Sec-Ch-Ua and Sec-Ch-Ua-Full-Version-List drop GREASE brands and
+//	   sort the rest
+//	4  the protocol component is normalized to h1.0, h1.1, h2, or h3
+//	5  components are length-prefixed rather than only joined with "|"
+//	6  the Priority header and the extended CONNECT protocol are added
+//	7  proxy and trace headers such as Via, CF-Ray, and X-Amzn-Trace-Id
+//	   are left out by default (see Config.ProxyHeaders)
+const SchemaVersion = 7
+
+// versionPrefix is prepended to both the hashed string and the hex digest.
+var versionPrefix = "v" + strconv.Itoa(SchemaVersion)
+
+// SplitVersion splits a fingerprint such as "v1:3f1a..." into its schema
+// version and hex digest. ok is false for fingerprints without a valid
+// version prefix, such as ones produced before versioning was introduced.
+func SplitVersion(fingerprint string) (version int, digest string, ok bool) {
+	prefix, digest, found := strings.Cut(fingerprint, ":")
+	if !found || !strings.HasPrefix(prefix, "v") {
+		return 0, "", false
+	}
+	version, err := strconv.Atoi(prefix[1:])
+	if err != nil || version < 1 || digest == "" {
+		return 0, "", false
+	}
+	return version, digest, true
+}
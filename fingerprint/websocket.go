@@ -0,0 +1,62 @@
+package fingerprint
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// webSocketHeaders are extracted in addition to the configured headers
+// when a request is a WebSocket upgrade. Clients differ in the protocol
+// version, the extensions they offer, and how they list subprotocols.
+var webSocketHeaders = []string{
+	"Upgrade",
+	"Sec-WebSocket-Version",
+	"Sec-WebSocket-Extensions",
+	"Sec-WebSocket-Protocol",
+}
+
+// IsWebSocketUpgrade reports whether r asks to upgrade the connection to
+// the WebSocket protocol (RFC 6455, Section 4.1).
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return isWebSocketUpgrade(r.Header.Get)
+}
+
+func isWebSocketUpgrade(get func(string) string) bool {
+	return hasToken(get("Connection"), "upgrade") && hasToken(get("Upgrade"), "websocket")
+}
+
+// hasToken reports whether the comma-separated list contains token,
+// ignoring case.
+func hasToken(list, token string) bool {
+	for _, item := range strings.Split(list, ",") {
+		if strings.EqualFold(strings.TrimSpace(item), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// withWebSocketHeaders returns names, or the default header set when names
+// is empty, followed by the WebSocket handshake headers.
+func withWebSocketHeaders(names []string) []string {
+	if len(names) == 0 {
+		names = defaultHeaders
+	}
+	return append(append([]string(nil), names...), webSocketHeaders...)
+}
+
+// webSocketKeyFormat describes a Sec-WebSocket-Key without its random
+// value: "base64-16" for the 16 random bytes RFC 6455 requires, "invalid"
+// for anything else, or "" when the header is missing. Hand-written clients
+// often get it wrong.
+func webSocketKeyFormat(key string) string {
+	if key == "" {
+		return ""
+	}
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(raw) != 16 {
+		return "invalid"
+	}
+	return "base64-16"
+}
@@ -0,0 +1,5 @@
+// Package fingerprintpb holds the Protocol Buffers messages and gRPC
+// stubs generated from fingerprint.proto for the fingerprint service.
+package fingerprintpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative fingerprint.proto
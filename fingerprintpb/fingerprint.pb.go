@@ -0,0 +1,1200 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: fingerprint.proto
+
+package fingerprintpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type FingerprintRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Request headers keyed by name, matched case-insensitively. Repeated
+	// headers are joined with ", ".
+	Headers map[string]string `protobuf:"bytes,1,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Address of the connection the request arrived on, as host:port. Its
+	// host is the client IP unless it is a trusted proxy.
+	RemoteAddr string `protobuf:"bytes,2,opt,name=remote_addr,json=remoteAddr,proto3" json:"remote_addr,omitempty"`
+	// Request method; GET when empty.
+	Method string `protobuf:"bytes,3,opt,name=method,proto3" json:"method,omitempty"`
+	// Protocol version, such as HTTP/1.1 or HTTP/2.0; HTTP/1.1 when empty.
+	Protocol string `protobuf:"bytes,4,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	// Host the request was sent to, possibly with a port.
+	Host string `protobuf:"bytes,5,opt,name=host,proto3" json:"host,omitempty"`
+	// Request target path with the query string; / when empty.
+	Path string `protobuf:"bytes,6,opt,name=path,proto3" json:"path,omitempty"`
+	// TLS connection details, absent for plain HTTP.
+	Tls *TLSInfo `protobuf:"bytes,7,opt,name=tls,proto3" json:"tls,omitempty"`
+	// Header names in the order they were received, when known.
+	HeaderOrder   []string `protobuf:"bytes,8,rep,name=header_order,json=headerOrder,proto3" json:"header_order,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FingerprintRequest) Reset() {
+	*x = FingerprintRequest{}
+	mi := &file_fingerprint_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FingerprintRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FingerprintRequest) ProtoMessage() {}
+
+func (x *FingerprintRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FingerprintRequest.ProtoReflect.Descriptor instead.
+func (*FingerprintRequest) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *FingerprintRequest) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetRemoteAddr() string {
+	if x != nil {
+		return x.RemoteAddr
+	}
+	return ""
+}
+
+func (x *FingerprintRequest) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *FingerprintRequest) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *FingerprintRequest) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *FingerprintRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *FingerprintRequest) GetTls() *TLSInfo {
+	if x != nil {
+		return x.Tls
+	}
+	return nil
+}
+
+func (x *FingerprintRequest) GetHeaderOrder() []string {
+	if x != nil {
+		return x.HeaderOrder
+	}
+	return nil
+}
+
+type TLSInfo struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Negotiated version: TLS1.0, TLS1.1, TLS1.2, or TLS1.3.
+	Version string `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	// Negotiated cipher suite by IANA name, such as TLS_AES_128_GCM_SHA256.
+	CipherSuite string `protobuf:"bytes,2,opt,name=cipher_suite,json=cipherSuite,proto3" json:"cipher_suite,omitempty"`
+	// Negotiated ALPN protocol, such as h2.
+	Alpn          string `protobuf:"bytes,3,opt,name=alpn,proto3" json:"alpn,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TLSInfo) Reset() {
+	*x = TLSInfo{}
+	mi := &file_fingerprint_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TLSInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TLSInfo) ProtoMessage() {}
+
+func (x *TLSInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TLSInfo.ProtoReflect.Descriptor instead.
+func (*TLSInfo) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *TLSInfo) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *TLSInfo) GetCipherSuite() string {
+	if x != nil {
+		return x.CipherSuite
+	}
+	return ""
+}
+
+func (x *TLSInfo) GetAlpn() string {
+	if x != nil {
+		return x.Alpn
+	}
+	return ""
+}
+
+type FingerprintResponse struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Fingerprint       string                 `protobuf:"bytes,1,opt,name=fingerprint,proto3" json:"fingerprint,omitempty"`
+	StableFingerprint string                 `protobuf:"bytes,2,opt,name=stable_fingerprint,json=stableFingerprint,proto3" json:"stable_fingerprint,omitempty"`
+	HashAlgorithm     string                 `protobuf:"bytes,3,opt,name=hash_algorithm,json=hashAlgorithm,proto3" json:"hash_algorithm,omitempty"`
+	Ip                string                 `protobuf:"bytes,4,opt,name=ip,proto3" json:"ip,omitempty"`
+	CipherSuite       string                 `protobuf:"bytes,5,opt,name=cipher_suite,json=cipherSuite,proto3" json:"cipher_suite,omitempty"`
+	Alpn              string                 `protobuf:"bytes,6,opt,name=alpn,proto3" json:"alpn,omitempty"`
+	HeaderOrder       []string               `protobuf:"bytes,7,rep,name=header_order,json=headerOrder,proto3" json:"header_order,omitempty"`
+	Country           string                 `protobuf:"bytes,8,opt,name=country,proto3" json:"country,omitempty"`
+	City              string                 `protobuf:"bytes,9,opt,name=city,proto3" json:"city,omitempty"`
+	Asn               uint32                 `protobuf:"varint,10,opt,name=asn,proto3" json:"asn,omitempty"`
+	// Set when datacenter detection is enabled.
+	Datacenter         *bool  `protobuf:"varint,11,opt,name=datacenter,proto3,oneof" json:"datacenter,omitempty"`
+	DatacenterProvider string `protobuf:"bytes,12,opt,name=datacenter_provider,json=datacenterProvider,proto3" json:"datacenter_provider,omitempty"`
+	Hostname           string `protobuf:"bytes,13,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	VerifiedBot        bool   `protobuf:"varint,14,opt,name=verified_bot,json=verifiedBot,proto3" json:"verified_bot,omitempty"`
+	HitCount           int64  `protobuf:"varint,15,opt,name=hit_count,json=hitCount,proto3" json:"hit_count,omitempty"`
+	// RFC 3339 time the fingerprint was first stored.
+	FirstSeen     string         `protobuf:"bytes,16,opt,name=first_seen,json=firstSeen,proto3" json:"first_seen,omitempty"`
+	ClusterId     string         `protobuf:"bytes,17,opt,name=cluster_id,json=clusterId,proto3" json:"cluster_id,omitempty"`
+	Client        *Client        `protobuf:"bytes,18,opt,name=client,proto3" json:"client,omitempty"`
+	DeviceProfile *DeviceProfile `protobuf:"bytes,19,opt,name=device_profile,json=deviceProfile,proto3" json:"device_profile,omitempty"`
+	Brands        []*Brand       `protobuf:"bytes,20,rep,name=brands,proto3" json:"brands,omitempty"`
+	BotScore      int32          `protobuf:"varint,21,opt,name=bot_score,json=botScore,proto3" json:"bot_score,omitempty"`
+	BotRules      []string       `protobuf:"bytes,22,rep,name=bot_rules,json=botRules,proto3" json:"bot_rules,omitempty"`
+	LowConfidence bool           `protobuf:"varint,23,opt,name=low_confidence,json=lowConfidence,proto3" json:"low_confidence,omitempty"`
+	// Set when the TLS details could be checked against the User-Agent.
+	TlsMismatch       *bool         `protobuf:"varint,24,opt,name=tls_mismatch,json=tlsMismatch,proto3,oneof" json:"tls_mismatch,omitempty"`
+	TlsMismatchReason string        `protobuf:"bytes,25,opt,name=tls_mismatch_reason,json=tlsMismatchReason,proto3" json:"tls_mismatch_reason,omitempty"`
+	PreferredLanguage string        `protobuf:"bytes,26,opt,name=preferred_language,json=preferredLanguage,proto3" json:"preferred_language,omitempty"`
+	Languages         []*Language   `protobuf:"bytes,27,rep,name=languages,proto3" json:"languages,omitempty"`
+	MediaTypes        []*Preference `protobuf:"bytes,28,rep,name=media_types,json=mediaTypes,proto3" json:"media_types,omitempty"`
+	Encodings         []*Preference `protobuf:"bytes,29,rep,name=encodings,proto3" json:"encodings,omitempty"`
+	Charsets          []*Preference `protobuf:"bytes,30,rep,name=charsets,proto3" json:"charsets,omitempty"`
+	// RFC 3339 time the fingerprint was computed.
+	Timestamp string `protobuf:"bytes,31,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// Combination of the per-tier sub-hashes of the components.
+	TieredFingerprint string `protobuf:"bytes,32,opt,name=tiered_fingerprint,json=tieredFingerprint,proto3" json:"tiered_fingerprint,omitempty"`
+	// Normalized protocol: h1.0, h1.1, h2, or h3.
+	Protocol string `protobuf:"bytes,33,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	// Set when churn tracking is enabled and the request carries an
+	// X-Fingerprint-Key header.
+	FingerprintChurn      *bool `protobuf:"varint,34,opt,name=fingerprint_churn,json=fingerprintChurn,proto3,oneof" json:"fingerprint_churn,omitempty"`
+	FingerprintChurnCount int32 `protobuf:"varint,35,opt,name=fingerprint_churn_count,json=fingerprintChurnCount,proto3" json:"fingerprint_churn_count,omitempty"`
+	// The HTTP client tool, such as curl or go-http, the request resembles.
+	ClientTool           string  `protobuf:"bytes,36,opt,name=client_tool,json=clientTool,proto3" json:"client_tool,omitempty"`
+	ClientToolConfidence float64 `protobuf:"fixed64,37,opt,name=client_tool_confidence,json=clientToolConfidence,proto3" json:"client_tool_confidence,omitempty"`
+	// Set when the request sent Sec-Fetch-* headers.
+	SecFetchValid  *bool  `protobuf:"varint,38,opt,name=sec_fetch_valid,json=secFetchValid,proto3,oneof" json:"sec_fetch_valid,omitempty"`
+	SecFetchReason string `protobuf:"bytes,39,opt,name=sec_fetch_reason,json=secFetchReason,proto3" json:"sec_fetch_reason,omitempty"`
+	// "tor" or "vpn" when Tor exit or VPN detection matches the client IP.
+	Anonymizer         string `protobuf:"bytes,40,opt,name=anonymizer,proto3" json:"anonymizer,omitempty"`
+	AnonymizerProvider string `protobuf:"bytes,41,opt,name=anonymizer_provider,json=anonymizerProvider,proto3" json:"anonymizer_provider,omitempty"`
+	// Set when the request sent X-Forwarded-Proto, X-Forwarded-Port, or
+	// Forwarded proto=.
+	ProtoMismatch       *bool  `protobuf:"varint,42,opt,name=proto_mismatch,json=protoMismatch,proto3,oneof" json:"proto_mismatch,omitempty"`
+	ProtoMismatchReason string `protobuf:"bytes,43,opt,name=proto_mismatch_reason,json=protoMismatchReason,proto3" json:"proto_mismatch_reason,omitempty"`
+	// Set with -short-id: a prefix of the digest for display.
+	ShortId string `protobuf:"bytes,44,opt,name=short_id,json=shortId,proto3" json:"short_id,omitempty"`
+	// Set with -stability-distance: the fingerprint this one is persisted
+	// under.
+	CanonicalFingerprint string `protobuf:"bytes,45,opt,name=canonical_fingerprint,json=canonicalFingerprint,proto3" json:"canonical_fingerprint,omitempty"`
+	// Set with -token-secret: the fingerprint and -token-fields, signed for
+	// fingerprint.VerifyToken.
+	Token string `protobuf:"bytes,46,opt,name=token,proto3" json:"token,omitempty"`
+	// Set when the request sent a header carrying the client IP, such as
+	// X-Forwarded-For or X-Real-IP.
+	IpHeaderConflict       *bool  `protobuf:"varint,47,opt,name=ip_header_conflict,json=ipHeaderConflict,proto3,oneof" json:"ip_header_conflict,omitempty"`
+	IpHeaderConflictReason string `protobuf:"bytes,48,opt,name=ip_header_conflict_reason,json=ipHeaderConflictReason,proto3" json:"ip_header_conflict_reason,omitempty"`
+	// Set with -good-bots: the name of the crawler the client IP belongs
+	// to, verified by forward-confirmed reverse DNS.
+	VerifiedGoodBot string `protobuf:"bytes,49,opt,name=verified_good_bot,json=verifiedGoodBot,proto3" json:"verified_good_bot,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *FingerprintResponse) Reset() {
+	*x = FingerprintResponse{}
+	mi := &file_fingerprint_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FingerprintResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FingerprintResponse) ProtoMessage() {}
+
+func (x *FingerprintResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FingerprintResponse.ProtoReflect.Descriptor instead.
+func (*FingerprintResponse) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *FingerprintResponse) GetFingerprint() string {
+	if x != nil {
+		return x.Fingerprint
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetStableFingerprint() string {
+	if x != nil {
+		return x.StableFingerprint
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetHashAlgorithm() string {
+	if x != nil {
+		return x.HashAlgorithm
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetCipherSuite() string {
+	if x != nil {
+		return x.CipherSuite
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetAlpn() string {
+	if x != nil {
+		return x.Alpn
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetHeaderOrder() []string {
+	if x != nil {
+		return x.HeaderOrder
+	}
+	return nil
+}
+
+func (x *FingerprintResponse) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetAsn() uint32 {
+	if x != nil {
+		return x.Asn
+	}
+	return 0
+}
+
+func (x *FingerprintResponse) GetDatacenter() bool {
+	if x != nil && x.Datacenter != nil {
+		return *x.Datacenter
+	}
+	return false
+}
+
+func (x *FingerprintResponse) GetDatacenterProvider() string {
+	if x != nil {
+		return x.DatacenterProvider
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetVerifiedBot() bool {
+	if x != nil {
+		return x.VerifiedBot
+	}
+	return false
+}
+
+func (x *FingerprintResponse) GetHitCount() int64 {
+	if x != nil {
+		return x.HitCount
+	}
+	return 0
+}
+
+func (x *FingerprintResponse) GetFirstSeen() string {
+	if x != nil {
+		return x.FirstSeen
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetClusterId() string {
+	if x != nil {
+		return x.ClusterId
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetClient() *Client {
+	if x != nil {
+		return x.Client
+	}
+	return nil
+}
+
+func (x *FingerprintResponse) GetDeviceProfile() *DeviceProfile {
+	if x != nil {
+		return x.DeviceProfile
+	}
+	return nil
+}
+
+func (x *FingerprintResponse) GetBrands() []*Brand {
+	if x != nil {
+		return x.Brands
+	}
+	return nil
+}
+
+func (x *FingerprintResponse) GetBotScore() int32 {
+	if x != nil {
+		return x.BotScore
+	}
+	return 0
+}
+
+func (x *FingerprintResponse) GetBotRules() []string {
+	if x != nil {
+		return x.BotRules
+	}
+	return nil
+}
+
+func (x *FingerprintResponse) GetLowConfidence() bool {
+	if x != nil {
+		return x.LowConfidence
+	}
+	return false
+}
+
+func (x *FingerprintResponse) GetTlsMismatch() bool {
+	if x != nil && x.TlsMismatch != nil {
+		return *x.TlsMismatch
+	}
+	return false
+}
+
+func (x *FingerprintResponse) GetTlsMismatchReason() string {
+	if x != nil {
+		return x.TlsMismatchReason
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetPreferredLanguage() string {
+	if x != nil {
+		return x.PreferredLanguage
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetLanguages() []*Language {
+	if x != nil {
+		return x.Languages
+	}
+	return nil
+}
+
+func (x *FingerprintResponse) GetMediaTypes() []*Preference {
+	if x != nil {
+		return x.MediaTypes
+	}
+	return nil
+}
+
+func (x *FingerprintResponse) GetEncodings() []*Preference {
+	if x != nil {
+		return x.Encodings
+	}
+	return nil
+}
+
+func (x *FingerprintResponse) GetCharsets() []*Preference {
+	if x != nil {
+		return x.Charsets
+	}
+	return nil
+}
+
+func (x *FingerprintResponse) GetTimestamp() string {
+	if x != nil {
+		return x.Timestamp
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetTieredFingerprint() string {
+	if x != nil {
+		return x.TieredFingerprint
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetFingerprintChurn() bool {
+	if x != nil && x.FingerprintChurn != nil {
+		return *x.FingerprintChurn
+	}
+	return false
+}
+
+func (x *FingerprintResponse) GetFingerprintChurnCount() int32 {
+	if x != nil {
+		return x.FingerprintChurnCount
+	}
+	return 0
+}
+
+func (x *FingerprintResponse) GetClientTool() string {
+	if x != nil {
+		return x.ClientTool
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetClientToolConfidence() float64 {
+	if x != nil {
+		return x.ClientToolConfidence
+	}
+	return 0
+}
+
+func (x *FingerprintResponse) GetSecFetchValid() bool {
+	if x != nil && x.SecFetchValid != nil {
+		return *x.SecFetchValid
+	}
+	return false
+}
+
+func (x *FingerprintResponse) GetSecFetchReason() string {
+	if x != nil {
+		return x.SecFetchReason
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetAnonymizer() string {
+	if x != nil {
+		return x.Anonymizer
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetAnonymizerProvider() string {
+	if x != nil {
+		return x.AnonymizerProvider
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetProtoMismatch() bool {
+	if x != nil && x.ProtoMismatch != nil {
+		return *x.ProtoMismatch
+	}
+	return false
+}
+
+func (x *FingerprintResponse) GetProtoMismatchReason() string {
+	if x != nil {
+		return x.ProtoMismatchReason
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetShortId() string {
+	if x != nil {
+		return x.ShortId
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetCanonicalFingerprint() string {
+	if x != nil {
+		return x.CanonicalFingerprint
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetIpHeaderConflict() bool {
+	if x != nil && x.IpHeaderConflict != nil {
+		return *x.IpHeaderConflict
+	}
+	return false
+}
+
+func (x *FingerprintResponse) GetIpHeaderConflictReason() string {
+	if x != nil {
+		return x.IpHeaderConflictReason
+	}
+	return ""
+}
+
+func (x *FingerprintResponse) GetVerifiedGoodBot() string {
+	if x != nil {
+		return x.VerifiedGoodBot
+	}
+	return ""
+}
+
+type Client struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Browser        string                 `protobuf:"bytes,1,opt,name=browser,proto3" json:"browser,omitempty"`
+	BrowserVersion string                 `protobuf:"bytes,2,opt,name=browser_version,json=browserVersion,proto3" json:"browser_version,omitempty"`
+	Os             string                 `protobuf:"bytes,3,opt,name=os,proto3" json:"os,omitempty"`
+	OsVersion      string                 `protobuf:"bytes,4,opt,name=os_version,json=osVersion,proto3" json:"os_version,omitempty"`
+	Device         string                 `protobuf:"bytes,5,opt,name=device,proto3" json:"device,omitempty"`
+	Engine         string                 `protobuf:"bytes,6,opt,name=engine,proto3" json:"engine,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Client) Reset() {
+	*x = Client{}
+	mi := &file_fingerprint_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Client) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Client) ProtoMessage() {}
+
+func (x *Client) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Client.ProtoReflect.Descriptor instead.
+func (*Client) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Client) GetBrowser() string {
+	if x != nil {
+		return x.Browser
+	}
+	return ""
+}
+
+func (x *Client) GetBrowserVersion() string {
+	if x != nil {
+		return x.BrowserVersion
+	}
+	return ""
+}
+
+func (x *Client) GetOs() string {
+	if x != nil {
+		return x.Os
+	}
+	return ""
+}
+
+func (x *Client) GetOsVersion() string {
+	if x != nil {
+		return x.OsVersion
+	}
+	return ""
+}
+
+func (x *Client) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *Client) GetEngine() string {
+	if x != nil {
+		return x.Engine
+	}
+	return ""
+}
+
+type DeviceProfile struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Platform        string                 `protobuf:"bytes,1,opt,name=platform,proto3" json:"platform,omitempty"`
+	PlatformVersion string                 `protobuf:"bytes,2,opt,name=platform_version,json=platformVersion,proto3" json:"platform_version,omitempty"`
+	Architecture    string                 `protobuf:"bytes,3,opt,name=architecture,proto3" json:"architecture,omitempty"`
+	Model           string                 `protobuf:"bytes,4,opt,name=model,proto3" json:"model,omitempty"`
+	Bitness         string                 `protobuf:"bytes,5,opt,name=bitness,proto3" json:"bitness,omitempty"`
+	Mobile          *bool                  `protobuf:"varint,6,opt,name=mobile,proto3,oneof" json:"mobile,omitempty"`
+	DeviceMemory    float64                `protobuf:"fixed64,7,opt,name=device_memory,json=deviceMemory,proto3" json:"device_memory,omitempty"`
+	Dpr             float64                `protobuf:"fixed64,8,opt,name=dpr,proto3" json:"dpr,omitempty"`
+	ViewportWidth   int32                  `protobuf:"varint,9,opt,name=viewport_width,json=viewportWidth,proto3" json:"viewport_width,omitempty"`
+	ViewportHeight  int32                  `protobuf:"varint,10,opt,name=viewport_height,json=viewportHeight,proto3" json:"viewport_height,omitempty"`
+	Width           int32                  `protobuf:"varint,11,opt,name=width,proto3" json:"width,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *DeviceProfile) Reset() {
+	*x = DeviceProfile{}
+	mi := &file_fingerprint_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeviceProfile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeviceProfile) ProtoMessage() {}
+
+func (x *DeviceProfile) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceProfile.ProtoReflect.Descriptor instead.
+func (*DeviceProfile) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DeviceProfile) GetPlatform() string {
+	if x != nil {
+		return x.Platform
+	}
+	return ""
+}
+
+func (x *DeviceProfile) GetPlatformVersion() string {
+	if x != nil {
+		return x.PlatformVersion
+	}
+	return ""
+}
+
+func (x *DeviceProfile) GetArchitecture() string {
+	if x != nil {
+		return x.Architecture
+	}
+	return ""
+}
+
+func (x *DeviceProfile) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *DeviceProfile) GetBitness() string {
+	if x != nil {
+		return x.Bitness
+	}
+	return ""
+}
+
+func (x *DeviceProfile) GetMobile() bool {
+	if x != nil && x.Mobile != nil {
+		return *x.Mobile
+	}
+	return false
+}
+
+func (x *DeviceProfile) GetDeviceMemory() float64 {
+	if x != nil {
+		return x.DeviceMemory
+	}
+	return 0
+}
+
+func (x *DeviceProfile) GetDpr() float64 {
+	if x != nil {
+		return x.Dpr
+	}
+	return 0
+}
+
+func (x *DeviceProfile) GetViewportWidth() int32 {
+	if x != nil {
+		return x.ViewportWidth
+	}
+	return 0
+}
+
+func (x *DeviceProfile) GetViewportHeight() int32 {
+	if x != nil {
+		return x.ViewportHeight
+	}
+	return 0
+}
+
+func (x *DeviceProfile) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+type Brand struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Brand         string                 `protobuf:"bytes,1,opt,name=brand,proto3" json:"brand,omitempty"`
+	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Brand) Reset() {
+	*x = Brand{}
+	mi := &file_fingerprint_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Brand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Brand) ProtoMessage() {}
+
+func (x *Brand) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Brand.ProtoReflect.Descriptor instead.
+func (*Brand) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Brand) GetBrand() string {
+	if x != nil {
+		return x.Brand
+	}
+	return ""
+}
+
+func (x *Brand) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type Language struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tag           string                 `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
+	Q             float64                `protobuf:"fixed64,2,opt,name=q,proto3" json:"q,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Language) Reset() {
+	*x = Language{}
+	mi := &file_fingerprint_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Language) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Language) ProtoMessage() {}
+
+func (x *Language) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Language.ProtoReflect.Descriptor instead.
+func (*Language) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Language) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *Language) GetQ() float64 {
+	if x != nil {
+		return x.Q
+	}
+	return 0
+}
+
+type Preference struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Q             float64                `protobuf:"fixed64,2,opt,name=q,proto3" json:"q,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Preference) Reset() {
+	*x = Preference{}
+	mi := &file_fingerprint_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Preference) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Preference) ProtoMessage() {}
+
+func (x *Preference) ProtoReflect() protoreflect.Message {
+	mi := &file_fingerprint_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Preference.ProtoReflect.Descriptor instead.
+func (*Preference) Descriptor() ([]byte, []int) {
+	return file_fingerprint_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Preference) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *Preference) GetQ() float64 {
+	if x != nil {
+		return x.Q
+	}
+	return 0
+}
+
+var File_fingerprint_proto protoreflect.FileDescriptor
+
+const file_fingerprint_proto_rawDesc = "" +
+	"\n" +
+	"\x11fingerprint.proto\x12\x0efingerprint.v1\"\xe6\x02\n" +
+	"\x12FingerprintRequest\x12I\n" +
+	"\aheaders\x18\x01 \x03(\v2/.fingerprint.v1.FingerprintRequest.HeadersEntryR\aheaders\x12\x1f\n" +
+	"\vremote_addr\x18\x02 \x01(\tR\n" +
+	"remoteAddr\x12\x16\n" +
+	"\x06method\x18\x03 \x01(\tR\x06method\x12\x1a\n" +
+	"\bprotocol\x18\x04 \x01(\tR\bprotocol\x12\x12\n" +
+	"\x04host\x18\x05 \x01(\tR\x04host\x12\x12\n" +
+	"\x04path\x18\x06 \x01(\tR\x04path\x12)\n" +
+	"\x03tls\x18\a \x01(\v2\x17.fingerprint.v1.TLSInfoR\x03tls\x12!\n" +
+	"\fheader_order\x18\b \x03(\tR\vheaderOrder\x1a:\n" +
+	"\fHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"Z\n" +
+	"\aTLSInfo\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\x12!\n" +
+	"\fcipher_suite\x18\x02 \x01(\tR\vcipherSuite\x12\x12\n" +
+	"\x04alpn\x18\x03 \x01(\tR\x04alpn\"\xc1\x10\n" +
+	"\x13FingerprintResponse\x12 \n" +
+	"\vfingerprint\x18\x01 \x01(\tR\vfingerprint\x12-\n" +
+	"\x12stable_fingerprint\x18\x02 \x01(\tR\x11stableFingerprint\x12%\n" +
+	"\x0ehash_algorithm\x18\x03 \x01(\tR\rhashAlgorithm\x12\x0e\n" +
+	"\x02ip\x18\x04 \x01(\tR\x02ip\x12!\n" +
+	"\fcipher_suite\x18\x05 \x01(\tR\vcipherSuite\x12\x12\n" +
+	"\x04alpn\x18\x06 \x01(\tR\x04alpn\x12!\n" +
+	"\fheader_order\x18\a \x03(\tR\vheaderOrder\x12\x18\n" +
+	"\acountry\x18\b \x01(\tR\acountry\x12\x12\n" +
+	"\x04city\x18\t \x01(\tR\x04city\x12\x10\n" +
+	"\x03asn\x18\n" +
+	" \x01(\rR\x03asn\x12#\n" +
+	"\n" +
+	"datacenter\x18\v \x01(\bH\x00R\n" +
+	"datacenter\x88\x01\x01\x12/\n" +
+	"\x13datacenter_provider\x18\f \x01(\tR\x12datacenterProvider\x12\x1a\n" +
+	"\bhostname\x18\r \x01(\tR\bhostname\x12!\n" +
+	"\fverified_bot\x18\x0e \x01(\bR\vverifiedBot\x12\x1b\n" +
+	"\thit_count\x18\x0f \x01(\x03R\bhitCount\x12\x1d\n" +
+	"\n" +
+	"first_seen\x18\x10 \x01(\tR\tfirstSeen\x12\x1d\n" +
+	"\n" +
+	"cluster_id\x18\x11 \x01(\tR\tclusterId\x12.\n" +
+	"\x06client\x18\x12 \x01(\v2\x16.fingerprint.v1.ClientR\x06client\x12D\n" +
+	"\x0edevice_profile\x18\x13 \x01(\v2\x1d.fingerprint.v1.DeviceProfileR\rdeviceProfile\x12-\n" +
+	"\x06brands\x18\x14 \x03(\v2\x15.fingerprint.v1.BrandR\x06brands\x12\x1b\n" +
+	"\tbot_score\x18\x15 \x01(\x05R\bbotScore\x12\x1b\n" +
+	"\tbot_rules\x18\x16 \x03(\tR\bbotRules\x12%\n" +
+	"\x0elow_confidence\x18\x17 \x01(\bR\rlowConfidence\x12&\n" +
+	"\ftls_mismatch\x18\x18 \x01(\bH\x01R\vtlsMismatch\x88\x01\x01\x12.\n" +
+	"\x13tls_mismatch_reason\x18\x19 \x01(\tR\x11tlsMismatchReason\x12-\n" +
+	"\x12preferred_language\x18\x1a \x01(\tR\x11preferredLanguage\x126\n" +
+	"\tlanguages\x18\x1b \x03(\v2\x18.fingerprint.v1.LanguageR\tlanguages\x12;\n" +
+	"\vmedia_types\x18\x1c \x03(\v2\x1a.fingerprint.v1.PreferenceR\n" +
+	"mediaTypes\x128\n" +
+	"\tencodings\x18\x1d \x03(\v2\x1a.fingerprint.v1.PreferenceR\tencodings\x126\n" +
+	"\bcharsets\x18\x1e \x03(\v2\x1a.fingerprint.v1.PreferenceR\bcharsets\x12\x1c\n" +
+	"\ttimestamp\x18\x1f \x01(\tR\ttimestamp\x12-\n" +
+	"\x12tiered_fingerprint\x18  \x01(\tR\x11tieredFingerprint\x12\x1a\n" +
+	"\bprotocol\x18! \x01(\tR\bprotocol\x120\n" +
+	"\x11fingerprint_churn\x18\" \x01(\bH\x02R\x10fingerprintChurn\x88\x01\x01\x126\n" +
+	"\x17fingerprint_churn_count\x18# \x01(\x05R\x15fingerprintChurnCount\x12\x1f\n" +
+	"\vclient_tool\x18$ \x01(\tR\n" +
+	"clientTool\x124\n" +
+	"\x16client_tool_confidence\x18% \x01(\x01R\x14clientToolConfidence\x12+\n" +
+	"\x0fsec_fetch_valid\x18& \x01(\bH\x03R\rsecFetchValid\x88\x01\x01\x12(\n" +
+	"\x10sec_fetch_reason\x18' \x01(\tR\x0esecFetchReason\x12\x1e\n" +
+	"\n" +
+	"anonymizer\x18( \x01(\tR\n" +
+	"anonymizer\x12/\n" +
+	"\x13anonymizer_provider\x18) \x01(\tR\x12anonymizerProvider\x12*\n" +
+	"\x0eproto_mismatch\x18* \x01(\bH\x04R\rprotoMismatch\x88\x01\x01\x122\n" +
+	"\x15proto_mismatch_reason\x18+ \x01(\tR\x13protoMismatchReason\x12\x19\n" +
+	"\bshort_id\x18, \x01(\tR\ashortId\x123\n" +
+	"\x15canonical_fingerprint\x18- \x01(\tR\x14canonicalFingerprint\x12\x14\n" +
+	"\x05token\x18. \x01(\tR\x05token\x121\n" +
+	"\x12ip_header_conflict\x18/ \x01(\bH\x05R\x10ipHeaderConflict\x88\x01\x01\x129\n" +
+	"\x19ip_header_conflict_reason\x180 \x01(\tR\x16ipHeaderConflictReason\x12*\n" +
+	"\x11verified_good_bot\x181 \x01(\tR\x0fverifiedGoodBotB\r\n" +
+	"\v_datacenterB\x0f\n" +
+	"\r_tls_mismatchB\x14\n" +
+	"\x12_fingerprint_churnB\x12\n" +
+	"\x10_sec_fetch_validB\x11\n" +
+	"\x0f_proto_mismatchB\x15\n" +
+	"\x13_ip_header_conflict\"\xaa\x01\n" +
+	"\x06Client\x12\x18\n" +
+	"\abrowser\x18\x01 \x01(\tR\abrowser\x12'\n" +
+	"\x0fbrowser_version\x18\x02 \x01(\tR\x0ebrowserVersion\x12\x0e\n" +
+	"\x02os\x18\x03 \x01(\tR\x02os\x12\x1d\n" +
+	"\n" +
+	"os_version\x18\x04 \x01(\tR\tosVersion\x12\x16\n" +
+	"\x06device\x18\x05 \x01(\tR\x06device\x12\x16\n" +
+	"\x06engine\x18\x06 \x01(\tR\x06engine\"\xef\x02\n" +
+	"\rDeviceProfile\x12\x1a\n" +
+	"\bplatform\x18\x01 \x01(\tR\bplatform\x12)\n" +
+	"\x10platform_version\x18\x02 \x01(\tR\x0fplatformVersion\x12\"\n" +
+	"\farchitecture\x18\x03 \x01(\tR\farchitecture\x12\x14\n" +
+	"\x05model\x18\x04 \x01(\tR\x05model\x12\x18\n" +
+	"\abitness\x18\x05 \x01(\tR\abitness\x12\x1b\n" +
+	"\x06mobile\x18\x06 \x01(\bH\x00R\x06mobile\x88\x01\x01\x12#\n" +
+	"\rdevice_memory\x18\a \x01(\x01R\fdeviceMemory\x12\x10\n" +
+	"\x03dpr\x18\b \x01(\x01R\x03dpr\x12%\n" +
+	"\x0eviewport_width\x18\t \x01(\x05R\rviewportWidth\x12'\n" +
+	"\x0fviewport_height\x18\n" +
+	" \x01(\x05R\x0eviewportHeight\x12\x14\n" +
+	"\x05width\x18\v \x01(\x05R\x05widthB\t\n" +
+	"\a_mobile\"7\n" +
+	"\x05Brand\x12\x14\n" +
+	"\x05brand\x18\x01 \x01(\tR\x05brand\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\"*\n" +
+	"\bLanguage\x12\x10\n" +
+	"\x03tag\x18\x01 \x01(\tR\x03tag\x12\f\n" +
+	"\x01q\x18\x02 \x01(\x01R\x01q\"0\n" +
+	"\n" +
+	"Preference\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\tR\x05value\x12\f\n" +
+	"\x01q\x18\x02 \x01(\x01R\x01q2l\n" +
+	"\x12FingerprintService\x12V\n" +
+	"\vFingerprint\x12\".fingerprint.v1.FingerprintRequest\x1a#.fingerprint.v1.FingerprintResponseB#Z!browser-fingerprint/fingerprintpbb\x06proto3"
+
+var (
+	file_fingerprint_proto_rawDescOnce sync.Once
+	file_fingerprint_proto_rawDescData []byte
+)
+
+func file_fingerprint_proto_rawDescGZIP() []byte {
+	file_fingerprint_proto_rawDescOnce.Do(func() {
+		file_fingerprint_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_fingerprint_proto_rawDesc), len(file_fingerprint_proto_rawDesc)))
+	})
+	return file_fingerprint_proto_rawDescData
+}
+
+var file_fingerprint_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_fingerprint_proto_goTypes = []any{
+	(*FingerprintRequest)(nil),  // 0: fingerprint.v1.FingerprintRequest
+	(*TLSInfo)(nil),             // 1: fingerprint.v1.TLSInfo
+	(*FingerprintResponse)(nil), // 2: fingerprint.v1.FingerprintResponse
+	(*Client)(nil),              // 3: fingerprint.v1.Client
+	(*DeviceProfile)(nil),       // 4: fingerprint.v1.DeviceProfile
+	(*Brand)(nil),               // 5: fingerprint.v1.Brand
+	(*Language)(nil),            // 6: fingerprint.v1.Language
+	(*Preference)(nil),          // 7: fingerprint.v1.Preference
+	nil,                         // 8: fingerprint.v1.FingerprintRequest.HeadersEntry
+}
+var file_fingerprint_proto_depIdxs = []int32{
+	8,  // 0: fingerprint.v1.FingerprintRequest.headers:type_name -> fingerprint.v1.FingerprintRequest.HeadersEntry
+	1,  // 1: fingerprint.v1.FingerprintRequest.tls:type_name -> fingerprint.v1.TLSInfo
+	3,  // 2: fingerprint.v1.FingerprintResponse.client:type_name -> fingerprint.v1.Client
+	4,  // 3: fingerprint.v1.FingerprintResponse.device_profile:type_name -> fingerprint.v1.DeviceProfile
+	5,  // 4: fingerprint.v1.FingerprintResponse.brands:type_name -> fingerprint.v1.Brand
+	6,  // 5: fingerprint.v1.FingerprintResponse.languages:type_name -> fingerprint.v1.Language
+	7,  // 6: fingerprint.v1.FingerprintResponse.media_types:type_name -> fingerprint.v1.Preference
+	7,  // 7: fingerprint.v1.FingerprintResponse.encodings:type_name -> fingerprint.v1.Preference
+	7,  // 8: fingerprint.v1.FingerprintResponse.charsets:type_name -> fingerprint.v1.Preference
+	0,  // 9: fingerprint.v1.FingerprintService.Fingerprint:input_type -> fingerprint.v1.FingerprintRequest
+	2,  // 10: fingerprint.v1.FingerprintService.Fingerprint:output_type -> fingerprint.v1.FingerprintResponse
+	10, // [10:11] is the sub-list for method output_type
+	9,  // [9:10] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_fingerprint_proto_init() }
+func file_fingerprint_proto_init() {
+	if File_fingerprint_proto != nil {
+		return
+	}
+	file_fingerprint_proto_msgTypes[2].OneofWrappers = []any{}
+	file_fingerprint_proto_msgTypes[4].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_fingerprint_proto_rawDesc), len(file_fingerprint_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_fingerprint_proto_goTypes,
+		DependencyIndexes: file_fingerprint_proto_depIdxs,
+		MessageInfos:      file_fingerprint_proto_msgTypes,
+	}.Build()
+	File_fingerprint_proto = out.File
+	file_fingerprint_proto_goTypes = nil
+	file_fingerprint_proto_depIdxs = nil
+}
@@ -0,0 +1,131 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: fingerprint.proto
+
+package fingerprintpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	FingerprintService_Fingerprint_FullMethodName = "/fingerprint.v1.FingerprintService/Fingerprint"
+)
+
+// FingerprintServiceClient is the client API for FingerprintService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// FingerprintService fingerprints a request described by its attributes,
+// for callers that speak gRPC rather than HTTP.
+type FingerprintServiceClient interface {
+	// Fingerprint computes, records, and enriches the fingerprint of a
+	// request, as /fingerprint does.
+	Fingerprint(ctx context.Context, in *FingerprintRequest, opts ...grpc.CallOption) (*FingerprintResponse, error)
+}
+
+type fingerprintServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFingerprintServiceClient(cc grpc.ClientConnInterface) FingerprintServiceClient {
+	return &fingerprintServiceClient{cc}
+}
+
+func (c *fingerprintServiceClient) Fingerprint(ctx context.Context, in *FingerprintRequest, opts ...grpc.CallOption) (*FingerprintResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FingerprintResponse)
+	err := c.cc.Invoke(ctx, FingerprintService_Fingerprint_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FingerprintServiceServer is the server API for FingerprintService service.
+// All implementations must embed UnimplementedFingerprintServiceServer
+// for forward compatibility.
+//
+// FingerprintService fingerprints a request described by its attributes,
+// for callers that speak gRPC rather than HTTP.
+type FingerprintServiceServer interface {
+	// Fingerprint computes, records, and enriches the fingerprint of a
+	// request, as /fingerprint does.
+	Fingerprint(context.Context, *FingerprintRequest) (*FingerprintResponse, error)
+	mustEmbedUnimplementedFingerprintServiceServer()
+}
+
+// UnimplementedFingerprintServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedFingerprintServiceServer struct{}
+
+func (UnimplementedFingerprintServiceServer) Fingerprint(context.Context, *FingerprintRequest) (*FingerprintResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Fingerprint not implemented")
+}
+func (UnimplementedFingerprintServiceServer) mustEmbedUnimplementedFingerprintServiceServer() {}
+func (UnimplementedFingerprintServiceServer) testEmbeddedByValue()                            {}
+
+// UnsafeFingerprintServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FingerprintServiceServer will
+// result in compilation errors.
+type UnsafeFingerprintServiceServer interface {
+	mustEmbedUnimplementedFingerprintServiceServer()
+}
+
+func RegisterFingerprintServiceServer(s grpc.ServiceRegistrar, srv FingerprintServiceServer) {
+	// If the following call panics, it indicates UnimplementedFingerprintServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&FingerprintService_ServiceDesc, srv)
+}
+
+func _FingerprintService_Fingerprint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FingerprintRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FingerprintServiceServer).Fingerprint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FingerprintService_Fingerprint_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FingerprintServiceServer).Fingerprint(ctx, req.(*FingerprintRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// FingerprintService_ServiceDesc is the grpc.ServiceDesc for FingerprintService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var FingerprintService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "fingerprint.v1.FingerprintService",
+	HandlerType: (*FingerprintServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Fingerprint",
+			Handler:    _FingerprintService_Fingerprint_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "fingerprint.proto",
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+)
+
+// fingerprintList is a set of fingerprints loaded from a file, used either
+// as a denylist, blocking the listed fingerprints, or as an allowlist,
+// blocking everything else. It can be reloaded while requests are served.
+type fingerprintList struct {
+	path  string
+	allow bool
+
+	entries atomic.Pointer[map[string]bool]
+}
+
+func loadFingerprintList(path string, allow bool) (*fingerprintList, error) {
+	l := &fingerprintList{path: path, allow: allow}
+	if err := l.reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// reload replaces the list with the current contents of its file. The old
+// list stays in effect if the file cannot be read. Each non-blank line
+// that is not a # comment is one fingerprint, full or stable.
+func (l *fingerprintList) reload() error {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	entries := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries[strings.ToLower(line)] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read %s: %w", l.path, err)
+	}
+	l.entries.Store(&entries)
+	return nil
+}
+
+// mode names the list for logs.
+func (l *fingerprintList) mode() string {
+	if l.allow {
+		return "allowlist"
+	}
+	return "denylist"
+}
+
+func (l *fingerprintList) size() int {
+	return len(*l.entries.Load())
+}
+
+// blocks reports whether a request with the given fingerprints is
+// blocked. A request is listed if any of them is on the list.
+func (l *fingerprintList) blocks(fingerprints ...string) bool {
+	entries := *l.entries.Load()
+	listed := false
+	for _, fp := range fingerprints {
+		if entries[strings.ToLower(fp)] {
+			listed = true
+			break
+		}
+	}
+	return listed != l.allow
+}
+
+// reloadOnSIGHUP reloads the list each time the process receives SIGHUP.
+func (l *fingerprintList) reloadOnSIGHUP() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := l.reload(); err != nil {
+				slog.Error("failed to reload fingerprint "+l.mode()+", keeping the previous one",
+					"path", l.path, "error", err)
+				continue
+			}
+			slog.Info("reloaded fingerprint "+l.mode(), "path", l.path, "count", l.size())
+		}
+	}()
+}
+
+// enforceList rejects requests whose full or stable fingerprint is blocked
+// by the configured list with s.blockStatus, before next runs, unless the
+// request's route policy turns enforcement off or the client is one of
+// the -good-bots. It is a no-op when no policy enforces a list.
+func (s *server) enforceList(next http.HandlerFunc) http.HandlerFunc {
+	if !s.routes.enforcesList() {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		policy := s.policy(r)
+		if !policy.enforceList {
+			next(w, r)
+			return
+		}
+		data, hash, stable := s.peekFingerprint(r)
+		if s.list.blocks(hash, stable) {
+			if s.goodBotBypass {
+				if bot := s.goodBot(r.Context(), data.IPAddress, s.now()); bot != "" {
+					slog.Info("good bot bypassed fingerprint "+s.list.mode(),
+						"fingerprint", hash,
+						"bot", bot,
+						"route", policy.name,
+						"ip", s.redactor.IP(data.IPAddress),
+						"path", r.URL.Path)
+					next(w, r)
+					return
+				}
+			}
+			slog.Warn("blocked fingerprint",
+				"fingerprint", hash,
+				"stable_fingerprint", stable,
+				"list", s.list.mode(),
+				"route", policy.name,
+				"ip", s.redactor.IP(data.IPAddress),
+				"user_agent", s.redactor.UserAgent(data.UserAgent),
+				"path", r.URL.Path)
+			writeJSON(w, s.blockStatus, errorResponse{Error: "fingerprint blocked"})
+			return
+		}
+		next(w, r)
+	}
+}
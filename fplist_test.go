@@ -0,0 +1,170 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// writeList writes contents to a fingerprint list file in a temporary
+// directory and returns its path.
+func writeList(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "list.txt")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFingerprintListBlocks(t *testing.T) {
+	const contents = "# blocked clients\nv2:AAAA\n\n  v2:bbbb  \n"
+	tests := []struct {
+		name         string
+		allow        bool
+		fingerprints []string
+		blocked      bool
+	}{
+		{name: "denylist listed", fingerprints: []string{"v2:aaaa"}, blocked: true},
+		{name: "denylist listed stable", fingerprints: []string{"v2:cccc", "v2:bbbb"}, blocked: true},
+		{name: "denylist unlisted", fingerprints: []string{"v2:cccc"}},
+		{name: "denylist comment", fingerprints: []string{"# blocked clients"}},
+		{name: "allowlist listed", allow: true, fingerprints: []string{"v2:cccc", "v2:AAAA"}},
+		{name: "allowlist unlisted", allow: true, fingerprints: []string{"v2:cccc"}, blocked: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := loadFingerprintList(writeList(t, contents), tt.allow)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := l.blocks(tt.fingerprints...); got != tt.blocked {
+				t.Errorf("blocks(%q) = %v, want %v", tt.fingerprints, got, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestFingerprintListReload(t *testing.T) {
+	path := writeList(t, "v2:aaaa\n")
+	l, err := loadFingerprintList(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("v2:bbbb\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if l.blocks("v2:aaaa") || !l.blocks("v2:bbbb") {
+		t.Error("reload did not replace the list")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.reload(); err == nil {
+		t.Error("reload of a missing file succeeded")
+	}
+	if !l.blocks("v2:bbbb") {
+		t.Error("failed reload dropped the previous list")
+	}
+}
+
+func TestEnforceList(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	request := func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/fingerprint", nil)
+		r.Header.Set("User-Agent", "curl/8.5.0")
+		return r
+	}
+	_, hash, stable := newTestServer(t).peekFingerprint(request())
+
+	tests := []struct {
+		name     string
+		contents string
+		allow    bool
+		enforce  bool
+		want     int
+	}{
+		{name: "denylisted", contents: hash, enforce: true, want: http.StatusTeapot},
+		{name: "denylisted stable", contents: stable, enforce: true, want: http.StatusTeapot},
+		{name: "not denylisted", contents: "v2:aaaa", enforce: true, want: http.StatusOK},
+		{name: "allowlisted", contents: hash, allow: true, enforce: true, want: http.StatusOK},
+		{name: "not allowlisted", contents: "v2:aaaa", allow: true, enforce: true, want: http.StatusTeapot},
+		{name: "route does not enforce", contents: hash, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			l, err := loadFingerprintList(writeList(t, tt.contents), tt.allow)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.list, s.blockStatus = l, http.StatusTeapot
+			s.routes = &routeTable{
+				routes:   []route{{prefix: "/other", policy: &routePolicy{name: "other", config: s.config, enforceList: true}}},
+				fallback: &routePolicy{name: "default", config: s.config, enforceList: tt.enforce},
+			}
+			w := httptest.NewRecorder()
+			s.enforceList(ok)(w, request())
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+// TestFingerprintListReloadConcurrent reloads the list while requests are
+// checked against it, as SIGHUP does. Each version of the file lists one
+// fingerprint, so a check that saw a list still being built would find it
+// empty. Run it with -race.
+func TestFingerprintListReloadConcurrent(t *testing.T) {
+	path := writeList(t, "v2:aaaa\n")
+	l, err := loadFingerprintList(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		defer close(done)
+		for i := range 50 {
+			contents := "v2:aaaa\n"
+			if i%2 == 0 {
+				contents = "v2:bbbb\n"
+			}
+			if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := l.reload(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	})
+	for range 4 {
+		wg.Go(func() {
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				entries := *l.entries.Load()
+				if len(entries) != 1 {
+					t.Errorf("list has %d entries, want 1", len(entries))
+					return
+				}
+				l.blocks("v2:aaaa", "v2:cccc")
+			}
+		})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+// geoRecord decodes the fields used for enrichment. City and Country
+// databases fill the location fields, ASN databases fill the AS number.
+type geoRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	ASN uint `maxminddb:"autonomous_system_number"`
+}
+
+// geoInfo is the GeoIP enrichment for a client IP. It is informational
+// only and never feeds the fingerprint hash.
+type geoInfo struct {
+	Country string
+	City    string
+	ASN     uint
+}
+
+// geoIP looks up client IPs in one or more MaxMind DB files, merging the
+// results so City/Country and ASN databases can be combined.
+type geoIP struct {
+	readers []*maxminddb.Reader
+}
+
+func openGeoIP(paths []string) (*geoIP, error) {
+	g := &geoIP{}
+	for _, path := range paths {
+		reader, err := maxminddb.Open(path)
+		if err != nil {
+			g.Close()
+			return nil, fmt.Errorf("open GeoIP database %s: %w", path, err)
+		}
+		g.readers = append(g.readers, reader)
+	}
+	return g, nil
+}
+
+// Lookup returns the enrichment for ip. Unparseable, private, and other
+// non-routable addresses return an empty result.
+func (g *geoIP) Lookup(ip string) geoInfo {
+	var info geoInfo
+	if g == nil {
+		return info
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return info
+	}
+
+	for _, reader := range g.readers {
+		var record geoRecord
+		if err := reader.Lookup(addr).Decode(&record); err != nil {
+			continue
+		}
+		if record.Country.ISOCode != "" {
+			info.Country = record.Country.ISOCode
+		}
+		if name := record.City.Names["en"]; name != "" {
+			info.City = name
+		}
+		if record.ASN != 0 {
+			info.ASN = record.ASN
+		}
+	}
+	return info
+}
+
+// Country returns only the country of ip, which is cheaper than Lookup.
+func (g *geoIP) Country(ip string) string {
+	if g == nil {
+		return ""
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return ""
+	}
+
+	var country string
+	for _, reader := range g.readers {
+		var code string
+		if err := reader.Lookup(addr).DecodePath(&code, "country", "iso_code"); err == nil && code != "" {
+			country = code
+		}
+	}
+	return country
+}
+
+func (g *geoIP) Close() {
+	for _, reader := range g.readers {
+		reader.Close()
+	}
+}
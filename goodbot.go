@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// goodBots is the set of known crawlers, by lower-cased name, whose
+// verified requests are marked verified_good_bot. Crawlers are only
+// recognized by forward-confirmed reverse DNS, never by the User-Agent
+// alone, which anyone can send. A nil goodBots matches nothing.
+type goodBots map[string]bool
+
+// parseGoodBots parses the -good-bots list: comma-separated crawler names
+// such as Googlebot, or "all" for every known crawler.
+func parseGoodBots(list string) (goodBots, error) {
+	known := make(map[string]bool, len(crawlers))
+	for _, c := range crawlers {
+		known[strings.ToLower(c.name)] = true
+	}
+	if strings.TrimSpace(list) == "all" {
+		return known, nil
+	}
+
+	bots := make(goodBots)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			return nil, fmt.Errorf("unknown crawler %q", name)
+		}
+		bots[name] = true
+	}
+	if len(bots) == 0 {
+		return nil, fmt.Errorf("no crawlers listed")
+	}
+	return bots, nil
+}
+
+// match returns the name of the crawler r verifies, if it is a good bot,
+// or "".
+func (b goodBots) match(r rdnsResult) string {
+	name := r.Crawler()
+	if !b[strings.ToLower(name)] {
+		return ""
+	}
+	return name
+}
+
+// goodBot returns the name of the good bot ip verifiably belongs to, or ""
+// when it is none or good bots are not configured. It costs a reverse DNS
+// lookup unless the result is cached.
+func (s *server) goodBot(ctx context.Context, ip string, now time.Time) string {
+	if s.goodBots == nil || s.rdns == nil {
+		return ""
+	}
+	return s.goodBots.match(s.rdns.Lookup(ctx, ip, now))
+}
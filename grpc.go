@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"browser-fingerprint/fingerprint"
+	"browser-fingerprint/fingerprintpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcService serves the Fingerprint RPC. Each call is turned into the
+// http.Request it describes and run through the same rate limiting, block
+// list, and signal checks as /fingerprint, so both transports fingerprint,
+// record, and enrich a request identically.
+type grpcService struct {
+	fingerprintpb.UnimplementedFingerprintServiceServer
+	s *server
+}
+
+// newGRPCServer returns a gRPC server exposing s as FingerprintService.
+func newGRPCServer(s *server) *grpc.Server {
+	srv := grpc.NewServer()
+	fingerprintpb.RegisterFingerprintServiceServer(srv, &grpcService{s: s})
+	return srv
+}
+
+func (g *grpcService) Fingerprint(ctx context.Context, req *fingerprintpb.FingerprintRequest) (*fingerprintpb.FingerprintResponse, error) {
+	r, err := grpcHTTPRequest(ctx, req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	var resp *fingerprintResponse
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		fp := g.s.fingerprint(w, r)
+		resp = &fp
+	}
+	rec := &grpcResponseWriter{header: make(http.Header)}
+	g.s.rateLimit(g.s.enforceList(g.s.requireSignals(g.s.metrics.instrument(handler))))(rec, r)
+	if resp == nil {
+		if retry := rec.header.Get("Retry-After"); retry != "" {
+			grpc.SetHeader(ctx, metadata.Pairs("retry-after", retry))
+		}
+		return nil, rec.err()
+	}
+	return resp.proto(), nil
+}
+
+// grpcHTTPRequest builds the request described by req.
+func grpcHTTPRequest(ctx context.Context, req *fingerprintpb.FingerprintRequest) (*http.Request, error) {
+	method := cmp.Or(req.GetMethod(), http.MethodGet)
+	proto := cmp.Or(req.GetProtocol(), "HTTP/1.1")
+	major, minor, ok := http.ParseHTTPVersion(proto)
+	if !ok {
+		return nil, fmt.Errorf("invalid protocol %q", proto)
+	}
+	target := cmp.Or(req.GetPath(), "/")
+	u, err := url.ParseRequestURI(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %q", target)
+	}
+
+	// Names that differ only in case are joined in sorted order so the
+	// result does not depend on map iteration
+	header := make(http.Header, len(req.GetHeaders()))
+	for _, name := range slices.Sorted(maps.Keys(req.GetHeaders())) {
+		value := req.GetHeaders()[name]
+		if existing := header.Get(name); existing != "" {
+			value = existing + ", " + value
+		}
+		header.Set(name, value)
+	}
+	host := req.GetHost()
+	if host == "" {
+		host = header.Get("Host")
+	}
+	header.Del("Host")
+
+	r := &http.Request{
+		Method:     method,
+		URL:        u,
+		Proto:      proto,
+		ProtoMajor: major,
+		ProtoMinor: minor,
+		Header:     header,
+		Host:       host,
+		RemoteAddr: req.GetRemoteAddr(),
+		RequestURI: target,
+	}
+	if info := req.GetTls(); info != nil {
+		if r.TLS, err = grpcTLSState(info); err != nil {
+			return nil, err
+		}
+	}
+	if order := req.GetHeaderOrder(); len(order) > 0 {
+		ctx = fingerprint.WithHeaderOrder(ctx, order)
+	}
+	return r.WithContext(ctx), nil
+}
+
+// grpcTLSState builds the connection state described by info.
+func grpcTLSState(info *fingerprintpb.TLSInfo) (*tls.ConnectionState, error) {
+	state := &tls.ConnectionState{HandshakeComplete: true, NegotiatedProtocol: info.GetAlpn()}
+	switch info.GetVersion() {
+	case "TLS1.0":
+		state.Version = tls.VersionTLS10
+	case "TLS1.1":
+		state.Version = tls.VersionTLS11
+	case "TLS1.2":
+		state.Version = tls.VersionTLS12
+	case "TLS1.3", "":
+		state.Version = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("invalid TLS version %q: must be TLS1.0, TLS1.1, TLS1.2, or TLS1.3", info.GetVersion())
+	}
+	if name := info.GetCipherSuite(); name != "" {
+		suites := append(tls.CipherSuites(), tls.InsecureCipherSuites()...)
+		i := slices.IndexFunc(suites, func(suite *tls.CipherSuite) bool { return suite.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+		state.CipherSuite = suites[i].ID
+	}
+	return state, nil
+}
+
+// grpcResponseWriter captures what the HTTP middleware writes when it
+// rejects a request, so it can be returned as a gRPC status.
+type grpcResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *grpcResponseWriter) Header() http.Header { return w.header }
+
+func (w *grpcResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *grpcResponseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(p)
+}
+
+// err converts the captured rejection into a gRPC status error carrying
+// its error message.
+func (w *grpcResponseWriter) err() error {
+	var body errorResponse
+	if json.Unmarshal(w.body.Bytes(), &body) != nil || body.Error == "" {
+		body.Error = strings.ToLower(http.StatusText(w.status))
+	}
+
+	code := codes.Internal
+	switch {
+	case w.status == http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case w.status == http.StatusBadRequest, w.status == http.StatusUnprocessableEntity:
+		code = codes.InvalidArgument
+	case w.status >= 400 && w.status < 500:
+		// Blocked fingerprints, whatever -block-status is
+		code = codes.PermissionDenied
+	}
+	return status.Error(code, body.Error)
+}
+
+// proto converts resp to its gRPC form. The debug-only fields and the
+// visitor ID, which needs a cookie, have no equivalent.
+func (resp fingerprintResponse) proto() *fingerprintpb.FingerprintResponse {
+	out := &fingerprintpb.FingerprintResponse{
+		Fingerprint:            resp.Fingerprint,
+		StableFingerprint:      resp.StableFingerprint,
+		TieredFingerprint:      resp.TieredFingerprint,
+		ShortId:                resp.ShortID,
+		Token:                  resp.Token,
+		HashAlgorithm:          resp.HashAlgorithm,
+		Ip:                     resp.IP,
+		Protocol:               resp.Protocol,
+		CipherSuite:            resp.CipherSuite,
+		Alpn:                   resp.ALPN,
+		HeaderOrder:            resp.HeaderOrder,
+		Country:                resp.Country,
+		City:                   resp.City,
+		Asn:                    uint32(resp.ASN),
+		Datacenter:             resp.Datacenter,
+		DatacenterProvider:     resp.DatacenterName,
+		Anonymizer:             resp.Anonymizer,
+		AnonymizerProvider:     resp.AnonymizerProvider,
+		Hostname:               resp.Hostname,
+		VerifiedBot:            resp.VerifiedBot,
+		VerifiedGoodBot:        resp.VerifiedGoodBot,
+		HitCount:               resp.HitCount,
+		FirstSeen:              resp.FirstSeen,
+		ClusterId:              resp.ClusterID,
+		CanonicalFingerprint:   resp.CanonicalFingerprint,
+		FingerprintChurn:       resp.FingerprintChurn,
+		FingerprintChurnCount:  int32(resp.ChurnCount),
+		BotScore:               int32(resp.BotScore),
+		BotRules:               resp.BotRules,
+		ClientTool:             resp.ClientTool,
+		ClientToolConfidence:   resp.ClientToolConfidence,
+		LowConfidence:          resp.LowConfidence,
+		TlsMismatch:            resp.TLSMismatch,
+		TlsMismatchReason:      resp.TLSMismatchReason,
+		SecFetchValid:          resp.SecFetchValid,
+		SecFetchReason:         resp.SecFetchReason,
+		ProtoMismatch:          resp.ProtoMismatch,
+		ProtoMismatchReason:    resp.ProtoMismatchReason,
+		IpHeaderConflict:       resp.IPHeaderConflict,
+		IpHeaderConflictReason: resp.IPHeaderConflictReason,
+		PreferredLanguage:      resp.PreferredLanguage,
+		MediaTypes:             protoPreferences(resp.MediaTypes),
+		Encodings:              protoPreferences(resp.Encodings),
+		Charsets:               protoPreferences(resp.Charsets),
+		Timestamp:              resp.Timestamp,
+	}
+	if c := resp.Client; c != nil {
+		out.Client = &fingerprintpb.Client{
+			Browser:        c.Browser,
+			BrowserVersion: c.BrowserVersion,
+			Os:             c.OS,
+			OsVersion:      c.OSVersion,
+			Device:         c.Device,
+			Engine:         c.Engine,
+		}
+	}
+	if p := resp.DeviceProfile; p != nil {
+		out.DeviceProfile = &fingerprintpb.DeviceProfile{
+			Platform:        p.Platform,
+			PlatformVersion: p.PlatformVersion,
+			Architecture:    p.Architecture,
+			Model:           p.Model,
+			Bitness:         p.Bitness,
+			Mobile:          p.Mobile,
+			DeviceMemory:    p.DeviceMemory,
+			Dpr:             p.DPR,
+			ViewportWidth:   int32(p.ViewportWidth),
+			ViewportHeight:  int32(p.ViewportHeight),
+			Width:           int32(p.Width),
+		}
+	}
+	for _, b := range resp.Brands {
+		out.Brands = append(out.Brands, &fingerprintpb.Brand{Brand: b.Brand, Version: b.Version})
+	}
+	for _, lang := range resp.Languages {
+		out.Languages = append(out.Languages, &fingerprintpb.Language{Tag: lang.Tag, Q: lang.Quality})
+	}
+	return out
+}
+
+func protoPreferences(prefs []fingerprint.Preference) []*fingerprintpb.Preference {
+	var out []*fingerprintpb.Preference
+	for _, pref := range prefs {
+		out = append(out, &fingerprintpb.Preference{Value: pref.Value, Q: pref.Quality})
+	}
+	return out
+}
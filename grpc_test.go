@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"browser-fingerprint/fingerprint"
+	"browser-fingerprint/fingerprintpb"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dialGRPC serves s over an in-memory connection and returns a client
+// for it.
+func dialGRPC(t *testing.T, s *server) fingerprintpb.FingerprintServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := newGRPCServer(s)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return fingerprintpb.NewFingerprintServiceClient(conn)
+}
+
+// grpcRequest describes r as a FingerprintRequest.
+func grpcRequest(r *http.Request) *fingerprintpb.FingerprintRequest {
+	req := &fingerprintpb.FingerprintRequest{
+		Headers:     make(map[string]string, len(r.Header)),
+		RemoteAddr:  r.RemoteAddr,
+		Method:      r.Method,
+		Protocol:    r.Proto,
+		Host:        r.Host,
+		Path:        r.URL.RequestURI(),
+		HeaderOrder: fingerprint.HeaderOrderFromContext(r.Context()),
+	}
+	for name, values := range r.Header {
+		req.Headers[name] = strings.Join(values, ", ")
+	}
+	if r.TLS != nil {
+		req.Tls = &fingerprintpb.TLSInfo{
+			Version:     strings.ReplaceAll(tls.VersionName(r.TLS.Version), " ", ""),
+			CipherSuite: tls.CipherSuiteName(r.TLS.CipherSuite),
+			Alpn:        r.TLS.NegotiatedProtocol,
+		}
+	}
+	return req
+}
+
+// TestGRPCFingerprint checks that the RPC fingerprints each self-test
+// fixture as /fingerprint does.
+func TestGRPCFingerprint(t *testing.T) {
+	s := newTestServer(t)
+	s.store = newMemoryStore()
+	client := dialGRPC(t, s)
+
+	for _, fixture := range fingerprint.SelfTestFixtures() {
+		t.Run(fixture.Name, func(t *testing.T) {
+			for hits := range int64(2) {
+				resp, err := client.Fingerprint(t.Context(), grpcRequest(fixture.Request()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp.GetFingerprint() != fixture.Fingerprint || resp.GetStableFingerprint() != fixture.StableFingerprint {
+					t.Errorf("fingerprints = %s, %s; want %s, %s", resp.GetFingerprint(), resp.GetStableFingerprint(),
+						fixture.Fingerprint, fixture.StableFingerprint)
+				}
+				if resp.GetHitCount() != hits+1 {
+					t.Errorf("hit_count = %d, want %d", resp.GetHitCount(), hits+1)
+				}
+			}
+		})
+	}
+}
+
+func TestGRPCErrors(t *testing.T) {
+	valid := &fingerprintpb.FingerprintRequest{
+		Headers:    map[string]string{"User-Agent": "curl/8.5.0", "Accept": "*/*"},
+		RemoteAddr: "192.0.2.1:5000",
+	}
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, s *server)
+		req   *fingerprintpb.FingerprintRequest
+		code  codes.Code
+	}{
+		{
+			name: "invalid protocol",
+			req:  &fingerprintpb.FingerprintRequest{Protocol: "HTTP/x"},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "invalid TLS version",
+			req:  &fingerprintpb.FingerprintRequest{Tls: &fingerprintpb.TLSInfo{Version: "SSL3"}},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "rate limited",
+			setup: func(t *testing.T, s *server) {
+				s.routes.fallback.limiter = newRateLimiter(0.001, 1)
+				s.metrics = newMetrics(prometheus.NewRegistry(), true, nil, nil, 1, false)
+			},
+			req:  valid,
+			code: codes.ResourceExhausted,
+		},
+		{
+			name: "blocked",
+			setup: func(t *testing.T, s *server) {
+				r, err := grpcHTTPRequest(t.Context(), valid)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, hash, _ := s.peekFingerprint(r)
+				l, err := loadFingerprintList(writeList(t, hash), false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				s.list, s.blockStatus = l, http.StatusForbidden
+				s.routes.fallback.enforceList = true
+			},
+			req:  valid,
+			code: codes.PermissionDenied,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			if tt.setup != nil {
+				tt.setup(t, s)
+			}
+			client := dialGRPC(t, s)
+
+			// A rate limited client is only refused its second request
+			var header metadata.MD
+			var err error
+			for range 2 {
+				_, err = client.Fingerprint(t.Context(), tt.req, grpc.Header(&header))
+			}
+			if got := status.Code(err); got != tt.code {
+				t.Fatalf("code = %v (%v), want %v", got, err, tt.code)
+			}
+			if tt.code == codes.ResourceExhausted && len(header.Get("retry-after")) == 0 {
+				t.Error("no retry-after metadata")
+			}
+		})
+	}
+}
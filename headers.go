@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/textproto"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// headersConfig is the -headers-config file format. YAML is a superset of
+// JSON, so the file may be written in either.
+//
+//	mode: merge        # "merge" (default) adds to the built-in list, "replace" uses only these
+//	headers: [Cookie]
+//	exclude: [Authorization]
+type headersConfig struct {
+	Mode    string   `yaml:"mode"`
+	Headers []string `yaml:"headers"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// loadHeadersConfig reads the header config at path and returns the
+// resulting list of fingerprint headers.
+func loadHeadersConfig(path string) ([]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read headers config: %w", err)
+	}
+
+	var cfg headersConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(raw))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("parse headers config %s: %w", path, err)
+	}
+
+	var base []string
+	switch cfg.Mode {
+	case "", "merge":
+		base = fingerprint.DefaultHeaders()
+	case "replace":
+	default:
+		return nil, fmt.Errorf("headers config %s: unknown mode %q (want merge or replace)", path, cfg.Mode)
+	}
+
+	excluded := make(map[string]bool)
+	for _, name := range cfg.Exclude {
+		if !validHeaderName(name) {
+			return nil, fmt.Errorf("headers config %s: invalid header name %q in exclude", path, name)
+		}
+		excluded[textproto.CanonicalMIMEHeaderKey(name)] = true
+	}
+	for _, name := range cfg.Headers {
+		if !validHeaderName(name) {
+			return nil, fmt.Errorf("headers config %s: invalid header name %q", path, name)
+		}
+	}
+
+	var headers []string
+	seen := make(map[string]bool)
+	for _, name := range append(base, cfg.Headers...) {
+		name = textproto.CanonicalMIMEHeaderKey(name)
+		if seen[name] || excluded[name] {
+			continue
+		}
+		seen[name] = true
+		headers = append(headers, name)
+	}
+
+	if len(headers) == 0 {
+		return nil, fmt.Errorf("headers config %s: no headers left to fingerprint", path)
+	}
+	return headers, nil
+}
+
+// parseHeaderList parses a comma-separated list of header names, such as
+// -exclude-headers, into lower-cased names.
+func parseHeaderList(list string) ([]string, error) {
+	var names []string
+	for name := range strings.SplitSeq(list, ",") {
+		name = strings.TrimSpace(name)
+		if !validHeaderName(name) {
+			return nil, fmt.Errorf("invalid header name %q", name)
+		}
+		names = append(names, strings.ToLower(name))
+	}
+	return names, nil
+}
+
+// validHeaderName reports whether name is a valid HTTP field name token
+// (RFC 9110, Section 5.1).
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// altSvcMaxAge is how long clients may remember the HTTP/3 endpoint
+// advertised in Alt-Svc.
+const altSvcMaxAge = 24 * time.Hour
+
+// newHTTP3Server returns an HTTP/3 server for main's handler. tlsConfig
+// must be a copy of main's TLS config taken before the ClientHello capture
+// is installed, since QUIC handshakes have no net.Conn to key hellos by,
+// so HTTP/3 requests are never JA3 or JA4 fingerprinted. The certificate
+// files are loaded into it when set.
+func newHTTP3Server(main *http.Server, tlsConfig *tls.Config, certFile, keyFile string) (*http3.Server, error) {
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = append(tlsConfig.Certificates, cert)
+	}
+	return &http3.Server{
+		Handler:        main.Handler,
+		TLSConfig:      http3.ConfigureTLSConfig(tlsConfig),
+		IdleTimeout:    main.IdleTimeout,
+		MaxHeaderBytes: main.MaxHeaderBytes,
+	}, nil
+}
+
+// listenUDP opens the UDP socket HTTP/3 is served on, at the host of
+// addr and the port the TCP listener got.
+func listenUDP(addr string, tcp net.Addr) (net.PacketConn, error) {
+	host, _, _ := net.SplitHostPort(addr)
+	_, port, _ := net.SplitHostPort(tcp.String())
+	udpAddr := net.JoinHostPort(host, port)
+	conn, err := net.ListenPacket("udp", udpAddr)
+	if err != nil {
+		return nil, fail("cannot listen for HTTP/3", "addr", udpAddr, "error", err)
+	}
+	return conn, nil
+}
+
+// advertiseHTTP3 sets an Alt-Svc header on every response next serves
+// over TCP, telling clients that HTTP/3 is available on port. Browsers
+// use it for later connections, so a first visit is never HTTP/3.
+func advertiseHTTP3(next http.Handler, port int) http.Handler {
+	altSvc := fmt.Sprintf(`h3=":%d"; ma=%d`, port, int(altSvcMaxAge.Seconds()))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor < 3 {
+			w.Header().Set("Alt-Svc", altSvc)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
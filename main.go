@@ -1,260 +1,1591 @@
 package main
 
 import (
-	"crypto/sha256"
+	"context"
+	"crypto/rand"
 	"crypto/tls"
-	"encoding/hex"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"math"
 	"net"
 	"net/http"
-	"sort"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
+
+	"browser-fingerprint/fingerprint"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/quic-go/quic-go/http3"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+	"google.golang.org/grpc"
 )
 
-type FingerprintData struct {
-	IPAddress     string
-	UserAgent     string
-	AcceptLang    string
-	AcceptEnc     string
-	Accept        string
-	Headers       map[string]string
-	RemoteAddr    string
-	XForwardedFor string
-	XRealIP       string
-	Method        string
-	Protocol      string
-	TLSVersion    string
-	Port          string
+type fingerprintResponse struct {
+	Fingerprint       string   `json:"fingerprint"`
+	StableFingerprint string   `json:"stable_fingerprint"`
+	TieredFingerprint string   `json:"tiered_fingerprint"`
+	ShortID           string   `json:"short_id,omitempty"`
+	Token             string   `json:"token,omitempty"`
+	HashAlgorithm     string   `json:"hash_algorithm"`
+	IP                string   `json:"ip"`
+	Protocol          string   `json:"protocol"`
+	CipherSuite       string   `json:"cipher_suite,omitempty"`
+	ALPN              string   `json:"alpn,omitempty"`
+	ClientCert        string   `json:"client_cert_thumbprint,omitempty"`
+	ClientCertSubject string   `json:"client_cert_subject,omitempty"`
+	ClientCertIssuer  string   `json:"client_cert_issuer,omitempty"`
+	JA3               string   `json:"ja3,omitempty"`
+	JA4               string   `json:"ja4,omitempty"`
+	H2Fingerprint     string   `json:"h2_fingerprint,omitempty"`
+	HeaderOrder       []string `json:"header_order,omitempty"`
+	Country           string   `json:"country,omitempty"`
+	City              string   `json:"city,omitempty"`
+	ASN               uint     `json:"asn,omitempty"`
+	Datacenter        *bool    `json:"datacenter,omitempty"`
+	DatacenterName    string   `json:"datacenter_provider,omitempty"`
+	// Anonymizer is "tor" or "vpn" when the client IP is a Tor exit or in
+	// a -vpn-ranges range
+	Anonymizer         string `json:"anonymizer,omitempty"`
+	AnonymizerProvider string `json:"anonymizer_provider,omitempty"`
+	Hostname           string `json:"hostname,omitempty"`
+	VerifiedBot        bool   `json:"verified_bot,omitempty"`
+	VerifiedGoodBot    string `json:"verified_good_bot,omitempty"`
+	HitCount           int64  `json:"hit_count,omitempty"`
+	FirstSeen          string `json:"first_seen,omitempty"`
+	VisitorID          string `json:"visitor_id,omitempty"`
+	ClusterID          string `json:"cluster_id,omitempty"`
+	// CanonicalFingerprint is set by -stability-distance: the fingerprint
+	// this one is persisted under
+	CanonicalFingerprint string `json:"canonical_fingerprint,omitempty"`
+
+	// Sampled is set when -sample-rate is below 1
+	Sampled *bool `json:"sampled,omitempty"`
+
+	// FingerprintChurn is set when churn tracking knows the client's key
+	FingerprintChurn *bool `json:"fingerprint_churn,omitempty"`
+	ChurnCount       int   `json:"fingerprint_churn_count,omitempty"`
+	Preview          bool  `json:"preview,omitempty"`
+
+	Client        *fingerprint.UserAgent     `json:"client,omitempty"`
+	DeviceProfile *fingerprint.DeviceProfile `json:"device_profile,omitempty"`
+	Brands        []fingerprint.Brand        `json:"brands,omitempty"`
+
+	BotScore int      `json:"bot_score"`
+	BotRules []string `json:"bot_rules,omitempty"`
+
+	ClientTool           string  `json:"client_tool,omitempty"`
+	ClientToolConfidence float64 `json:"client_tool_confidence,omitempty"`
+
+	// LowConfidence marks requests below -min-signals
+	LowConfidence bool `json:"low_confidence,omitempty"`
+
+	// TLSMismatch is set when the TLS details could be checked against
+	// the User-Agent
+	TLSMismatch       *bool  `json:"tls_mismatch,omitempty"`
+	TLSMismatchReason string `json:"tls_mismatch_reason,omitempty"`
+
+	// SecFetchValid is set when the request sent Sec-Fetch-* headers
+	SecFetchValid  *bool  `json:"sec_fetch_valid,omitempty"`
+	SecFetchReason string `json:"sec_fetch_reason,omitempty"`
+
+	// ProtoMismatch is set when the request sent X-Forwarded-Proto,
+	// X-Forwarded-Port, or Forwarded proto=
+	ProtoMismatch       *bool  `json:"proto_mismatch,omitempty"`
+	ProtoMismatchReason string `json:"proto_mismatch_reason,omitempty"`
+
+	// IPHeaderConflict is set when the request sent a header carrying the
+	// client IP, such as X-Forwarded-For or X-Real-IP
+	IPHeaderConflict       *bool  `json:"ip_header_conflict,omitempty"`
+	IPHeaderConflictReason string `json:"ip_header_conflict_reason,omitempty"`
+
+	PreferredLanguage string                 `json:"preferred_language,omitempty"`
+	Languages         []fingerprint.Language `json:"languages,omitempty"`
+
+	MediaTypes []fingerprint.Preference `json:"media_types,omitempty"`
+	Encodings  []fingerprint.Preference `json:"encodings,omitempty"`
+	Charsets   []fingerprint.Preference `json:"charsets,omitempty"`
+
+	// Components and the entropy estimate are only included when the
+	// request asks for ?debug=1
+	Components        []string               `json:"components,omitempty"`
+	IPChain           []string               `json:"ip_chain,omitempty"`
+	IPHeaders         map[string][]string    `json:"ip_headers,omitempty"`
+	EntropyBits       *float64               `json:"entropy_bits,omitempty"`
+	EntropyComponents map[string]float64     `json:"entropy_components,omitempty"`
+	Tiers             []fingerprint.TierHash `json:"tiers,omitempty"`
+	TLSExtensions     []uint16               `json:"tls_extensions,omitempty"`
+	SupportedGroups   []uint16               `json:"supported_groups,omitempty"`
+	ChurnHistory      []string               `json:"churn_history,omitempty"`
+
+	Timestamp string `json:"timestamp"`
+
+	// fields, when set, limits the encoded fields to those selected with
+	// ?fields=
+	fields fieldSet
+}
+
+func (resp fingerprintResponse) MarshalJSON() ([]byte, error) {
+	type plain fingerprintResponse
+	body, err := json.Marshal(plain(resp))
+	if err != nil || resp.fields == nil {
+		return body, err
+	}
+	return resp.fields.filter(body)
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type statusResponse struct {
+	Status string `json:"status"`
 }
 
-func extractIPAddress(r *http.Request) string {
-	// Check for X-Forwarded-For header (proxy/load balancer)
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// Take the first IP in the chain
-		ips := strings.Split(xff, ",")
-		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
+type server struct {
+	config  *fingerprint.Config
+	geo     *geoIP
+	dcs     *datacenterList
+	anon    *anonymizers
+	rdns    *reverseDNS
+	store   Store
+	limiter *rateLimiter
+	// routes picks the policy of each request, falling back to the one
+	// the flags configure
+	routes *routeTable
+	// clientKey keys clients for rate limiting and /stats
+	clientKey clientKeyStrategy
+	metrics   *metrics
+	entropy   *entropyTable
+
+	// now returns the current time. Handlers call it instead of time.Now
+	// so tests can substitute a fixed clock.
+	now func() time.Time
+
+	// stats summarizes fingerprinted traffic for /stats
+	stats *statsTracker
+
+	// sinks receive every computed fingerprint
+	sinks []Sink
+
+	// stream, when set, is the sink fanning fingerprints out to /stream
+	stream *streamBroadcaster
+
+	// redactor rewrites personal data before it is logged or persisted
+	redactor *redactor
+
+	// nonces, when set, requires a single-use nonce on /fingerprint
+	nonces *nonceIssuer
+
+	// list, when set, blocks requests by fingerprint with blockStatus
+	list        *fingerprintList
+	blockStatus int
+
+	// goodBots, when set, are the verified crawlers marked
+	// verified_good_bot, and let through list unless goodBotBypass is off
+	goodBots      goodBots
+	goodBotBypass bool
+
+	// clusters, when set, groups similar fingerprints
+	clusters *clusterIndex
+
+	// churn, when set, flags clients cycling through fingerprints
+	churn *churnTracker
+
+	// stability, when set, persists fingerprints that drift slightly
+	// under the one their client was first seen with
+	stability *stabilityWindow
+
+	// sampler, when set, limits side effects to a fraction of requests
+	sampler *sampler
+
+	// minSignals, when positive, marks requests with fewer signals as low
+	// confidence, or rejects them if rejectLowSignal is set
+	minSignals      int
+	rejectLowSignal bool
+
+	// cookie enables the visitor ID cookie
+	cookie bool
+
+	// fingerprintProbes logs the fingerprint of requests to unknown paths
+	fingerprintProbes bool
+
+	// tools classifies requests from HTTP client tools such as curl
+	tools *fingerprint.ToolClassifier
+
+	// shortID, when set, derives the short_id of each fingerprint
+	shortID *fingerprint.ShortIDFormat
+
+	// responseHeaders are the response fields /fingerprint also sets as
+	// headers
+	responseHeaders []string
+
+	// adminToken, when set, is the bearer token of the /admin endpoints
+	adminToken string
+
+	// tokens, when set, signs a token of each /fingerprint response
+	tokens *tokenIssuer
+
+	// tracer, when set, records a span of each /fingerprint request
+	tracer trace.Tracer
+
+	// collisions, when set, checks that no fingerprint is computed from
+	// two different component vectors
+	collisions *collisionAudit
+
+	// ready is set once all optional dependencies are initialized
+	ready atomic.Bool
+}
+
+// handleHealth is the liveness probe. It never touches optional
+// dependencies so it keeps working when they are disabled or degraded.
+func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, statusResponse{Status: "ok"})
+}
+
+// handleReady is the readiness probe. It reports 503 until startup has
+// finished and while the fingerprint store is unreachable.
+func (s *server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if !s.ready.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, statusResponse{Status: "starting"})
+		return
+	}
+	if s.store != nil {
+		if err := s.store.Ping(r.Context()); err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, statusResponse{Status: "database unavailable"})
+			return
 		}
 	}
+	writeJSON(w, http.StatusOK, statusResponse{Status: "ready"})
+}
 
-	// Check for X-Real-IP header
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return strings.TrimSpace(xri)
+// rateLimit rejects requests from clients that have exhausted their token
+// bucket with 429 Too Many Requests, using the limiter of the request's
+// route policy. It is a no-op when no policy rate limits.
+func (s *server) rateLimit(next http.HandlerFunc) http.HandlerFunc {
+	if !s.routes.rateLimited() {
+		return next
 	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		limiter := s.policy(r).limiter
+		if limiter == nil {
+			next(w, r)
+			return
+		}
+		key := s.clientKey.key(s.client(r, func() string {
+			_, hash, _ := s.peekFingerprint(r)
+			return hash
+		}))
+		if ok, wait := limiter.Allow(key, s.now()); !ok {
+			seconds := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+			s.metrics.rateLimited.Inc()
+			writeJSON(w, http.StatusTooManyRequests, errorResponse{Error: "rate limit exceeded"})
+			return
+		}
+		next(w, r)
+	}
+}
 
-	// Fall back to RemoteAddr
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
+// client describes the sender of r; hash computes the fingerprint of r
+// for strategies that key by it.
+func (s *server) client(r *http.Request, hash func() string) client {
+	return client{
+		ip:          s.config.ClientIP(r),
+		userAgent:   r.UserAgent(),
+		fingerprint: hash,
 	}
-	return ip
 }
 
-func extractHeaders(r *http.Request) map[string]string {
-	headers := make(map[string]string)
-
-	// Extract specific headers that are useful for fingerprinting
-	fingerprintHeaders := []string{
-		"User-Agent",
-		"Accept",
-		"Accept-Language",
-		"Accept-Encoding",
-		"Accept-Charset",
-		"Connection",
-		"Upgrade-Insecure-Requests",
-		"Sec-Fetch-Site",
-		"Sec-Fetch-Mode",
-		"Sec-Fetch-User",
-		"Sec-Fetch-Dest",
-		"Sec-Ch-Ua",
-		"Sec-Ch-Ua-Mobile",
-		"Sec-Ch-Ua-Platform",
-		"Sec-Ch-Ua-Platform-Version",
-		"Sec-Ch-Ua-Arch",
-		"Sec-Ch-Ua-Model",
-		"Sec-Ch-Ua-Bitness",
-		"Sec-Ch-Ua-Full-Version",
-		"Sec-Ch-Ua-Full-Version-List",
-		"Sec-Ch-Ua-Wow64",
-		"Sec-Ch-Viewport-Width",
-		"Sec-Ch-Viewport-Height",
-		"Sec-Ch-Dpr",
-		"Sec-Ch-Device-Memory",
-		"Sec-Ch-Prefers-Color-Scheme",
-		"Sec-Ch-Prefers-Reduced-Motion",
-		"Cache-Control",
-		"Pragma",
-		"DNT",
-		"Referer",
-		"Origin",
-		"Host",
-		"Authorization",
-		"X-Requested-With",
-		"Content-Type",
-		"If-None-Match",
-		"If-Modified-Since",
-		"X-Forwarded-Proto",
-		"X-Forwarded-Port",
-		"CF-Ray",
-		"CF-IPCountry",
-		"CF-Connecting-IP",
-		"True-Client-IP",
-		"X-Client-IP",
-		"X-Cluster-Client-IP",
-		"Forwarded",
-		"Via",
-		"X-Original-Forwarded-For",
-		"CloudFront-Viewer-Country",
-		"X-Amzn-Trace-Id",
-		"Accept-Datetime",
-		"TE",
-		"Expect",
-		"Max-Forwards",
-		"Range",
-		"Warning",
-		"Date",
-		"From",
-		"Save-Data",
-		"Viewport-Width",
-		"Width",
-		"DPR",
-		"Device-Memory",
-		"ECT",
-		"RTT",
-		"Downlink",
-	}
-
-	for _, headerName := range fingerprintHeaders {
-		if value := r.Header.Get(headerName); value != "" {
-			headers[strings.ToLower(headerName)] = value
-		}
-	}
-
-	return headers
+// peekFingerprint computes the full and stable fingerprints of r for
+// middleware, leaving r untouched. It fingerprints a copy without the
+// visitor cookie and nonce header, so the hashes match the ones the
+// handler computes.
+func (s *server) peekFingerprint(r *http.Request) (data fingerprint.Data, hash, stable string) {
+	clone := r.Clone(r.Context())
+	if s.cookie {
+		clone = stripVisitorCookie(clone)
+	}
+	if s.churn != nil {
+		clone = stripHeader(clone, churnKeyHeader)
+	}
+	config := s.policy(r).config
+	data, hash = config.FromRequest(stripNonceHeader(clone))
+	return data, hash, config.GenerateStable(data)
 }
 
-func extractAdditionalSignals(r *http.Request) (string, string, string, string) {
-	method := r.Method
-	protocol := r.Proto
+func (s *server) handleFingerprint(w http.ResponseWriter, r *http.Request) {
+	resp := s.fingerprint(w, r)
+	s.setResponseHeaders(w, resp)
+	writeJSON(w, http.StatusOK, resp)
+}
 
-	// Extract port from Host header
-	host := r.Host
-	port := ""
-	if strings.Contains(host, ":") {
-		_, port, _ = net.SplitHostPort(host)
+// fingerprint computes, logs, and records the fingerprint of r and builds
+// its response. It may set headers such as the visitor cookie on w.
+func (s *server) fingerprint(w http.ResponseWriter, r *http.Request) fingerprintResponse {
+	var visitor string
+	if s.cookie {
+		var isNew bool
+		visitor, isNew = visitorID(r)
+		if isNew {
+			setVisitorCookie(w, r, visitor)
+		}
+		r = stripVisitorCookie(r)
+	}
+	var churnKey string
+	if s.churn != nil {
+		churnKey = clientKey(r, visitor)
+		r = stripHeader(r, churnKeyHeader)
 	}
 
-	// Extract TLS version if available
-	tlsVersion := ""
-	if r.TLS != nil {
-		switch r.TLS.Version {
-		case tls.VersionTLS10:
-			tlsVersion = "TLS1.0"
-		case tls.VersionTLS11:
-			tlsVersion = "TLS1.1"
-		case tls.VersionTLS12:
-			tlsVersion = "TLS1.2"
-		case tls.VersionTLS13:
-			tlsVersion = "TLS1.3"
-		default:
-			tlsVersion = "unknown"
+	config := s.policy(r).config
+	data, hash := config.FromRequest(r)
+	now := s.now()
+	fields := selectFields(w, r)
+
+	s.metrics.observeFingerprint(data.Protocol, hash)
+	sampled := s.sampler.Sample(hash)
+	if sampled {
+		s.metrics.observeSampled()
+		s.record(r.Context(), data, hash, now)
+		if s.collisions != nil {
+			s.auditCollision(config, data, hash)
+		}
+	}
+
+	// Fields copied into response headers, signed into the token, or added
+	// to the trace span are computed even when ?fields= leaves them out of
+	// the body
+	needed := fields.with(s.responseHeaders...).with(s.tokens.needs()...).with(spanFields(r.Context())...)
+	resp := s.describe(config, r, data, hash, now, needed)
+	resp.fields = fields
+	annotateSpan(r.Context(), data, resp)
+	resp.VisitorID = visitor
+	if s.sampler != nil {
+		resp.Sampled = &sampled
+	}
+	key := s.clientKey.key(client{ip: data.IPAddress, userAgent: data.UserAgent, fingerprint: func() string { return hash }})
+	if sampled && !resp.LowConfidence {
+		country := resp.Country
+		if !fields.wants(geoFields...) {
+			country = s.geo.Country(data.IPAddress)
+		}
+		// /stats is public and lists clients to admins, so its clients
+		// and User-Agents are redacted like the logs
+		redacted := client{ip: s.redactor.IP(data.IPAddress), userAgent: s.redactor.UserAgent(data.UserAgent), fingerprint: func() string { return hash }}
+		s.stats.observe(s.clientKey.key(redacted), redacted.userAgent, country, data.Protocol)
+	}
+
+	var components []string
+	var bits *float64
+	var contributions map[string]float64
+	if sampled || isDebug(r) {
+		components = config.Components(data)
+	}
+	if sampled {
+		total, byComponent := s.entropy.Observe(components, now)
+		bits, contributions = &total, byComponent
+		if s.clusters != nil {
+			resp.ClusterID = s.clusters.Assign(hash, components)
+		}
+	}
+	stored := hash
+	if s.stability != nil && sampled {
+		stored = s.stability.Canonical(key, hash, components, now)
+		resp.CanonicalFingerprint = stored
+	}
+	var churn churnResult
+	if churnKey != "" {
+		churn = s.churn.Observe(churnKey, hash, now)
+		resp.FingerprintChurn, resp.ChurnCount = &churn.Churn, churn.Count
+	}
+	if isDebug(r) {
+		resp.Components = components
+		_, resp.IPChain = s.config.ClientIPChain(r)
+		resp.EntropyBits = bits
+		resp.EntropyComponents = contributions
+		resp.ChurnHistory = churn.Fingerprints
+	}
+
+	if s.store != nil && sampled {
+		v, err := s.store.Upsert(r.Context(), stored, s.sighting(data, now, config.Components))
+		if err != nil {
+			slog.Error("failed to persist fingerprint", "fingerprint", stored, "error", err)
+		} else {
+			resp.HitCount = v.HitCount
+			resp.FirstSeen = v.FirstSeen.Format(time.RFC3339)
+			if !resp.LowConfidence {
+				s.stats.observeAge(v, now)
+			}
 		}
 	}
 
-	return method, protocol, tlsVersion, port
+	if s.tokens != nil && fields.wants("token") {
+		token, err := s.tokens.sign(resp, now)
+		if err != nil {
+			slog.Error("failed to sign fingerprint token", "fingerprint", hash, "error", err)
+		} else {
+			resp.Token = token
+		}
+	}
+
+	return resp
 }
 
-func generateFingerprint(data FingerprintData) string {
-	var parts []string
+// geoFields are the response fields that need a GeoIP lookup.
+var geoFields = []string{"country", "city", "asn", "datacenter", "datacenter_provider", "anonymizer", "anonymizer_provider"}
 
-	// Add IP address
-	parts = append(parts, fmt.Sprintf("ip:%s", data.IPAddress))
+// describe builds the response for a fingerprint computed with config,
+// enriching it without affecting the hash. Enrichment is skipped when
+// fields selects none of the fields it feeds. It has no side effects
+// beyond those of config's hash cache.
+func (s *server) describe(config *fingerprint.Config, r *http.Request, data fingerprint.Data, hash string, now time.Time, fields fieldSet) fingerprintResponse {
+	var geo geoInfo
+	if fields.wants(geoFields...) {
+		geo = s.geo.Lookup(data.IPAddress)
+	}
+	resp := fingerprintResponse{
+		Fingerprint:       hash,
+		HashAlgorithm:     config.Hash.String(),
+		IP:                data.IPAddress,
+		Protocol:          fingerprint.NormalizeProtocol(data.Protocol),
+		CipherSuite:       data.CipherSuite,
+		ALPN:              data.ALPN,
+		ClientCert:        data.ClientCertThumbprint,
+		ClientCertSubject: data.ClientCertSubject,
+		ClientCertIssuer:  data.ClientCertIssuer,
+		JA3:               data.JA3,
+		JA4:               data.JA4,
+		H2Fingerprint:     data.H2Fingerprint,
+		HeaderOrder:       data.HeaderOrder,
+		Country:           geo.Country,
+		City:              geo.City,
+		ASN:               geo.ASN,
+		Timestamp:         now.Format(time.RFC3339),
+		fields:            fields,
+	}
+	if fields.wants("stable_fingerprint") {
+		resp.StableFingerprint = config.GenerateStable(data)
+	}
+	if s.shortID != nil && fields.wants("short_id") {
+		resp.ShortID = s.shortID.ShortID(hash)
+	}
+	if fields.wants("tiered_fingerprint", "tiers") {
+		tiered := config.GenerateTiered(data)
+		resp.TieredFingerprint = tiered.Hash
+		if isDebug(r) {
+			resp.Tiers = tiered.Tiers
+		}
+	}
+	if isDebug(r) {
+		resp.TLSExtensions, resp.SupportedGroups = data.TLSExtensions, data.SupportedGroups
+	}
+	if s.dcs != nil && fields.wants("datacenter", "datacenter_provider") {
+		provider := s.dcs.Lookup(data.IPAddress, geo.ASN)
+		isDatacenter := provider != ""
+		resp.Datacenter, resp.DatacenterName = &isDatacenter, provider
+	}
+	if s.anon != nil && fields.wants("anonymizer", "anonymizer_provider") {
+		resp.Anonymizer, resp.AnonymizerProvider = s.anon.Lookup(data.IPAddress, geo.ASN)
+	}
+	if s.rdns != nil && fields.wants("hostname", "verified_bot", "verified_good_bot") {
+		rdns := s.rdns.Lookup(r.Context(), data.IPAddress, now)
+		resp.Hostname, resp.VerifiedBot = rdns.Hostname, rdns.VerifiedBot()
+		resp.VerifiedGoodBot = s.goodBots.match(rdns)
+	}
+	if data.UserAgent != "" && fields.wants("client") {
+		client := fingerprint.ParseUserAgent(data.UserAgent)
+		resp.Client = &client
+	}
+	if fields.wants("device_profile") {
+		if profile, ok := fingerprint.ParseDeviceProfile(data.Headers); ok {
+			resp.DeviceProfile = &profile
+		}
+	}
+	if fields.wants("brands") {
+		brands := r.Header.Get("Sec-Ch-Ua-Full-Version-List")
+		if brands == "" {
+			brands = r.Header.Get("Sec-Ch-Ua")
+		}
+		resp.Brands, _ = fingerprint.ParseBrands(brands)
+	}
+	if data.AcceptLang != "" && fields.wants("languages", "preferred_language") {
+		resp.Languages = fingerprint.ParseAcceptLanguage(data.AcceptLang)
+		resp.PreferredLanguage = fingerprint.PreferredLanguage(data.AcceptLang)
+	}
+	// Parsed from the request rather than data, which may hold the
+	// canonical form, so equal weights keep the client's order
+	if fields.wants("media_types", "encodings", "charsets") {
+		resp.MediaTypes = fingerprint.ParsePreferences(r.Header.Get("Accept"))
+		resp.Encodings = fingerprint.ParsePreferences(r.Header.Get("Accept-Encoding"))
+		resp.Charsets = fingerprint.ParsePreferences(r.Header.Get("Accept-Charset"))
+	}
 
-	// Add request metadata
-	parts = append(parts, fmt.Sprintf("method:%s", data.Method))
-	parts = append(parts, fmt.Sprintf("protocol:%s", data.Protocol))
-	if data.TLSVersion != "" {
-		parts = append(parts, fmt.Sprintf("tls:%s", data.TLSVersion))
+	if fields.wants("bot_score", "bot_rules") {
+		bot := fingerprint.ScoreBot(data)
+		resp.BotScore, resp.BotRules = bot.Score, bot.Rules
+	}
+	if fields.wants("client_tool", "client_tool_confidence") {
+		tool := s.tools.Classify(data)
+		resp.ClientTool, resp.ClientToolConfidence = tool.Tool, tool.Confidence
+	}
+	resp.LowConfidence = s.lowConfidence(data)
+	if fields.wants("tls_mismatch", "tls_mismatch_reason") {
+		if check := fingerprint.CheckTLS(data); check.Checked {
+			resp.TLSMismatch, resp.TLSMismatchReason = &check.Mismatch, check.Reason
+		}
+	}
+	if fields.wants("sec_fetch_valid", "sec_fetch_reason") {
+		if check := fingerprint.CheckSecFetch(data); check.Checked {
+			resp.SecFetchValid, resp.SecFetchReason = &check.Valid, check.Reason
+		}
+	}
+	if fields.wants("proto_mismatch", "proto_mismatch_reason") {
+		if check := fingerprint.CheckForwardedProto(r, config.TrustedProxies); check.Checked {
+			resp.ProtoMismatch, resp.ProtoMismatchReason = &check.Mismatch, check.Reason
+		}
 	}
-	if data.Port != "" {
-		parts = append(parts, fmt.Sprintf("port:%s", data.Port))
+	if fields.wants("ip_header_conflict", "ip_header_conflict_reason", "ip_headers") {
+		if check := fingerprint.CheckIPHeaders(r.Header); check.Checked {
+			resp.IPHeaderConflict, resp.IPHeaderConflictReason = &check.Conflict, check.Reason
+			if isDebug(r) {
+				resp.IPHeaders = check.Values
+			}
+		}
 	}
+	return resp
+}
 
-	// Add main headers
-	parts = append(parts, fmt.Sprintf("ua:%s", data.UserAgent))
-	parts = append(parts, fmt.Sprintf("accept:%s", data.Accept))
-	parts = append(parts, fmt.Sprintf("accept-lang:%s", data.AcceptLang))
-	parts = append(parts, fmt.Sprintf("accept-enc:%s", data.AcceptEnc))
+// isDebug reports whether the request asked for debug output with a
+// truthy debug query parameter such as ?debug=1.
+func isDebug(r *http.Request) bool {
+	debug, _ := strconv.ParseBool(r.URL.Query().Get("debug"))
+	return debug
+}
 
-	// Add other headers in sorted order for consistency
-	var headerKeys []string
-	for key := range data.Headers {
-		if key != "user-agent" && key != "accept" && key != "accept-language" && key != "accept-encoding" {
-			headerKeys = append(headerKeys, key)
-		}
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("failed to encode response", "error", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
 	}
-	sort.Strings(headerKeys)
 
-	for _, key := range headerKeys {
-		parts = append(parts, fmt.Sprintf("%s:%s", key, data.Headers[key]))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// newLogger returns a function that builds a logger writing to out in the
+// given format, either "json" or "text". The format is checked up front so
+// a bad one is reported before the output is opened.
+func newLogger(format string) (func(out io.Writer) *slog.Logger, error) {
+	opts := &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				a.Key = "timestamp"
+			}
+			return a
+		},
 	}
 
-	// Join all parts and create hash
-	fingerprint := strings.Join(parts, "|")
+	switch format {
+	case "json":
+		return func(out io.Writer) *slog.Logger { return slog.New(slog.NewJSONHandler(out, opts)) }, nil
+	case "text":
+		return func(out io.Writer) *slog.Logger { return slog.New(slog.NewTextHandler(out, opts)) }, nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (want json or text)", format)
+	}
+}
 
-	// Generate SHA-256 hash
-	hasher := sha256.New()
-	hasher.Write([]byte(fingerprint))
-	hash := hex.EncodeToString(hasher.Sum(nil))
+// logFile is the -log-file output, or nil when logging to stdout.
+var logFile *rotatingFile
 
-	return hash
+// exitError is an error that ends run, logged by main as msg with the
+// key-value pairs of args.
+type exitError struct {
+	msg  string
+	args []any
 }
 
-func fingerprintHandler(w http.ResponseWriter, r *http.Request) {
-	// Extract additional signals
-	method, protocol, tlsVersion, port := extractAdditionalSignals(r)
-
-	// Extract fingerprint data
-	data := FingerprintData{
-		IPAddress:     extractIPAddress(r),
-		UserAgent:     r.Header.Get("User-Agent"),
-		AcceptLang:    r.Header.Get("Accept-Language"),
-		AcceptEnc:     r.Header.Get("Accept-Encoding"),
-		Accept:        r.Header.Get("Accept"),
-		Headers:       extractHeaders(r),
-		RemoteAddr:    r.RemoteAddr,
-		XForwardedFor: r.Header.Get("X-Forwarded-For"),
-		XRealIP:       r.Header.Get("X-Real-IP"),
-		Method:        method,
-		Protocol:      protocol,
-		TLSVersion:    tlsVersion,
-		Port:          port,
-	}
-
-	// Generate fingerprint
-	fingerprint := generateFingerprint(data)
-
-	// Output to stdout (as requested)
-	fmt.Printf("[%s] Fingerprint: %s | IP: %s | UA: %s\n",
-		time.Now().Format(time.RFC3339),
-		fingerprint,
-		data.IPAddress,
-		data.UserAgent)
-
-	// Also return to client
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, `{"fingerprint": "%s", "timestamp": "%s"}`, fingerprint, time.Now().Format(time.RFC3339))
+func (e *exitError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.msg)
+	for i := 0; i+1 < len(e.args); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", e.args[i], e.args[i+1])
+	}
+	return b.String()
+}
+
+// fail returns an exitError, for run to return as the reason it stopped.
+func fail(msg string, args ...any) error {
+	return &exitError{msg: msg, args: args}
+}
+
+// listen opens a TCP listener on addr.
+func listen(addr string) (net.Listener, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		if errors.Is(err, syscall.EADDRINUSE) {
+			return nil, fail("cannot listen: address already in use", "addr", addr)
+		}
+		return nil, fail("cannot listen", "addr", addr, "error", err)
+	}
+	return listener, nil
+}
+
+// newPlainServer returns the -http-addr server, which serves only the
+// probes and metrics so they keep working over plain HTTP when the main
+// listener requires TLS. With autocert it also answers ACME HTTP-01
+// challenges. It shares main's timeouts.
+func newPlainServer(s *server, certManager *autocert.Manager, main *http.Server) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.HandleFunc("/readyz", s.handleReady)
+	mux.Handle("/metrics", promhttp.Handler())
+
+	var handler http.Handler = mux
+	if certManager != nil {
+		handler = certManager.HTTPHandler(mux)
+	}
+	return &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: main.ReadHeaderTimeout,
+		ReadTimeout:       main.ReadTimeout,
+		WriteTimeout:      main.WriteTimeout,
+		IdleTimeout:       main.IdleTimeout,
+		MaxHeaderBytes:    main.MaxHeaderBytes,
+	}
+}
+
+// defaultMaxHeaderBytes is generous, since fingerprinted requests can
+// carry many client hints and large cookies, but well below the 1 MiB
+// net/http allows by default.
+const defaultMaxHeaderBytes = 64 << 10
+
+// loadCertPool reads the PEM-encoded certificates in path into a pool.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
 }
 
 func main() {
-	http.HandleFunc("/fingerprint", fingerprintHandler)
+	err := run()
+	if err != nil {
+		var e *exitError
+		if errors.As(err, &e) {
+			slog.Error(e.msg, e.args...)
+		} else {
+			slog.Error(err.Error())
+		}
+	}
+	// Closed here rather than deferred in run so the error above is
+	// written to it
+	if logFile != nil {
+		logFile.Close()
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// run starts the server and serves until SIGINT or SIGTERM, or runs the
+// -once or -audit-file command. Errors are returned rather than exiting,
+// so the store and other resources it opened are closed by their deferred
+// calls first.
+func run() error {
+	configPath := flag.String("config", os.Getenv(flagEnv("config")),
+		"YAML file of settings keyed by flag name; flags and FINGERPRINT_* environment variables override it")
+	addr := flag.String("addr", ":8080", "listen address (env FINGERPRINT_ADDR)")
+	once := flag.Bool("once", false,
+		"fingerprint one request read from stdin as JSON attributes, in the /compare format, print its fingerprint, and exit instead of serving")
+	onceJSON := flag.Bool("json", false, "with -once, print the fingerprint, stable and tiered fingerprints, and components as JSON")
+	auditPath := flag.String("audit-file", "",
+		"check a file of request attributes, a /batch array or one JSON object per line, for fingerprint collisions, print them, and exit instead of serving")
+	collisionAuditSize := flag.Int("collision-audit", 0,
+		"log an error when a fingerprint recurs with different components among the last this many fingerprints, which means the hash encoding is broken (0 disables)")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate file; enables HTTPS together with -tls-key")
+	tlsKey := flag.String("tls-key", "", "TLS private key file")
+	autocertDomains := flag.String("autocert-domain", "",
+		"comma-separated domains to obtain Let's Encrypt certificates for; enables HTTPS instead of -tls-cert")
+	autocertCache := flag.String("autocert-cache", "autocert-cache", "directory where -autocert-domain certificates are stored")
+	autocertEmail := flag.String("autocert-email", "", "contact email for the Let's Encrypt account (optional)")
+	httpAddr := flag.String("http-addr", "",
+		"extra plain HTTP listen address serving only /healthz, /readyz, /metrics, and ACME challenges")
+	enableHTTP3 := flag.Bool("http3", false,
+		"also serve HTTP/3 over QUIC on the UDP port of -addr and advertise it with Alt-Svc; needs TLS")
+	grpcAddr := flag.String("grpc-addr", "", "listen address of the plaintext gRPC FingerprintService (disabled if empty)")
+	otel := flag.Bool("otel", false,
+		"export an OpenTelemetry span of each /fingerprint request over OTLP/HTTP, to the endpoint in the standard OTEL_EXPORTER_OTLP_ENDPOINT environment variable")
+	pprofAddr := flag.String("pprof", "",
+		"listen address of the net/http/pprof profiling endpoints, such as localhost:6060 (disabled if empty); never expose it to clients")
+	tlsClientCA := flag.String("tls-client-ca", "",
+		"PEM file of CAs that sign client certificates; clients presenting a valid one are fingerprinted by it")
+	trustedProxies := flag.String("trusted-proxies", "",
+		"comma-separated CIDRs or IPs of proxies whose -client-ip-header is trusted")
+	clientIPHeader := flag.String("client-ip-header", "x-forwarded-for",
+		"the header the -trusted-proxies write the client IP to: x-forwarded-for, forwarded, or x-real-ip; the others are ignored")
+	geoipDB := flag.String("geoip-db", "",
+		"comma-separated MaxMind DB files (City/Country and ASN) used to enrich responses")
+	datacenter := flag.Bool("datacenter", false,
+		"flag client IPs from known cloud and hosting providers, using the built-in ranges and any -datacenter-ranges")
+	datacenterRanges := flag.String("datacenter-ranges", "",
+		"comma-separated files of extra CIDR or AS number to provider mappings; implies -datacenter")
+	torExits := flag.Bool("tor-exits", false, "flag client IPs that are Tor exit nodes, using the list at -tor-exit-list")
+	torExitList := flag.String("tor-exit-list", defaultTorExitList, "URL or file of Tor exit IPs, one per line, for -tor-exits")
+	torRefresh := flag.Duration("tor-refresh", time.Hour, "how often -tor-exit-list is reloaded; a failed reload keeps the previous list")
+	vpnRanges := flag.String("vpn-ranges", "",
+		"comma-separated files of VPN and proxy CIDR or AS number to provider mappings, in the -datacenter-ranges format")
+	rdns := flag.Bool("rdns", false, "enrich responses with the reverse DNS hostname of the client IP and flag verified crawlers")
+	rdnsTimeout := flag.Duration("rdns-timeout", 500*time.Millisecond, "maximum time a -rdns lookup may take")
+	rdnsTTL := flag.Duration("rdns-ttl", time.Hour, "how long -rdns results, including failures, are cached")
+	rdnsCacheSize := flag.Int("rdns-cache-size", 10000, "number of client IPs whose -rdns results are cached")
+	goodBotList := flag.String("good-bots", "",
+		"comma-separated -rdns verified crawlers, such as Googlebot,Bingbot, or all, to mark as verified_good_bot; needs -rdns")
+	goodBotBypass := flag.Bool("good-bot-bypass", true, "let -good-bots through -denylist and -allowlist")
+	dbPath := flag.String("db", "", "SQLite database file used to track first/last seen times per fingerprint; short for -store sqlite -store-dsn FILE")
+	storeKind := flag.String("store", "", "fingerprint store: memory, sqlite, postgres, or redis")
+	storeDSN := flag.String("store-dsn", "",
+		"SQLite file, Postgres connection string, or Redis URL of -store (env FINGERPRINT_STORE_DSN)")
+	rateLimit := flag.Float64("rate", 0, "per-client rate limit in requests per second (0 disables)")
+	rateBurst := flag.Int("burst", 10, "maximum burst of requests per client when -rate is set")
+	clientKeyName := flag.String("client-key", "ip",
+		"what identifies a client for -rate and /stats: ip, subnet24, subnet48-v6, ip+ua, or fingerprint")
+	clientTools := flag.String("client-tools", "", "YAML file of extra client tool signatures for client_tool, matched before the built-in ones")
+	headersConfig := flag.String("headers-config", "", "JSON or YAML file that adds to, removes from, or replaces the fingerprint header list")
+	excludeHeaders := flag.String("exclude-headers", "",
+		"comma-separated headers to leave out of the fingerprint hash while still extracting them, such as one a proxy injects")
+	proxyHeaders := flag.String("proxy-headers", strings.Join(fingerprint.DefaultProxyHeaders(), ","),
+		"comma-separated proxy and trace headers, such as Via and CF-Ray, to leave out of the fingerprint hash while still extracting them, or none to hash them")
+	logExcludedHeaders := flag.Bool("log-excluded-headers", false, "add the values of the -exclude-headers and -proxy-headers headers to the fingerprint log line")
+	routesPath := flag.String("routes", "",
+		"JSON or YAML file of per-path policies that choose the hashed signals, whether the denylist or allowlist applies, and the rate limit")
+	logFormat := flag.String("log-format", "json", "log output format: json or text")
+	logPath := flag.String("log-file", "", "write logs to this file instead of stdout")
+	logMaxSize := flag.Int("log-max-size", 100, "size in megabytes at which -log-file is rotated")
+	logMaxBackups := flag.Int("log-max-backups", 3, "number of rotated -log-file backups to keep")
+	hashName := flag.String("hash", "sha256", "fingerprint hash algorithm: sha256, sha1, md5, or xxhash")
+	shortID := flag.String("short-id", "", "also return a short_id of each fingerprint for display and logs: hex or base62 (disabled if empty)")
+	shortIDLength := flag.Int("short-id-length", fingerprint.DefaultShortIDLength, "characters of -short-id; hex needs at least 10 and base62 at least 7")
+	salt := flag.String("salt", "",
+		"secret mixed into every fingerprint hash so fingerprints are unique to this deployment; changing it changes every fingerprint")
+	normalizeHeaders := flag.Bool("normalize-headers", false,
+		"canonicalize whitespace, case, and order of list headers such as Accept-Encoding before hashing")
+	canonicalNegotiation := flag.Bool("canonical-negotiation", false,
+		"hash Accept, Accept-Encoding, and Accept-Charset in a canonical weight-sorted form")
+	canonicalHeaders := flag.Bool("canonical-headers", false,
+		"hash every header in a canonical form per header, with lower-cased names, so proxy reformatting does not change fingerprints")
+	includePath := flag.Bool("include-path", false, "add the request path to the fingerprint")
+	includeQueryKeys := flag.Bool("include-query-keys", false, "add the sorted query parameter names, without values, to the fingerprint")
+	includeCookieNames := flag.Bool("include-cookie-names", false, "add the sorted cookie names, without values, to the fingerprint")
+	noIP := flag.Bool("no-ip", false, "leave the client IP out of the fingerprint so it survives network changes")
+	cacheSize := flag.Int("cache-size", 0, "number of recent fingerprint hashes to cache in memory (0 disables)")
+	bodySignals := flag.Bool("body-signals", false,
+		"add request body framing (transfer encoding, Content-Length presence, multipart boundary style) to the fingerprint")
+	secFetchSignal := flag.Bool("sec-fetch-signal", false,
+		"add the Sec-Fetch-* headers to the fingerprint as one component, marked with whether their combination is one a browser sends")
+	transportSignal := flag.Bool("transport-signal", false,
+		"hash the Connection, TE, Keep-Alive, and Proxy-Connection headers as one transport component that keeps their order and letter case")
+	tlsOrder := flag.Bool("tls-order", false,
+		"add the ClientHello's TLS extensions and supported groups, in the order sent, to the fingerprint")
+	denylist := flag.String("denylist", "", "file of fingerprints to block, one per line; reloaded on SIGHUP")
+	allowlist := flag.String("allowlist", "", "file of the only fingerprints allowed, one per line; reloaded on SIGHUP")
+	blockStatus := flag.Int("block-status", http.StatusForbidden, "HTTP status returned to blocked fingerprints")
+	webhookURL := flag.String("webhook-url", "", "POST each fingerprint as JSON to this URL")
+	webhookRetries := flag.Int("webhook-retries", 3, "times a failed -webhook-url delivery is retried")
+	webhookTimeout := flag.Duration("webhook-timeout", 5*time.Second, "timeout of each -webhook-url request")
+	stream := flag.Bool("stream", false, "serve /stream, which streams every fingerprint as newline-delimited JSON or server-sent events")
+	streamBuffer := flag.Int("stream-buffer", 256, "fingerprints buffered per /stream subscriber before records are dropped for it")
+	tokenSecret := flag.String("token-secret", "",
+		"shared secret that adds an HMAC-signed token of the fingerprint to /fingerprint responses, for fingerprint.VerifyToken (env FINGERPRINT_TOKEN_SECRET)")
+	tokenTTL := flag.Duration("token-ttl", 5*time.Minute, "how long a -token-secret token stays valid")
+	tokenFields := flag.String("token-fields", "stable_fingerprint,bot_score", "comma-separated response fields signed into -token-secret tokens alongside the fingerprint")
+	adminToken := flag.String("admin-token", "",
+		"bearer token that enables /admin/fingerprint/{hash}, which returns a stored fingerprint's history, and /diff, which compares two stored fingerprints, when there is a store, and guards /stream (env FINGERPRINT_ADMIN_TOKEN)")
+	redactIP := flag.String("redact-ip", "keep",
+		"how client IPs are logged, persisted, and sent to sinks: keep, truncate (to /24 or /48), hash, or drop")
+	redactUA := flag.String("redact-ua", "keep", "how User-Agents are logged, persisted, and sent to sinks: keep, hash, or drop")
+	redactHeaders := flag.String("redact-headers", "authorization,cookie,proxy-authorization",
+		"comma-separated headers whose values are never logged, persisted, or sent to sinks")
+	redactKey := flag.String("redact-key", "",
+		"HMAC key of the hash redaction policies; random per process if empty, so hashes change on restart")
+	nonceSecret := flag.String("nonce-secret", "",
+		"HMAC secret for /nonce tokens; when set, /fingerprint requires a nonce in the "+nonceHeader+" header (env FINGERPRINT_NONCE_SECRET)")
+	nonceTTL := flag.Duration("nonce-ttl", 2*time.Minute, "how long a /nonce token stays valid")
+	sampleRate := flag.Float64("sample-rate", 1,
+		"fraction of fingerprinted requests, from 0 to 1, that are logged, sent to sinks, persisted, and counted in /stats; every request still gets its fingerprint")
+	sampleByFingerprint := flag.Bool("sample-by-fingerprint", false,
+		"decide -sample-rate by fingerprint instead of per request, so a client is consistently sampled or not")
+	cookie := flag.Bool("cookie", false, "set a long-lived visitor ID cookie and return it alongside the fingerprint")
+	fingerprintProbes := flag.Bool("fingerprint-404-probes", false,
+		"log the fingerprint of requests to unknown paths, such as scanners probing for admin pages, before answering 404")
+	responseHeaderList := flag.String("response-headers", "",
+		"comma-separated /fingerprint fields also set as response headers: fingerprint (X-Fingerprint), stable_fingerprint (X-Fingerprint-Stable), bot_score (X-Bot-Score)")
+	minSignals := flag.Int("min-signals", 0,
+		"mark requests without a User-Agent and this many Accept, Sec-Ch-*, and Sec-Fetch-* signals as low confidence (0 disables)")
+	lowSignal := flag.String("low-signal", "flag", "what to do with requests below -min-signals: flag them with low_confidence or reject them with 422")
+	clusterThreshold := flag.Float64("cluster-threshold", 0,
+		"group fingerprints within this component distance (0 to 1) into clusters and return a cluster_id (0 disables)")
+	clusterSize := flag.Int("cluster-size", 10000, "maximum number of fingerprint clusters kept in memory")
+	churnThreshold := flag.Int("churn-threshold", 0,
+		"flag fingerprint_churn when a visitor cookie or "+churnKeyHeader+" key produces more than this many distinct fingerprints within -churn-window (0 disables)")
+	churnWindow := flag.Duration("churn-window", 10*time.Minute, "sliding window of -churn-threshold")
+	churnKeys := flag.Int("churn-keys", 10000, "maximum number of client keys whose -churn-threshold history is kept in memory")
+	stabilityDistance := flag.Int("stability-distance", 0,
+		"persist a fingerprint under a recent one of the same -client-key that differs in at most this many components, returned as canonical_fingerprint; needs a store (0 disables)")
+	stabilityWindowFlag := flag.Duration("stability-window", 24*time.Hour, "how recently a fingerprint must have been seen for -stability-distance to merge into it")
+	stabilityKeys := flag.Int("stability-keys", 10000, "maximum number of client keys whose -stability-distance history is kept in memory")
+	entropyHalfLife := flag.Duration("entropy-half-life", 24*time.Hour,
+		"half-life of the signal frequencies behind the debug entropy estimate")
+	readHeaderTimeout := flag.Duration("read-header-timeout", 10*time.Second,
+		"maximum time to read request headers, which bounds slow-header (slowloris) clients")
+	readTimeout := flag.Duration("read-timeout", time.Minute, "maximum time to read a whole request, including the body")
+	writeTimeout := flag.Duration("write-timeout", time.Minute, "maximum time from the end of the request headers to the end of the response")
+	idleTimeout := flag.Duration("idle-timeout", 2*time.Minute, "how long an idle keep-alive connection is kept open")
+	maxHeaderBytes := flag.Int("max-header-bytes", defaultMaxHeaderBytes, "maximum size of the request line and headers")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 10*time.Second,
+		"how long to wait for in-flight requests to finish on SIGINT/SIGTERM")
+	flag.Parse()
+	configSources, err := applyConfig(flag.CommandLine, *configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if *logMaxSize <= 0 || *logMaxBackups < 0 {
+		fmt.Fprintln(os.Stderr, "-log-max-size must be positive and -log-max-backups must not be negative")
+		os.Exit(2)
+	}
+	logger, err := newLogger(*logFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -log-format: %v\n", err)
+		os.Exit(2)
+	}
+	var logOut io.Writer = os.Stdout
+	if *once || *auditPath != "" {
+		// stdout carries the -once result or -audit-file report
+		logOut = os.Stderr
+	}
+	if *logPath != "" {
+		f, err := newRotatingFile(*logPath, int64(*logMaxSize)<<20, *logMaxBackups)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot open -log-file: %v\n", err)
+			os.Exit(1)
+		}
+		logFile, logOut = f, f
+	}
+
+	slog.SetDefault(logger(logOut))
+	logConfig(flag.CommandLine, configSources)
+
+	if (*tlsCert == "") != (*tlsKey == "") {
+		return fail("both -tls-cert and -tls-key must be set to enable TLS")
+	}
+	if *tlsCert != "" && *autocertDomains != "" {
+		return fail("-tls-cert and -autocert-domain are mutually exclusive")
+	}
+	useTLS := *tlsCert != "" || *autocertDomains != ""
+	if *enableHTTP3 && !useTLS {
+		return fail("-http3 needs -tls-cert and -tls-key or -autocert-domain")
+	}
+	if *tlsClientCA != "" && !useTLS {
+		return fail("-tls-client-ca requires -tls-cert and -tls-key or -autocert-domain")
+	}
+
+	proxies, err := fingerprint.ParseTrustedProxies(strings.Split(*trustedProxies, ","))
+	if err != nil {
+		return fail("invalid -trusted-proxies", "error", err)
+	}
+	ipHeader, err := fingerprint.ParseIPHeader(*clientIPHeader)
+	if err != nil {
+		return fail("invalid -client-ip-header", "error", err)
+	}
+	if len(proxies) > 0 {
+		slog.Info("trusting forwarding headers", "proxies", *trustedProxies, "header", ipHeader)
+	}
+
+	hashAlgorithm, err := fingerprint.ParseHashAlgorithm(*hashName)
+	if err != nil {
+		return fail("invalid -hash", "error", err)
+	}
+	if *salt != "" {
+		slog.Info("salting fingerprint hashes")
+	}
+
+	for name, timeout := range map[string]time.Duration{
+		"-read-header-timeout": *readHeaderTimeout,
+		"-read-timeout":        *readTimeout,
+		"-write-timeout":       *writeTimeout,
+		"-idle-timeout":        *idleTimeout,
+	} {
+		if timeout < 0 {
+			return fail(name + " must not be negative")
+		}
+	}
+	if *maxHeaderBytes <= 0 {
+		return fail("-max-header-bytes must be positive")
+	}
+
+	if *entropyHalfLife <= 0 {
+		return fail("-entropy-half-life must be positive")
+	}
+
+	s := &server{
+		config: &fingerprint.Config{
+			TrustedProxies:       proxies,
+			ClientIPHeader:       ipHeader,
+			NormalizeHeaders:     *normalizeHeaders,
+			CanonicalNegotiation: *canonicalNegotiation,
+			CanonicalHeaders:     *canonicalHeaders,
+			BodySignals:          *bodySignals,
+			SecFetchSignal:       *secFetchSignal,
+			TransportSignal:      *transportSignal,
+			TLSOrder:             *tlsOrder,
+			ExcludeIP:            *noIP,
+			IncludePath:          *includePath,
+			IncludeQueryKeys:     *includeQueryKeys,
+			IncludeCookieNames:   *includeCookieNames,
+			Hash:                 hashAlgorithm,
+			Salt:                 *salt,
+		},
+		now:     time.Now,
+		entropy: newEntropyTable(*entropyHalfLife),
+		stats:   newStatsTracker(time.Now()),
+		sinks:   []Sink{logSink{}},
+		cookie:  *cookie,
+
+		fingerprintProbes: *fingerprintProbes,
+	}
+	if *shortID != "" {
+		format, err := fingerprint.NewShortIDFormat(*shortID, *shortIDLength)
+		if err != nil {
+			return fail("invalid -short-id", "error", err)
+		}
+		s.shortID = &format
+		slog.Info("returning short IDs", "encoding", format.Encoding, "length", format.Length)
+	}
+
+	key := []byte(*redactKey)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+	s.redactor, err = newRedactor(*redactIP, *redactUA, strings.Split(*redactHeaders, ","), key)
+	if err != nil {
+		return fail("invalid redaction policy", "error", err)
+	}
+	if *redactIP != "keep" || *redactUA != "keep" {
+		slog.Info("redacting logged and persisted fingerprints", "ip", *redactIP, "user_agent", *redactUA)
+		if (*redactIP == "hash" || *redactUA == "hash") && *redactKey == "" {
+			slog.Warn("no -redact-key set, hashed IPs and User-Agents will change on restart")
+		}
+	}
+
+	if !(*sampleRate >= 0 && *sampleRate <= 1) {
+		return fail("-sample-rate must be between 0 and 1")
+	}
+	if *sampleRate < 1 {
+		s.sampler = &sampler{rate: *sampleRate, byFingerprint: *sampleByFingerprint}
+		slog.Info("sampling fingerprinted requests", "rate", *sampleRate, "by_fingerprint", *sampleByFingerprint)
+	}
+	if s.responseHeaders, err = parseResponseHeaders(*responseHeaderList); err != nil {
+		return fail("invalid -response-headers", "error", err)
+	}
+
+	if *headersConfig != "" {
+		headers, err := loadHeadersConfig(*headersConfig)
+		if err != nil {
+			return fail("invalid -headers-config", "error", err)
+		}
+		s.config.Headers = headers
+		slog.Info("loaded fingerprint headers", "path", *headersConfig, "count", len(headers), "headers", headers)
+	}
+	if *excludeHeaders != "" {
+		excluded, err := parseHeaderList(*excludeHeaders)
+		if err != nil {
+			return fail("invalid -exclude-headers", "error", err)
+		}
+		s.config.ExcludeHeaders = excluded
+		slog.Info("excluding headers from fingerprints", "headers", excluded)
+	}
+	if strings.TrimSpace(*proxyHeaders) == "none" {
+		s.config.ProxyHeaders = []string{}
+	} else {
+		proxied, err := parseHeaderList(*proxyHeaders)
+		if err != nil {
+			return fail("invalid -proxy-headers", "error", err)
+		}
+		s.config.ProxyHeaders = proxied
+	}
+	if *logExcludedHeaders {
+		logged := slices.Concat(s.config.ExcludeHeaders, s.config.ProxyHeaders)
+		slices.Sort(logged)
+		logged = slices.Compact(logged)
+		if len(logged) == 0 {
+			return fail("-log-excluded-headers needs -exclude-headers or -proxy-headers")
+		}
+		s.sinks[0] = logSink{headers: logged}
+	}
+	if *onceJSON && !*once {
+		return fail("-json needs -once")
+	}
+	if *once {
+		if err := runOnce(s.config, os.Stdin, os.Stdout, *onceJSON); err != nil {
+			return fail("cannot fingerprint -once input", "error", err)
+		}
+		return nil
+	}
+	if *auditPath != "" {
+		f, err := os.Open(*auditPath)
+		if err != nil {
+			return fail("cannot open -audit-file", "error", err)
+		}
+		entries, collisions, err := auditFile(s.config, f, os.Stdout)
+		f.Close()
+		if err != nil {
+			return fail("cannot audit -audit-file", "path", *auditPath, "error", err)
+		}
+		if collisions > 0 {
+			return fail("found fingerprint collisions", "entries", entries, "collisions", collisions)
+		}
+		slog.Info("no fingerprint collisions found", "entries", entries)
+		return nil
+	}
+	if *collisionAuditSize < 0 {
+		return fail("-collision-audit must not be negative")
+	}
+	if *collisionAuditSize > 0 {
+		s.collisions = newCollisionAudit(*collisionAuditSize)
+		slog.Info("auditing fingerprint collisions", "size", *collisionAuditSize)
+	}
+
+	s.tools, _ = fingerprint.NewToolClassifier(fingerprint.DefaultToolSignatures())
+	if *clientTools != "" {
+		tools, err := loadToolSignatures(*clientTools)
+		if err != nil {
+			return fail("invalid -client-tools", "error", err)
+		}
+		s.tools = tools
+		slog.Info("loaded client tool signatures", "path", *clientTools)
+	}
+
+	if *geoipDB != "" {
+		geo, err := openGeoIP(strings.Split(*geoipDB, ","))
+		if err != nil {
+			slog.Warn("GeoIP enrichment disabled", "error", err)
+		} else {
+			defer func() {
+				geo.Close()
+				slog.Info("closed GeoIP databases")
+			}()
+			s.geo = geo
+			slog.Info("GeoIP enrichment enabled", "databases", *geoipDB)
+		}
+	}
+
+	if *datacenter || *datacenterRanges != "" {
+		dcs := newDatacenterList()
+		if err := dcs.load("datacenters.txt", strings.NewReader(defaultDatacenters)); err != nil {
+			return fail("invalid built-in datacenter ranges", "error", err)
+		}
+		if *datacenterRanges != "" {
+			for _, path := range strings.Split(*datacenterRanges, ",") {
+				if err := dcs.loadFile(path); err != nil {
+					return fail("invalid -datacenter-ranges", "error", err)
+				}
+			}
+		}
+		s.dcs = dcs
+		slog.Info("datacenter detection enabled", "ranges", len(dcs.prefixes), "asns", len(dcs.asns))
+	}
+
+	if *torExits || *vpnRanges != "" {
+		anon := &anonymizers{}
+		if *torExits {
+			if *torRefresh <= 0 {
+				return fail("-tor-refresh must be positive")
+			}
+			tor, err := newTorExitList(*torExitList, *torRefresh, 30*time.Second)
+			if err != nil {
+				slog.Error("failed to load Tor exit list, retrying every -tor-refresh", "source", *torExitList, "error", err)
+			}
+			defer tor.Close()
+			anon.tor = tor
+			slog.Info("Tor exit detection enabled", "source", *torExitList, "count", tor.size(), "refresh", torRefresh.String())
+		}
+		if *vpnRanges != "" {
+			anon.vpn = newDatacenterList()
+			for _, path := range strings.Split(*vpnRanges, ",") {
+				if err := anon.vpn.loadFile(path); err != nil {
+					return fail("invalid -vpn-ranges", "error", err)
+				}
+			}
+			slog.Info("VPN detection enabled", "ranges", len(anon.vpn.prefixes), "asns", len(anon.vpn.asns))
+		}
+		s.anon = anon
+	}
+
+	if *rdns {
+		if *rdnsTimeout <= 0 || *rdnsTTL <= 0 || *rdnsCacheSize < 1 {
+			return fail("-rdns-timeout, -rdns-ttl, and -rdns-cache-size must be positive")
+		}
+		s.rdns = newReverseDNS(net.DefaultResolver, *rdnsTimeout, *rdnsTTL, *rdnsCacheSize)
+		slog.Info("reverse DNS enrichment enabled", "timeout", *rdnsTimeout, "ttl", *rdnsTTL)
+	}
+	if *goodBotList != "" {
+		if s.rdns == nil {
+			return fail("-good-bots needs -rdns")
+		}
+		if s.goodBots, err = parseGoodBots(*goodBotList); err != nil {
+			return fail("invalid -good-bots", "error", err)
+		}
+		s.goodBotBypass = *goodBotBypass
+		slog.Info("recognizing good bots", "bots", *goodBotList, "bypass_list", *goodBotBypass)
+	}
+
+	if *dbPath != "" {
+		if *storeKind != "" && *storeKind != "sqlite" || *storeDSN != "" && *storeDSN != *dbPath {
+			return fail("-db cannot be combined with another -store or -store-dsn")
+		}
+		*storeKind, *storeDSN = "sqlite", *dbPath
+	}
+	if *storeKind != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		store, err := openStore(ctx, *storeKind, *storeDSN)
+		cancel()
+		if err != nil {
+			return fail("cannot open fingerprint store", "store", *storeKind, "error", err)
+		}
+		defer func() {
+			if err := store.Close(); err != nil {
+				slog.Error("failed to close fingerprint store", "error", err)
+				return
+			}
+			slog.Info("closed fingerprint store")
+		}()
+		s.store = store
+		slog.Info("persisting fingerprints", "store", *storeKind)
+	}
+	if *tokenSecret != "" {
+		if len(*tokenSecret) < minTokenSecret {
+			return fail("-token-secret is too short", "min_bytes", minTokenSecret)
+		}
+		if *tokenTTL <= 0 {
+			return fail("-token-ttl must be positive")
+		}
+		fields, err := parseTokenFields(*tokenFields)
+		if err != nil {
+			return fail("invalid -token-fields", "error", err)
+		}
+		s.tokens = &tokenIssuer{secret: []byte(*tokenSecret), ttl: *tokenTTL, fields: fields}
+		slog.Info("signing fingerprint tokens", "ttl", *tokenTTL, "fields", fields)
+	}
+	if *adminToken != "" {
+		if s.store == nil && !*stream {
+			return fail("-admin-token needs -store, -db, or -stream")
+		}
+		if len(*adminToken) < minAdminToken {
+			return fail("-admin-token is too short", "min_bytes", minAdminToken)
+		}
+		s.adminToken = *adminToken
+		if s.store != nil {
+			slog.Info("serving /admin/fingerprint and /diff")
+		}
+	}
+	if s.clientKey, err = parseClientKey(*clientKeyName); err != nil {
+		return fail("invalid -client-key", "error", err)
+	}
+	if *rateLimit > 0 {
+		if *rateBurst < 1 {
+			return fail("-burst must be at least 1 when rate limiting is enabled")
+		}
+		s.limiter = newRateLimiter(*rateLimit, *rateBurst)
+		slog.Info("rate limiting enabled", "rate", *rateLimit, "burst", *rateBurst, "client_key", *clientKeyName)
+	}
+
+	if *cacheSize < 0 {
+		return fail("-cache-size must not be negative")
+	}
+	if *webhookURL != "" {
+		if u, err := url.Parse(*webhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fail("-webhook-url must be an http or https URL", "url", *webhookURL)
+		}
+		if *webhookRetries < 0 || *webhookTimeout <= 0 {
+			return fail("-webhook-retries must not be negative and -webhook-timeout must be positive")
+		}
+		s.sinks = append(s.sinks, newWebhookSink(*webhookURL, *webhookRetries, *webhookTimeout))
+		slog.Info("forwarding fingerprints to webhook", "url", *webhookURL)
+	}
+	if *stream {
+		// The stream carries every client's request, so it is an operator
+		// endpoint like /admin
+		if *adminToken == "" {
+			return fail("-stream needs -admin-token")
+		}
+		if *streamBuffer < 1 {
+			return fail("-stream-buffer must be at least 1")
+		}
+		s.stream = newStreamBroadcaster(*streamBuffer)
+		s.sinks = append(s.sinks, s.stream)
+		slog.Info("streaming fingerprints on /stream", "buffer", *streamBuffer)
+	}
+
+	if *nonceSecret != "" {
+		if len(*nonceSecret) < minNonceSecret {
+			return fail("-nonce-secret is too short", "min_bytes", minNonceSecret)
+		}
+		if *nonceTTL <= 0 {
+			return fail("-nonce-ttl must be positive")
+		}
+		s.nonces = newNonceIssuer([]byte(*nonceSecret), *nonceTTL)
+		slog.Info("requiring fingerprint nonces", "header", nonceHeader, "ttl", *nonceTTL)
+	}
+
+	if *minSignals < 0 {
+		return fail("-min-signals must not be negative")
+	}
+	switch *lowSignal {
+	case "flag":
+	case "reject":
+		s.rejectLowSignal = true
+	default:
+		return fail("-low-signal must be flag or reject", "value", *lowSignal)
+	}
+	if *minSignals > 0 {
+		s.minSignals = *minSignals
+		slog.Info("requiring fingerprint signals", "min", *minSignals, "action", *lowSignal)
+	}
+
+	if *clusterThreshold < 0 || *clusterThreshold >= 1 {
+		return fail("-cluster-threshold must be at least 0 and less than 1")
+	}
+	if *clusterThreshold > 0 {
+		if *clusterSize < 1 {
+			return fail("-cluster-size must be positive")
+		}
+		s.clusters = newClusterIndex(*clusterThreshold, *clusterSize)
+		slog.Info("clustering fingerprints", "threshold", *clusterThreshold, "size", *clusterSize)
+	}
+	if *churnThreshold < 0 {
+		return fail("-churn-threshold must not be negative")
+	}
+	if *churnThreshold > 0 {
+		if *churnWindow <= 0 || *churnKeys < 1 {
+			return fail("-churn-window must be positive and -churn-keys at least 1")
+		}
+		s.churn = newChurnTracker(*churnThreshold, *churnWindow, *churnKeys)
+		slog.Info("tracking fingerprint churn", "threshold", *churnThreshold, "window", *churnWindow, "keys", *churnKeys)
+	}
+	if *stabilityDistance < 0 {
+		return fail("-stability-distance must not be negative")
+	}
+	if *stabilityDistance > 0 {
+		if s.store == nil {
+			return fail("-stability-distance needs -store or -db")
+		}
+		if *stabilityWindowFlag <= 0 || *stabilityKeys < 1 {
+			return fail("-stability-window must be positive and -stability-keys at least 1")
+		}
+		s.stability = newStabilityWindow(*stabilityDistance, *stabilityWindowFlag, *stabilityKeys)
+		slog.Info("merging drifting fingerprints", "distance", *stabilityDistance, "window", *stabilityWindowFlag, "keys", *stabilityKeys)
+	}
 
-	port := ":8080"
-	fmt.Printf("Browser fingerprinting server starting on port %s\n", port)
-	fmt.Println("Send requests to http://localhost:8080/fingerprint")
+	if *denylist != "" && *allowlist != "" {
+		return fail("-denylist and -allowlist are mutually exclusive")
+	}
+	if *blockStatus < 400 || *blockStatus > 599 {
+		return fail("-block-status must be a 4xx or 5xx status code")
+	}
+	listPath, allow := *denylist, false
+	if *allowlist != "" {
+		listPath, allow = *allowlist, true
+	}
+	if listPath != "" {
+		list, err := loadFingerprintList(listPath, allow)
+		if err != nil {
+			return fail("cannot load fingerprint list", "path", listPath, "error", err)
+		}
+		list.reloadOnSIGHUP()
+		s.list, s.blockStatus = list, *blockStatus
+		slog.Info("enforcing fingerprint "+list.mode(), "path", listPath, "count", list.size(), "status", *blockStatus)
+	}
 
-	log.Fatal(http.ListenAndServe(port, nil))
+	if *cacheSize > 0 {
+		s.config.Cache = fingerprint.NewHashCache(*cacheSize)
+		slog.Info("caching fingerprint hashes", "size", *cacheSize)
+	}
+
+	s.routes = &routeTable{fallback: &routePolicy{name: "default", config: s.config, enforceList: s.list != nil, limiter: s.limiter}}
+	if *routesPath != "" {
+		routes, err := loadRoutes(*routesPath, s.routes.fallback, *rateBurst, s.list != nil)
+		if err != nil {
+			return fail("cannot load routes", "path", *routesPath, "error", err)
+		}
+		s.routes = routes
+		slog.Info("applying route policies", "path", *routesPath, "routes", len(routes.routes))
+	}
+
+	if *otel {
+		tp, err := newTracerProvider(context.Background())
+		if err != nil {
+			return fail("cannot set up OpenTelemetry tracing", "error", err)
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := tp.Shutdown(ctx); err != nil {
+				slog.Error("failed to flush trace spans", "error", err)
+			}
+		}()
+		s.tracer = tp.Tracer(tracingScope)
+		slog.Info("exporting OpenTelemetry traces")
+	}
+
+	s.metrics = newMetrics(prometheus.DefaultRegisterer, s.routes.rateLimited(), s.config.Cache, s.stream, *sampleRate, s.collisions != nil)
+
+	// The public listener has a mux of its own, so debug handlers that
+	// register on http.DefaultServeMux, such as net/http/pprof's, are
+	// never served on it
+	mux := http.NewServeMux()
+	handleFingerprint := s.traced("fingerprint", s.rateLimit(s.enforceList(s.requireSignals(s.requireNonce(s.metrics.instrument(s.handleFingerprint))))))
+	mux.HandleFunc("/fingerprint", handleFingerprint)
+	if *routesPath != "" {
+		// Pages can then call /fingerprint/login and the like to pick
+		// their route policy
+		mux.HandleFunc("/fingerprint/", handleFingerprint)
+	}
+	mux.HandleFunc("/ws-fingerprint", s.rateLimit(s.enforceList(s.requireSignals(s.metrics.instrument(s.handleWebSocketFingerprint)))))
+	if s.nonces != nil {
+		mux.HandleFunc("/nonce", s.rateLimit(s.handleNonce))
+	}
+	mux.HandleFunc("/fingerprint/self-test", s.rateLimit(s.handleSelfTest))
+	mux.HandleFunc("/preview", s.handlePreview)
+	mux.HandleFunc("/client-signals", s.rateLimit(s.enforceList(s.handleClientSignals)))
+	mux.HandleFunc("/fp.js", handleFPJS)
+	mux.HandleFunc("/stats", s.rateLimit(s.handleStats))
+	if s.stream != nil {
+		mux.HandleFunc("/stream", s.rateLimit(s.requireAdmin(s.handleStream)))
+	}
+	if s.adminToken != "" && s.store != nil {
+		mux.HandleFunc("/admin/fingerprint/{hash}", s.rateLimit(s.requireAdmin(s.handleAdminFingerprint)))
+		mux.HandleFunc("/diff", s.rateLimit(s.requireAdmin(s.handleDiff)))
+	}
+	mux.HandleFunc("/compare", s.rateLimit(s.handleCompare))
+	mux.HandleFunc("/batch", s.rateLimit(s.handleBatch))
+	mux.HandleFunc("/raw", s.rateLimit(s.handleRaw))
+	mux.HandleFunc("/verify", s.rateLimit(s.handleVerify))
+	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.HandleFunc("/readyz", s.handleReady)
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/", s.rateLimit(s.handleNotFound))
+
+	// The timeouts must be set before the HTTP/2 capture is installed,
+	// since its inner server copies them
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: *readHeaderTimeout,
+		ReadTimeout:       *readTimeout,
+		WriteTimeout:      *writeTimeout,
+		IdleTimeout:       *idleTimeout,
+		MaxHeaderBytes:    *maxHeaderBytes,
+	}
+	var certManager *autocert.Manager
+	if useTLS {
+		srv.TLSConfig = &tls.Config{}
+	}
+	if *autocertDomains != "" {
+		domains := strings.Split(*autocertDomains, ",")
+		certManager = &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(domains...),
+			Cache:      autocert.DirCache(*autocertCache),
+			Email:      *autocertEmail,
+		}
+		// TLS-ALPN-01 challenges are answered on the HTTPS listener itself
+		srv.TLSConfig.GetCertificate = certManager.GetCertificate
+		srv.TLSConfig.NextProtos = append(srv.TLSConfig.NextProtos, acme.ALPNProto)
+		slog.Info("obtaining certificates with ACME", "domains", domains, "cache", *autocertCache)
+	}
+	if *tlsClientCA != "" {
+		pool, err := loadCertPool(*tlsClientCA)
+		if err != nil {
+			return fail("cannot load -tls-client-ca", "error", err)
+		}
+		// Client certificates stay optional so browsers without one can
+		// still be fingerprinted
+		srv.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		srv.TLSConfig.ClientCAs = pool
+		slog.Info("verifying client certificates", "ca", *tlsClientCA)
+	}
+	var h3TLS *tls.Config
+	if *enableHTTP3 {
+		h3TLS = srv.TLSConfig.Clone()
+	}
+	if useTLS {
+		// Record each ClientHello so requests can be JA3 fingerprinted
+		fingerprint.NewHelloCapture().Install(srv)
+		// Record HTTP/2 SETTINGS and header order for the Akamai fingerprint
+		fingerprint.InstallHTTP2Capture(srv)
+	}
+
+	listener, err := listen(*addr)
+	if err != nil {
+		return err
+	}
+	if !useTLS {
+		// Record the wire order of request headers; net/http discards it
+		listener = fingerprint.CaptureHeaderOrder(srv, listener)
+	}
+
+	var h3Srv *http3.Server
+	var h3Conn net.PacketConn
+	if *enableHTTP3 {
+		if h3Srv, err = newHTTP3Server(srv, h3TLS, *tlsCert, *tlsKey); err != nil {
+			return fail("cannot load TLS certificate for HTTP/3", "error", err)
+		}
+		if h3Conn, err = listenUDP(*addr, listener.Addr()); err != nil {
+			return err
+		}
+		srv.Handler = advertiseHTTP3(mux, h3Conn.LocalAddr().(*net.UDPAddr).Port)
+	}
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		host = "localhost"
+	}
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+	slog.Info("browser fingerprinting server starting",
+		"addr", listener.Addr().String(),
+		"url", fmt.Sprintf("%s://%s/fingerprint", scheme, net.JoinHostPort(host, port)))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 5)
+	go func() {
+		// Certificate files are empty with -autocert-domain, which
+		// supplies certificates through GetCertificate instead
+		if useTLS {
+			serveErr <- srv.ServeTLS(listener, *tlsCert, *tlsKey)
+		} else {
+			serveErr <- srv.Serve(listener)
+		}
+	}()
+
+	if h3Srv != nil {
+		slog.Info("serving HTTP/3", "addr", h3Conn.LocalAddr().String())
+		go func() {
+			serveErr <- h3Srv.Serve(h3Conn)
+		}()
+	}
+
+	var plainSrv *http.Server
+	if *httpAddr != "" {
+		plainSrv = newPlainServer(s, certManager, srv)
+		plainListener, err := listen(*httpAddr)
+		if err != nil {
+			return err
+		}
+		slog.Info("serving health checks over plain HTTP", "addr", plainListener.Addr().String())
+		go func() {
+			serveErr <- plainSrv.Serve(plainListener)
+		}()
+	}
+
+	var grpcSrv *grpc.Server
+	if *grpcAddr != "" {
+		grpcSrv = newGRPCServer(s)
+		grpcListener, err := listen(*grpcAddr)
+		if err != nil {
+			return err
+		}
+		slog.Info("serving gRPC", "addr", grpcListener.Addr().String())
+		go func() {
+			serveErr <- grpcSrv.Serve(grpcListener)
+		}()
+	}
+
+	var pprofSrv *http.Server
+	if *pprofAddr != "" {
+		pprofSrv = newPprofServer(srv)
+		pprofListener, err := listen(*pprofAddr)
+		if err != nil {
+			return err
+		}
+		slog.Warn("serving pprof profiling endpoints; never expose this address to clients",
+			"addr", pprofListener.Addr().String())
+		go func() {
+			serveErr <- pprofSrv.Serve(pprofListener)
+		}()
+	}
+
+	s.ready.Store(true)
+	select {
+	case err := <-serveErr:
+		return fail("server stopped", "error", err)
+	case <-ctx.Done():
+	}
+	// A second signal during shutdown kills the process immediately
+	stop()
+
+	slog.Info("shutting down, waiting for in-flight requests", "timeout", shutdownTimeout.String())
+	s.ready.Store(false)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	if s.stream != nil {
+		// Streams never finish on their own
+		s.stream.Close(shutdownCtx)
+	}
+	if plainSrv != nil {
+		go plainSrv.Shutdown(shutdownCtx)
+	}
+	if pprofSrv != nil {
+		// A running CPU profile or trace is cut off rather than waited for
+		pprofSrv.Close()
+	}
+	if grpcSrv != nil {
+		// GracefulStop waits for in-flight RPCs; Stop cuts them off at
+		// the shutdown deadline
+		stopped := context.AfterFunc(shutdownCtx, grpcSrv.Stop)
+		grpcSrv.GracefulStop()
+		stopped()
+	}
+	if h3Srv != nil {
+		if err := h3Srv.Shutdown(shutdownCtx); err != nil {
+			h3Srv.Close()
+		}
+	}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("graceful shutdown timed out, closing remaining connections", "error", err)
+		srv.Close()
+	}
+	s.closeSinks(shutdownCtx)
+	slog.Info("server stopped")
+	return nil
 }
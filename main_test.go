@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// TestFingerprintResponseJSON checks that header values which need escaping
+// still produce a response body that decodes as JSON.
+func TestFingerprintResponseJSON(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/fingerprint", strings.NewReader("{}"))
+	r.Header.Set("User-Agent", "Mozilla/5.0 \"quoted\" back\\slash\nnewline")
+	w := httptest.NewRecorder()
+	s := newTestServer(t)
+	s.handleFingerprint(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var resp fingerprintResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("response %q is not JSON: %v", w.Body, err)
+	}
+	if _, digest, ok := fingerprint.SplitVersion(resp.Fingerprint); !ok || len(digest) != 64 {
+		t.Errorf("fingerprint = %q, want a version and 64 hex digits", resp.Fingerprint)
+	}
+	if resp.Timestamp == "" {
+		t.Error("timestamp is empty")
+	}
+}
+
+// TestMaxHeaderBytes checks that a server using the default header limit
+// rejects an oversized header with 431 and still accepts a large but
+// plausible one.
+func TestMaxHeaderBytes(t *testing.T) {
+	s := newTestServer(t)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(s.handleFingerprint))
+	srv.Config.MaxHeaderBytes = defaultMaxHeaderBytes
+	srv.Start()
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		size int
+		want int
+	}{
+		{name: "large cookie", size: 16 << 10, want: http.StatusOK},
+		{name: "oversized header", size: 100 << 10, want: http.StatusRequestHeaderFieldsTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+"/fingerprint", nil)
+			req.Header.Set("Cookie", "c="+strings.Repeat("x", tt.size))
+			resp, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+// TestReadHeaderTimeout checks that a client trickling its headers is
+// disconnected once the read header timeout expires.
+func TestReadHeaderTimeout(t *testing.T) {
+	s := newTestServer(t)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(s.handleFingerprint))
+	srv.Config.ReadHeaderTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	start := time.Now()
+	if _, err := io.WriteString(conn, "GET /fingerprint HTTP/1.1\r\nHost: example.com\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	// Keep sending a header byte at a time without ever finishing
+	go func() {
+		for range 20 {
+			time.Sleep(50 * time.Millisecond)
+			if _, err := io.WriteString(conn, "X"); err != nil {
+				return
+			}
+		}
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	// The server either closes the connection or resets it when the
+	// trickled bytes are still unread
+	body, err := io.ReadAll(conn)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatal("connection not closed by the server")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("disconnected after %v, want about 100ms", elapsed)
+	}
+	if strings.Contains(string(body), "200 OK") {
+		t.Errorf("slow client got a response: %q", body)
+	}
+}
+
+// TestOversizedBody checks that /compare stops reading a body larger than
+// its limit.
+func TestOversizedBody(t *testing.T) {
+	s := newTestServer(t)
+	body := `{"a": {"user_agent": "` + strings.Repeat("x", maxCompareBody) + `"}, "b": {}}`
+	r := httptest.NewRequest(http.MethodPost, "/compare", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	s.handleCompare(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if !strings.Contains(w.Body.String(), "request body exceeds") {
+		t.Errorf("body = %s", w.Body)
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: "json", want: `"timestamp":`},
+		{format: "text", want: "timestamp="},
+		{format: "xml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			logger, err := newLogger(tt.format)
+			if tt.want == "" {
+				if err == nil {
+					t.Error("newLogger accepted an unknown format")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out strings.Builder
+			logger(&out).Info("started")
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("log line %q does not contain %q", out.String(), tt.want)
+			}
+		})
+	}
+}
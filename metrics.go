@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"math/bits"
+	"net/http"
+	"sync"
+	"time"
+
+	"browser-fingerprint/fingerprint"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metrics holds the Prometheus collectors exported on /metrics. Labels are
+// limited to fixed sets of values; fingerprints and client IPs are never
+// used as labels.
+type metrics struct {
+	requests    *prometheus.CounterVec
+	duration    prometheus.Histogram
+	rateLimited prometheus.Counter
+	sampled     prometheus.Counter
+	collisions  prometheus.Counter
+	unique      *hyperLogLog
+}
+
+func newMetrics(reg prometheus.Registerer, rateLimiting bool, cache *fingerprint.HashCache, stream *streamBroadcaster, sampleRate float64, collisionAudit bool) *metrics {
+	factory := promauto.With(reg)
+	m := &metrics{
+		requests: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "fingerprint_requests_total",
+			Help: "Fingerprint requests handled, by HTTP protocol.",
+		}, []string{"protocol"}),
+		duration: factory.NewHistogram(prometheus.HistogramOpts{
+			Name:    "fingerprint_request_duration_seconds",
+			Help:    "Time taken to handle fingerprint requests.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		sampled: factory.NewCounter(prometheus.CounterOpts{
+			Name: "fingerprint_sampled_requests_total",
+			Help: "Fingerprint requests sampled for logging, sinks, persistence, and /stats.",
+		}),
+		unique: newHyperLogLog(),
+	}
+	factory.NewGauge(prometheus.GaugeOpts{
+		Name: "fingerprint_sample_rate",
+		Help: "Configured fraction of fingerprint requests sampled (-sample-rate).",
+	}).Set(sampleRate)
+	factory.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "fingerprint_unique_fingerprints",
+		Help: "Estimated number of distinct fingerprints seen since startup.",
+	}, m.unique.Estimate)
+
+	if rateLimiting {
+		m.rateLimited = factory.NewCounter(prometheus.CounterOpts{
+			Name: "fingerprint_rate_limited_requests_total",
+			Help: "Fingerprint requests rejected by the per-client rate limiter.",
+		})
+	}
+	if collisionAudit {
+		m.collisions = factory.NewCounter(prometheus.CounterOpts{
+			Name: "fingerprint_collisions_total",
+			Help: "Fingerprints seen with two different component vectors by -collision-audit.",
+		})
+	}
+	if cache != nil {
+		factory.NewCounterFunc(prometheus.CounterOpts{
+			Name: "fingerprint_cache_hits_total",
+			Help: "Fingerprint hashes served from the LRU cache.",
+		}, func() float64 {
+			hits, _ := cache.Stats()
+			return float64(hits)
+		})
+		factory.NewCounterFunc(prometheus.CounterOpts{
+			Name: "fingerprint_cache_misses_total",
+			Help: "Fingerprint hashes computed because they were not cached.",
+		}, func() float64 {
+			_, misses := cache.Stats()
+			return float64(misses)
+		})
+	}
+	if stream != nil {
+		factory.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "fingerprint_stream_subscribers",
+			Help: "Open /stream connections.",
+		}, func() float64 { return float64(stream.subscribers()) })
+		factory.NewCounterFunc(prometheus.CounterOpts{
+			Name: "fingerprint_stream_dropped_total",
+			Help: "Fingerprints dropped for /stream subscribers that fell behind.",
+		}, func() float64 { return float64(stream.dropped.Load()) })
+	}
+	return m
+}
+
+// instrument records the latency of each request handled by next.
+func (m *metrics) instrument(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next(w, r)
+		m.duration.Observe(time.Since(start).Seconds())
+	}
+}
+
+// observeFingerprint counts a fingerprinted request.
+func (m *metrics) observeFingerprint(protocol, hash string) {
+	m.requests.WithLabelValues(protocolLabel(protocol)).Inc()
+	m.unique.Add(hash)
+}
+
+// observeSampled counts a fingerprinted request that was sampled.
+func (m *metrics) observeSampled() {
+	m.sampled.Inc()
+}
+
+// protocolLabel maps r.Proto onto a fixed set of label values.
+func protocolLabel(protocol string) string {
+	switch protocol {
+	case "HTTP/1.0", "HTTP/1.1", "HTTP/2.0", "HTTP/3.0":
+		return protocol
+	default:
+		return "other"
+	}
+}
+
+// hllPrecision gives 2^14 registers, a 16 KiB sketch with a standard error
+// of about 0.8%.
+const hllPrecision = 14
+
+// hyperLogLog estimates the number of distinct fingerprints in constant
+// memory (Flajolet et al., 2007).
+type hyperLogLog struct {
+	mu        sync.Mutex
+	registers [1 << hllPrecision]uint8
+}
+
+func newHyperLogLog() *hyperLogLog {
+	return &hyperLogLog{}
+}
+
+// Add records a versioned fingerprint. Its digest is already a hash, so
+// the leading bytes are used directly as the hash value.
+func (h *hyperLogLog) Add(hash string) {
+	_, digest, ok := fingerprint.SplitVersion(hash)
+	if !ok || len(digest) < 16 {
+		return
+	}
+	raw, err := hex.DecodeString(digest[:16])
+	if err != nil {
+		return
+	}
+	x := binary.BigEndian.Uint64(raw)
+
+	index := x >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1)) + 1)
+
+	h.mu.Lock()
+	if rank > h.registers[index] {
+		h.registers[index] = rank
+	}
+	h.mu.Unlock()
+}
+
+// Estimate returns the approximate number of distinct values added, using
+// linear counting for small cardinalities.
+func (h *hyperLogLog) Estimate() float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	m := float64(len(h.registers))
+	var sum float64
+	var zeros int
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return estimate
+}
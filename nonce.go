@@ -0,0 +1,186 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// nonceHeader carries a token from /nonce on a /fingerprint request.
+const nonceHeader = "X-Fingerprint-Nonce"
+
+// minNonceSecret is the shortest -nonce-secret accepted, in bytes.
+const minNonceSecret = 16
+
+var (
+	errNonceMissing  = errors.New("nonce required")
+	errNonceInvalid  = errors.New("invalid nonce")
+	errNonceExpired  = errors.New("nonce expired")
+	errNonceMismatch = errors.New("nonce was issued to a different fingerprint")
+	errNonceReused   = errors.New("nonce already used")
+)
+
+// nonceIssuer issues and checks single-use tokens that protect
+// /fingerprint against replay. A token is an ID, an expiry time, and the
+// fingerprint of the client it was issued to, signed with HMAC-SHA256:
+//
+//	base64url(id[16] | expiry[8] | fingerprint) "." base64url(mac)
+//
+// Used IDs are remembered until they expire, in memory, so a token can be
+// redeemed once per server process.
+type nonceIssuer struct {
+	secret []byte
+	ttl    time.Duration
+
+	mu        sync.Mutex
+	used      map[[16]byte]time.Time
+	lastSweep time.Time
+}
+
+func newNonceIssuer(secret []byte, ttl time.Duration) *nonceIssuer {
+	return &nonceIssuer{secret: secret, ttl: ttl, used: make(map[[16]byte]time.Time)}
+}
+
+// issue returns a token bound to hash that expires after the issuer's TTL.
+func (n *nonceIssuer) issue(hash string, now time.Time) (string, time.Time) {
+	expires := now.Add(n.ttl)
+	payload := make([]byte, 16, 24+len(hash))
+	rand.Read(payload)
+	payload = binary.BigEndian.AppendUint64(payload, uint64(expires.Unix()))
+	payload = append(payload, hash...)
+
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(n.sign(payload)), expires
+}
+
+// redeem checks that token is authentic, unexpired, and bound to hash,
+// and marks it used. A token presented with the wrong fingerprint is not
+// consumed, so whoever captured it cannot burn it for its owner.
+func (n *nonceIssuer) redeem(token, hash string, now time.Time) error {
+	if token == "" {
+		return errNonceMissing
+	}
+	encPayload, encMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return errNonceInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil || len(payload) < 24 {
+		return errNonceInvalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encMAC)
+	if err != nil || !hmac.Equal(mac, n.sign(payload)) {
+		return errNonceInvalid
+	}
+
+	id := [16]byte(payload[:16])
+	expires := time.Unix(int64(binary.BigEndian.Uint64(payload[16:24])), 0)
+	if !now.Before(expires) {
+		return errNonceExpired
+	}
+	if string(payload[24:]) != hash {
+		return errNonceMismatch
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if now.Sub(n.lastSweep) > n.ttl {
+		for used, exp := range n.used {
+			if !now.Before(exp) {
+				delete(n.used, used)
+			}
+		}
+		n.lastSweep = now
+	}
+	if _, ok := n.used[id]; ok {
+		return errNonceReused
+	}
+	n.used[id] = expires
+	return nil
+}
+
+func (n *nonceIssuer) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, n.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+type nonceResponse struct {
+	Nonce     string `json:"nonce"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// handleNonce issues a nonce bound to the requesting client's fingerprint.
+// The client must request it the same way it will request /fingerprint,
+// so both fingerprints match.
+func (s *server) handleNonce(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+
+	// The nonce is redeemed on /fingerprint, so with -include-path it is
+	// bound to that path rather than this one
+	target := r.Clone(r.Context())
+	target.URL.Path = "/fingerprint"
+	_, hash, _ := s.peekFingerprint(target)
+	token, expires := s.nonces.issue(hash, s.now())
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, nonceResponse{Nonce: token, ExpiresAt: expires.UTC().Format(time.RFC3339)})
+}
+
+// requireNonce rejects requests without a valid, unused nonce bound to
+// their fingerprint with 403, before next runs. It is a no-op when nonces
+// are not configured.
+func (s *server) requireNonce(next http.HandlerFunc) http.HandlerFunc {
+	if s.nonces == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, hash, _ := s.peekFingerprint(r)
+		if err := s.nonces.redeem(r.Header.Get(nonceHeader), hash, s.now()); err != nil {
+			slog.Warn("rejected fingerprint nonce",
+				"fingerprint", hash,
+				"ip", s.redactor.IP(data.IPAddress),
+				"error", err)
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: err.Error()})
+			return
+		}
+		next(w, stripNonceHeader(r))
+	}
+}
+
+// stripNonceHeader removes the nonce header from r and its recorded header
+// order, since the nonce differs per request and must not feed the hash.
+func stripNonceHeader(r *http.Request) *http.Request {
+	return stripHeader(r, nonceHeader)
+}
+
+// stripHeader removes the named header from r and its recorded header
+// order.
+func stripHeader(r *http.Request, name string) *http.Request {
+	if r.Header.Values(name) == nil {
+		return r
+	}
+	r.Header.Del(name)
+	order := fingerprint.HeaderOrderFromContext(r.Context())
+	if order == nil {
+		return r
+	}
+	kept := slices.DeleteFunc(slices.Clone(order), func(header string) bool {
+		return strings.EqualFold(header, name)
+	})
+	return r.WithContext(fingerprint.WithHeaderOrder(r.Context(), kept))
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequireNonce(t *testing.T) {
+	s := newTestServer(t)
+	s.nonces = newNonceIssuer([]byte("0123456789abcdef"), time.Minute)
+	request := func(userAgent, nonce string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/fingerprint", nil)
+		r.Header.Set("User-Agent", userAgent)
+		if nonce != "" {
+			r.Header.Set(nonceHeader, nonce)
+		}
+		return r
+	}
+	issue := func(userAgent string, at time.Time) string {
+		_, hash, _ := s.peekFingerprint(request(userAgent, ""))
+		token, _ := s.nonces.issue(hash, at)
+		return token
+	}
+
+	valid := issue("curl/8.5.0", time.Now())
+	// Changing the first character changes the first byte of the ID
+	tampered := "A" + valid[1:]
+	if valid[0] == 'A' {
+		tampered = "B" + valid[1:]
+	}
+	tests := []struct {
+		name    string
+		nonce   string
+		want    int
+		wantErr error
+	}{
+		{name: "missing", wantErr: errNonceMissing},
+		{name: "malformed", nonce: "not-a-nonce", wantErr: errNonceInvalid},
+		{name: "tampered", nonce: tampered, wantErr: errNonceInvalid},
+		{name: "expired", nonce: issue("curl/8.5.0", time.Now().Add(-2*time.Minute)), wantErr: errNonceExpired},
+		{name: "mismatched", nonce: issue("Wget/1.21.4", time.Now()), wantErr: errNonceMismatch},
+		{name: "valid", nonce: valid, want: http.StatusOK},
+		{name: "reused", nonce: valid, wantErr: errNonceReused},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sawNonce bool
+			next := func(w http.ResponseWriter, r *http.Request) {
+				_, sawNonce = r.Header[nonceHeader]
+				w.WriteHeader(http.StatusOK)
+			}
+			w := httptest.NewRecorder()
+			s.requireNonce(next)(w, request("curl/8.5.0", tt.nonce))
+
+			if tt.wantErr == nil {
+				if w.Code != tt.want {
+					t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+				}
+				if sawNonce {
+					t.Error("handler saw the nonce header")
+				}
+				return
+			}
+			if w.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+			}
+			var resp errorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Error != tt.wantErr.Error() {
+				t.Errorf("body = %s, want error %q", w.Body, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// handleNotFound answers requests to paths no endpoint serves with a JSON
+// 404. With -fingerprint-404-probes it also logs the fingerprint of each
+// one, since scanners probing random paths are worth recognizing when they
+// come back; probes are not persisted, sent to sinks, or counted.
+func (s *server) handleNotFound(w http.ResponseWriter, r *http.Request) {
+	if s.fingerprintProbes {
+		data, hash := s.policy(r).config.FromRequest(r)
+		slog.Info("probe request",
+			"fingerprint", hash,
+			"method", r.Method,
+			"path", r.URL.Path,
+			"ip", s.redactor.IP(data.IPAddress),
+			"user_agent", s.redactor.UserAgent(data.UserAgent),
+			"bot_score", fingerprint.ScoreBot(data).Score)
+	}
+	writeJSON(w, http.StatusNotFound, errorResponse{Error: "not found"})
+}
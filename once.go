@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// onceResult is the -once -json output.
+type onceResult struct {
+	Fingerprint       string   `json:"fingerprint"`
+	StableFingerprint string   `json:"stable_fingerprint"`
+	TieredFingerprint string   `json:"tiered_fingerprint"`
+	HashAlgorithm     string   `json:"hash_algorithm"`
+	Components        []string `json:"components"`
+}
+
+// runOnce fingerprints the JSON request attributes read from in, in the
+// /compare and /batch format, and writes the fingerprint to out on a line
+// of its own, or the whole result as JSON when full is set. Unknown fields
+// and trailing data are rejected.
+func runOnce(config *fingerprint.Config, in io.Reader, out io.Writer, full bool) error {
+	dec := json.NewDecoder(in)
+	dec.DisallowUnknownFields()
+	var attrs requestAttributes
+	if err := dec.Decode(&attrs); err != nil {
+		return fmt.Errorf("invalid JSON input: %w", err)
+	}
+	if dec.More() {
+		return errors.New("invalid JSON input: unexpected data after object")
+	}
+
+	data, hash := config.FromData(attrs.data())
+	if !full {
+		_, err := fmt.Fprintln(out, hash)
+		return err
+	}
+	body, err := json.Marshal(onceResult{
+		Fingerprint:       hash,
+		StableFingerprint: config.GenerateStable(data),
+		TieredFingerprint: config.GenerateTiered(data).Hash,
+		HashAlgorithm:     config.Hash.String(),
+		Components:        config.Components(data),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s\n", body)
+	return err
+}
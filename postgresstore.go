@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS fingerprints (
+	fingerprint     TEXT PRIMARY KEY,
+	first_seen      TIMESTAMPTZ NOT NULL,
+	last_seen       TIMESTAMPTZ NOT NULL,
+	hit_count       BIGINT NOT NULL,
+	last_ip         TEXT NOT NULL,
+	last_user_agent TEXT NOT NULL
+);
+ALTER TABLE fingerprints ADD COLUMN IF NOT EXISTS ips TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE fingerprints ADD COLUMN IF NOT EXISTS user_agents TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE fingerprints ADD COLUMN IF NOT EXISTS components TEXT[] NOT NULL DEFAULT '{}'`
+
+// postgresUpsert moves the IP and User-Agent of the sighting to the front
+// of the recent lists and truncates them to $5 entries in the same
+// statement, so concurrent sightings cannot lose each other's values. The
+// components, $6, replace those of the previous sighting.
+const postgresUpsert = `
+INSERT INTO fingerprints (fingerprint, first_seen, last_seen, hit_count, last_ip, last_user_agent, ips, user_agents, components)
+VALUES ($1, $2, $2, 1, $3, $4, array_remove(ARRAY[$3::text], ''), array_remove(ARRAY[$4::text], ''), $6)
+ON CONFLICT (fingerprint) DO UPDATE SET
+	last_seen = excluded.last_seen,
+	hit_count = fingerprints.hit_count + 1,
+	last_ip = excluded.last_ip,
+	last_user_agent = excluded.last_user_agent,
+	ips = CASE WHEN excluded.last_ip = '' THEN fingerprints.ips
+		ELSE (array_prepend(excluded.last_ip, array_remove(fingerprints.ips, excluded.last_ip)))[1:$5] END,
+	user_agents = CASE WHEN excluded.last_user_agent = '' THEN fingerprints.user_agents
+		ELSE (array_prepend(excluded.last_user_agent, array_remove(fingerprints.user_agents, excluded.last_user_agent)))[1:$5] END,
+	components = excluded.components
+RETURNING hit_count, first_seen, last_seen, last_ip, last_user_agent, ips, user_agents, components`
+
+const postgresGet = `
+SELECT hit_count, first_seen, last_seen, last_ip, last_user_agent, ips, user_agents, components
+FROM fingerprints WHERE fingerprint = $1`
+
+// postgresStore persists fingerprints to a PostgreSQL table, which several
+// instances can share. The upsert is a single statement, so concurrent
+// sightings of one fingerprint are all counted.
+type postgresStore struct {
+	pool *pgxpool.Pool
+}
+
+func openPostgresStore(ctx context.Context, dsn string) (*postgresStore, error) {
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open postgres: %w", err)
+	}
+	if _, err := pool.Exec(ctx, postgresSchema); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("create postgres schema: %w", err)
+	}
+	return &postgresStore{pool: pool}, nil
+}
+
+func (s *postgresStore) Upsert(ctx context.Context, fp string, meta Sighting) (Visit, error) {
+	row := s.pool.QueryRow(ctx, postgresUpsert, fp, meta.Seen, meta.IP, meta.UserAgent, maxVisitValues, postgresList(meta.Components))
+	v, err := scanPostgresVisit(row)
+	if err != nil {
+		return Visit{}, fmt.Errorf("record fingerprint: %w", err)
+	}
+	return v, nil
+}
+
+func (s *postgresStore) Get(ctx context.Context, fp string) (Visit, error) {
+	v, err := scanPostgresVisit(s.pool.QueryRow(ctx, postgresGet, fp))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Visit{}, errNotFound
+	}
+	if err != nil {
+		return Visit{}, fmt.Errorf("get fingerprint: %w", err)
+	}
+	return v, nil
+}
+
+func (s *postgresStore) Count(ctx context.Context) (int64, error) {
+	var n int64
+	if err := s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM fingerprints").Scan(&n); err != nil {
+		return 0, fmt.Errorf("count fingerprints: %w", err)
+	}
+	return n, nil
+}
+
+func scanPostgresVisit(row pgx.Row) (Visit, error) {
+	var v Visit
+	err := row.Scan(&v.HitCount, &v.FirstSeen, &v.LastSeen, &v.LastIP, &v.LastUserAgent, &v.IPs, &v.UserAgents, &v.Components)
+	return v, err
+}
+
+// postgresList returns values as a TEXT[] parameter, which must not be
+// NULL.
+func postgresList(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func (s *postgresStore) Ping(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
+
+func (s *postgresStore) Close() error {
+	s.pool.Close()
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// newPprofServer returns the -pprof server, which serves the net/http/pprof
+// profiling endpoints under /debug/pprof/ and nothing else. Profiles
+// expose the command line, memory contents such as recent request headers
+// in heap samples, and a CPU-bound endpoint any caller can start, so the
+// server has no authentication by design and belongs on a loopback or
+// otherwise private address. It shares main's header limits, but not its
+// write timeout, which would cut off ?seconds= profiles and traces.
+func newPprofServer(main *http.Server) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: main.ReadHeaderTimeout,
+		ReadTimeout:       main.ReadTimeout,
+		IdleTimeout:       main.IdleTimeout,
+		MaxHeaderBytes:    main.MaxHeaderBytes,
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// handlePreview returns the fingerprint and components a /fingerprint
+// request would produce, without any side effects: nothing is logged,
+// sent to sinks, persisted, counted in metrics or /stats, or rate limited,
+// and no visitor cookie is set. The stored hit count of a fingerprint seen
+// before is returned as is. An existing visitor cookie and the nonce
+// header are left out of the hash as /fingerprint does.
+func (s *server) handlePreview(w http.ResponseWriter, r *http.Request) {
+	var visitor string
+	if s.cookie {
+		if id, isNew := visitorID(r); !isNew {
+			visitor = id
+		}
+		r = stripVisitorCookie(r)
+	}
+	r = stripNonceHeader(r)
+	if s.churn != nil {
+		r = stripHeader(r, churnKeyHeader)
+	}
+
+	// The hash cache keeps hit and miss counts, so it is bypassed too
+	config := *s.policy(r).config
+	config.Cache = nil
+	data, hash := config.FromRequest(r)
+
+	resp := s.describe(&config, r, data, hash, s.now(), selectFields(w, r))
+	resp.VisitorID, resp.Preview = visitor, true
+	resp.Components = config.Components(data)
+	if s.clusters != nil {
+		resp.ClusterID = s.clusters.Lookup(hash, resp.Components)
+	}
+	stored := hash
+	if s.stability != nil {
+		key := s.clientKey.key(client{ip: data.IPAddress, userAgent: data.UserAgent, fingerprint: func() string { return hash }})
+		stored = s.stability.Lookup(key, hash, resp.Components, s.now())
+		resp.CanonicalFingerprint = stored
+	}
+	if s.store != nil {
+		v, err := s.store.Get(r.Context(), stored)
+		switch {
+		case err == nil:
+			resp.HitCount = v.HitCount
+			resp.FirstSeen = v.FirstSeen.Format(time.RFC3339)
+		case !errors.Is(err, errNotFound):
+			slog.Error("failed to look up fingerprint", "fingerprint", stored, "error", err)
+		}
+	}
+	if isDebug(r) {
+		_, resp.IPChain = s.config.ClientIPChain(r)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
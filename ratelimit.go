@@ -0,0 +1,95 @@
+package main
+
+import (
+	"container/list"
+	"math"
+	"sync"
+	"time"
+)
+
+// maxRateLimitKeys bounds the number of clients tracked at once so a spray
+// of distinct IPs cannot grow the limiter without limit.
+const maxRateLimitKeys = 100000
+
+// rateLimiter is a token-bucket rate limiter keyed by client. Each client
+// may make burst requests at once and is refilled at rate requests per
+// second.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	// order holds the buckets from most to least recently used
+	order *list.List
+}
+
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Allow reports whether a request from key may proceed at now. When it may
+// not, the returned duration is how long until a token becomes available.
+// A new client at capacity takes the place of the least recently used
+// one, the closest to refilled, so clients still spending their burst
+// keep their buckets.
+func (l *rateLimiter) Allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.evict(now)
+
+	var b *bucket
+	if elem, ok := l.buckets[key]; ok {
+		l.order.MoveToFront(elem)
+		b = elem.Value.(*bucket)
+	} else {
+		if len(l.buckets) >= maxRateLimitKeys {
+			l.remove(l.order.Back())
+		}
+		b = &bucket{key: key, tokens: l.burst, last: now}
+		l.buckets[key] = l.order.PushFront(b)
+	}
+
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// evict drops buckets that have been idle long enough to refill
+// completely, since they are indistinguishable from new clients. The
+// buckets are kept in order of use, so it stops at the first bucket still
+// refilling.
+func (l *rateLimiter) evict(now time.Time) {
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	for elem := l.order.Back(); elem != nil; elem = l.order.Back() {
+		if now.Sub(elem.Value.(*bucket).last) < refill {
+			break
+		}
+		l.remove(elem)
+	}
+}
+
+func (l *rateLimiter) remove(elem *list.Element) {
+	l.order.Remove(elem)
+	delete(l.buckets, elem.Value.(*bucket).key)
+}
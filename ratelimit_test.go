@@ -0,0 +1,145 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		at       time.Duration
+		key      string
+		allowed  bool
+		wantWait time.Duration
+	}{
+		{name: "first of burst", key: "a", allowed: true},
+		{name: "second of burst", key: "a", allowed: true},
+		{name: "burst spent", key: "a", wantWait: 500 * time.Millisecond},
+		{name: "other client", key: "b", allowed: true},
+		{name: "refilled one token", at: 500 * time.Millisecond, key: "a", allowed: true},
+		{name: "spent again", at: 500 * time.Millisecond, key: "a", wantWait: 500 * time.Millisecond},
+	}
+	l := newRateLimiter(2, 2)
+	for _, tt := range tests {
+		allowed, wait := l.Allow(tt.key, start.Add(tt.at))
+		if allowed != tt.allowed || wait != tt.wantWait {
+			t.Errorf("%s: Allow = %v, %v; want %v, %v", tt.name, allowed, wait, tt.allowed, tt.wantWait)
+		}
+	}
+}
+
+// TestRateLimitHammer sends many requests from one IP and checks that only
+// the burst gets through, while other IPs are unaffected.
+func TestRateLimitHammer(t *testing.T) {
+	const burst = 5
+	s := newTestServer(t)
+	s.routes.fallback.limiter = newRateLimiter(0.001, burst)
+	s.metrics = newMetrics(prometheus.NewRegistry(), true, nil, nil, 1, false)
+	handler := s.rateLimit(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	send := func(remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/fingerprint", nil)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	for i := range 50 {
+		// Each request comes from a new source port of the same IP
+		w := send("203.0.113.7:" + strconv.Itoa(40000+i))
+		if i < burst {
+			if w.Code != http.StatusOK {
+				t.Fatalf("request %d: status %d within the burst", i, w.Code)
+			}
+			continue
+		}
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("request %d: status %d after the burst, want 429", i, w.Code)
+		}
+		if retry := w.Header().Get("Retry-After"); retry == "" || retry == "0" {
+			t.Errorf("request %d: Retry-After = %q", i, retry)
+		}
+	}
+
+	if w := send("198.51.100.1:40000"); w.Code != http.StatusOK {
+		t.Errorf("other client: status %d, want 200", w.Code)
+	}
+}
+
+// TestRateLimiterEvictsLeastRecentlyUsed checks that a limiter at capacity
+// makes room by dropping the longest idle client, never one that is
+// still spending its burst.
+func TestRateLimiterEvictsLeastRecentlyUsed(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newRateLimiter(0.001, 1)
+
+	// The active client is the first added, so eviction in insertion or
+	// map order would be likely to drop it
+	l.Allow("active", start)
+	for i := range maxRateLimitKeys - 1 {
+		l.Allow(strconv.Itoa(i), start.Add(time.Millisecond))
+	}
+	if allowed, _ := l.Allow("active", start.Add(2*time.Millisecond)); allowed {
+		t.Fatal("active client allowed past its burst")
+	}
+
+	l.Allow("new", start.Add(3*time.Millisecond))
+	if len(l.buckets) != maxRateLimitKeys {
+		t.Errorf("tracking %d clients, want %d", len(l.buckets), maxRateLimitKeys)
+	}
+	if _, ok := l.buckets["0"]; ok {
+		t.Error("least recently used client was kept")
+	}
+	if allowed, _ := l.Allow("active", start.Add(4*time.Millisecond)); allowed {
+		t.Error("eviction reset the bucket of an active client")
+	}
+}
+
+func TestRateLimiterEvictsRefilled(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newRateLimiter(1, 2)
+	l.Allow("idle", start)
+	l.Allow("recent", start.Add(time.Second))
+	l.Allow("now", start.Add(2*time.Second))
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("refilled bucket was kept")
+	}
+	if _, ok := l.buckets["recent"]; !ok {
+		t.Error("bucket still refilling was dropped")
+	}
+}
+
+// TestRateLimiterConcurrent checks that concurrent requests from one
+// client never get more than its burst between them. Run it with -race.
+func TestRateLimiterConcurrent(t *testing.T) {
+	const burst = 50
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newRateLimiter(0.001, burst)
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Go(func() {
+			for i := range 100 {
+				if ok, _ := l.Allow("shared", now); ok {
+					allowed.Add(1)
+				}
+				l.Allow(strconv.Itoa(g*100+i), now)
+			}
+		})
+	}
+	wg.Wait()
+	if got := allowed.Load(); got != burst {
+		t.Errorf("allowed %d requests, want the burst of %d", got, burst)
+	}
+}
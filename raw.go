@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// maxRawBody bounds the size of a /raw request body, at the largest
+// request head net/http reads by default.
+const maxRawBody = http.DefaultMaxHeaderBytes
+
+// handleRaw fingerprints a raw HTTP/1.x request posted as the body, such
+// as a request head saved for forensics. The request is parsed with
+// http.ReadRequest and fingerprinted under the route policy of its own
+// path, with its header order as written, and the response is that of
+// /preview: nothing is logged, persisted, or counted. A remote_addr query
+// parameter sets the address the request came from, which a dump does not
+// record.
+func (s *server) handleRaw(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+
+	raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRawBody))
+	if err != nil {
+		var maxBytes *http.MaxBytesError
+		if errors.As(err, &maxBytes) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, errorResponse{Error: fmt.Sprintf("request body exceeds %d bytes", maxBytes.Limit)})
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	req, err := fingerprint.ReadRawRequest(raw)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid raw request: " + err.Error()})
+		return
+	}
+	if addr := r.URL.Query().Get("remote_addr"); addr != "" {
+		if req.RemoteAddr, err = rawRemoteAddr(addr); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+	}
+	req = req.WithContext(fingerprint.WithHeaderOrder(r.Context(), fingerprint.HeaderOrderFromContext(req.Context())))
+
+	if s.cookie {
+		req = stripVisitorCookie(req)
+	}
+	req = stripNonceHeader(req)
+	if s.churn != nil {
+		req = stripHeader(req, churnKeyHeader)
+	}
+
+	config := *s.policy(req).config
+	config.Cache = nil
+	data, hash := config.FromRequest(req)
+
+	resp := s.describe(&config, req, data, hash, s.now(), selectFields(w, r))
+	resp.Components = config.Components(data)
+	if isDebug(r) {
+		_, resp.IPChain = config.ClientIPChain(req)
+		resp.IPHeaders = fingerprint.CheckIPHeaders(req.Header).Values
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// rawRemoteAddr returns the remote_addr of a /raw request as a host:port
+// RemoteAddr, accepting a bare IP address.
+func rawRemoteAddr(addr string) (string, error) {
+	if ip, err := netip.ParseAddr(addr); err == nil {
+		return net.JoinHostPort(ip.String(), "0"), nil
+	}
+	if _, err := netip.ParseAddrPort(addr); err != nil {
+		return "", fmt.Errorf("invalid remote_addr %q", addr)
+	}
+	return addr, nil
+}
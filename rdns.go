@@ -0,0 +1,169 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// crawler is a search engine crawler, identified by the domain of the
+// hosts it crawls from.
+type crawler struct {
+	domain string
+	name   string
+}
+
+// crawlers are the known search engine crawlers. A client whose
+// forward-confirmed PTR record is one of their domains, or a subdomain,
+// is a verified bot.
+var crawlers = []crawler{
+	{"googlebot.com", "Googlebot"},
+	{"google.com", "Google"}, // Google special-case crawlers
+	{"search.msn.com", "Bingbot"},
+	{"crawl.yahoo.net", "Slurp"},
+	{"applebot.apple.com", "Applebot"},
+	{"yandex.ru", "YandexBot"},
+	{"yandex.net", "YandexBot"},
+	{"yandex.com", "YandexBot"},
+	{"baidu.com", "Baiduspider"},
+	{"baidu.jp", "Baiduspider"},
+	{"crawl.amazonbot.amazon", "Amazonbot"},
+}
+
+// resolver is the subset of *net.Resolver used for reverse DNS, so tests
+// can substitute a fake.
+type resolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// rdnsResult is the reverse DNS enrichment of a client IP.
+type rdnsResult struct {
+	// Hostname is the PTR record, preferring one that resolves back to
+	// the IP, without the trailing dot
+	Hostname string
+	// Confirmed is set when Hostname resolves back to the IP
+	Confirmed bool
+}
+
+// VerifiedBot reports whether the IP belongs to a known crawler, as
+// confirmed by forward-confirmed reverse DNS.
+func (r rdnsResult) VerifiedBot() bool {
+	return r.Crawler() != ""
+}
+
+// Crawler returns the name of the known crawler the IP belongs to, as
+// confirmed by forward-confirmed reverse DNS, or "" for other IPs.
+func (r rdnsResult) Crawler() string {
+	if !r.Confirmed {
+		return ""
+	}
+	host := strings.ToLower(r.Hostname)
+	for _, c := range crawlers {
+		if host == c.domain || strings.HasSuffix(host, "."+c.domain) {
+			return c.name
+		}
+	}
+	return ""
+}
+
+// reverseDNS looks up the PTR records of client IPs. Results, including
+// failures, are cached for ttl in a bounded LRU cache, and each lookup is
+// bounded by timeout, so a slow or unreachable resolver delays a request
+// at most once per IP and ttl. It is safe for concurrent use.
+type reverseDNS struct {
+	resolver resolver
+	timeout  time.Duration
+	ttl      time.Duration
+	size     int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type rdnsEntry struct {
+	ip      string
+	result  rdnsResult
+	expires time.Time
+}
+
+func newReverseDNS(r resolver, timeout, ttl time.Duration, size int) *reverseDNS {
+	return &reverseDNS{
+		resolver: r,
+		timeout:  timeout,
+		ttl:      ttl,
+		size:     size,
+		entries:  make(map[string]*list.Element, size),
+		order:    list.New(),
+	}
+}
+
+// Lookup returns the reverse DNS result for ip, from the cache when it has
+// not expired at now. A nil *reverseDNS returns an empty result.
+func (d *reverseDNS) Lookup(ctx context.Context, ip string, now time.Time) rdnsResult {
+	if d == nil {
+		return rdnsResult{}
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return rdnsResult{}
+	}
+
+	d.mu.Lock()
+	if elem, ok := d.entries[ip]; ok {
+		if entry := elem.Value.(*rdnsEntry); now.Before(entry.expires) {
+			d.order.MoveToFront(elem)
+			d.mu.Unlock()
+			return entry.result
+		}
+	}
+	d.mu.Unlock()
+
+	// A client hanging up must not cache a failed lookup for everyone
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.timeout)
+	defer cancel()
+	result := d.resolve(ctx, addr)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	entry := &rdnsEntry{ip: ip, result: result, expires: now.Add(d.ttl)}
+	if elem, ok := d.entries[ip]; ok {
+		elem.Value = entry
+		d.order.MoveToFront(elem)
+		return result
+	}
+	d.entries[ip] = d.order.PushFront(entry)
+	if d.order.Len() > d.size {
+		oldest := d.order.Back()
+		d.order.Remove(oldest)
+		delete(d.entries, oldest.Value.(*rdnsEntry).ip)
+	}
+	return result
+}
+
+// resolve looks up the PTR records of addr and forward-confirms them.
+func (d *reverseDNS) resolve(ctx context.Context, addr netip.Addr) rdnsResult {
+	names, err := d.resolver.LookupAddr(ctx, addr.String())
+	if err != nil || len(names) == 0 {
+		return rdnsResult{}
+	}
+
+	result := rdnsResult{Hostname: strings.TrimSuffix(names[0], ".")}
+	for _, name := range names {
+		ips, err := d.resolver.LookupIPAddr(ctx, name)
+		if err != nil {
+			continue
+		}
+		for _, ip := range ips {
+			if forward, ok := netip.AddrFromSlice(ip.IP); ok && forward.Unmap() == addr.Unmap() {
+				return rdnsResult{Hostname: strings.TrimSuffix(name, "."), Confirmed: true}
+			}
+		}
+	}
+	return result
+}
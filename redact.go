@@ -0,0 +1,221 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// redacted replaces values that are dropped entirely.
+const redacted = "[redacted]"
+
+// Prefix lengths kept by the truncate IP policy: the last octet of an
+// IPv4 address and the last 80 bits of an IPv6 address are zeroed.
+const (
+	truncateIPv4Bits = 24
+	truncateIPv6Bits = 48
+)
+
+// ipPolicies and uaPolicies list the -redact-ip and -redact-ua policies.
+var (
+	ipPolicies = []string{"keep", "truncate", "hash", "drop"}
+	uaPolicies = []string{"keep", "hash", "drop"}
+)
+
+// ipHeaders are the lower-cased names of the headers that carry lists of
+// client IPs, which the IP policy is applied to element by element.
+var ipHeaders = func() map[string]bool {
+	names := make(map[string]bool)
+	for _, name := range fingerprint.AddressListHeaders() {
+		names[strings.ToLower(name)] = true
+	}
+	return names
+}()
+
+// redactor rewrites the personal data in a fingerprint before it is
+// logged, persisted, or passed to sinks. Fingerprints are always computed
+// from the unredacted data, so redaction never changes a hash. A nil
+// *redactor keeps everything.
+type redactor struct {
+	ip func(string) string
+	ua func(string) string
+
+	// headers are the lower-cased names of headers whose values are
+	// replaced with redacted
+	headers map[string]bool
+}
+
+// newRedactor returns a redactor applying the named IP and User-Agent
+// policies and dropping the values of headers. key keys the hash policy,
+// so hashed values cannot be reversed by hashing every IP or common
+// User-Agent.
+func newRedactor(ipPolicy, uaPolicy string, headers []string, key []byte) (*redactor, error) {
+	hash := func(value string) string {
+		if value == "" {
+			return ""
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(value))
+		return hex.EncodeToString(mac.Sum(nil)[:16])
+	}
+	drop := func(value string) string {
+		if value == "" {
+			return ""
+		}
+		return redacted
+	}
+
+	r := &redactor{headers: make(map[string]bool, len(headers))}
+	switch ipPolicy {
+	case "keep":
+	case "truncate":
+		r.ip = truncateIP
+	case "hash":
+		r.ip = hash
+	case "drop":
+		r.ip = drop
+	default:
+		return nil, fmt.Errorf("unknown IP redaction policy %q, expected one of %v", ipPolicy, ipPolicies)
+	}
+	switch uaPolicy {
+	case "keep":
+	case "hash":
+		r.ua = hash
+	case "drop":
+		r.ua = drop
+	default:
+		return nil, fmt.Errorf("unknown User-Agent redaction policy %q, expected one of %v", uaPolicy, uaPolicies)
+	}
+	for _, name := range headers {
+		if name = strings.TrimSpace(name); name != "" {
+			r.headers[strings.ToLower(name)] = true
+		}
+	}
+	return r, nil
+}
+
+// truncateIP zeroes the host bits of ip beyond truncateIPv4Bits or
+// truncateIPv6Bits. A value that is not an IP is dropped.
+func truncateIP(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return redacted
+	}
+	addr = addr.Unmap().WithZone("")
+	bits := truncateIPv6Bits
+	if addr.Is4() {
+		bits = truncateIPv4Bits
+	}
+	prefix, _ := addr.Prefix(bits)
+	return prefix.Addr().String()
+}
+
+// IP returns ip redacted by the IP policy.
+func (r *redactor) IP(ip string) string {
+	if r == nil || r.ip == nil {
+		return ip
+	}
+	return r.ip(ip)
+}
+
+// UserAgent returns ua redacted by the User-Agent policy.
+func (r *redactor) UserAgent(ua string) string {
+	if r == nil || r.ua == nil {
+		return ua
+	}
+	return r.ua(ua)
+}
+
+// ipList applies the IP policy to each address of a comma-separated list
+// such as X-Forwarded-For, keeping the port of an address that has one.
+func (r *redactor) ipList(list string) string {
+	elements := strings.Split(list, ",")
+	for i, element := range elements {
+		element = strings.TrimSpace(element)
+		if addrPort, err := netip.ParseAddrPort(element); err == nil {
+			elements[i] = net.JoinHostPort(r.IP(addrPort.Addr().String()), strconv.Itoa(int(addrPort.Port())))
+			continue
+		}
+		elements[i] = r.IP(element)
+	}
+	return strings.Join(elements, ", ")
+}
+
+// via applies the IP policy to the received-by host of each element of a
+// Via header, such as "1.1 203.0.113.7:8080 (squid)", and drops the
+// comments, which are free text. Pseudonyms such as "1.1 edge-1" are kept.
+func (r *redactor) via(value string) string {
+	elements := strings.Split(value, ",")
+	for i, element := range elements {
+		fields := strings.Fields(element)
+		if len(fields) < 2 {
+			elements[i] = strings.Join(fields, " ")
+			continue
+		}
+		host := fields[1]
+		if addrPort, err := netip.ParseAddrPort(host); err == nil {
+			host = net.JoinHostPort(r.IP(addrPort.Addr().String()), strconv.Itoa(int(addrPort.Port())))
+		} else if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+			host = r.IP(addr.String())
+		}
+		elements[i] = fields[0] + " " + host
+	}
+	return strings.Join(elements, ", ")
+}
+
+// Data returns a copy of data with the policies applied to the client IP,
+// the User-Agent, the headers carrying them, and the dropped headers. The
+// headers map is copied, so data itself is left intact.
+func (r *redactor) Data(data fingerprint.Data) fingerprint.Data {
+	if r == nil {
+		return data
+	}
+	data.IPAddress = r.IP(data.IPAddress)
+	data.UserAgent = r.UserAgent(data.UserAgent)
+	if r.ip != nil {
+		data.RemoteAddr = r.ipList(data.RemoteAddr)
+		data.XForwardedFor = r.ipList(data.XForwardedFor)
+		data.XRealIP = r.ipList(data.XRealIP)
+	}
+	if len(data.Headers) == 0 {
+		return data
+	}
+
+	data.Headers = maps.Clone(data.Headers)
+	for name, value := range data.Headers {
+		switch {
+		case r.headers[name]:
+			data.Headers[name] = redacted
+		case name == "user-agent":
+			data.Headers[name] = r.UserAgent(value)
+		case r.ip != nil && ipHeaders[name]:
+			data.Headers[name] = r.ipList(value)
+		case r.ip != nil && name == "via":
+			data.Headers[name] = r.via(value)
+		case r.ip != nil && name == "forwarded":
+			// Forwarded mixes addresses with other parameters, so it is
+			// dropped rather than parsed
+			data.Headers[name] = redacted
+		}
+	}
+	return data
+}
+
+// sighting returns the store record of a request, redacted, with the
+// components that components derives from the redacted data.
+func (s *server) sighting(data fingerprint.Data, seen time.Time, components func(fingerprint.Data) []string) Sighting {
+	data = s.redactor.Data(data)
+	return Sighting{IP: data.IPAddress, UserAgent: data.UserAgent, Seen: seen, Components: components(data)}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// clientAddr is the address the redaction tests expect never to see once
+// the IP policy is applied.
+const clientAddr = "198.51.100.77"
+
+func TestRedactIPHeaders(t *testing.T) {
+	headers := map[string]string{
+		"X-Forwarded-For":          clientAddr + ", 10.0.0.1",
+		"X-Original-Forwarded-For": clientAddr,
+		"X-Real-IP":                clientAddr,
+		"True-Client-IP":           clientAddr,
+		"CF-Connecting-IP":         clientAddr,
+		"X-Client-IP":              clientAddr,
+		"X-Cluster-Client-IP":      clientAddr,
+		"Forwarded":                "for=" + clientAddr,
+		"Via":                      "1.1 " + clientAddr + ":3128 (squid " + clientAddr + "), 1.1 edge-1",
+	}
+	config := &fingerprint.Config{Headers: append(fingerprint.DefaultHeaders(), "X-Forwarded-For", "X-Real-IP")}
+	for _, policy := range []string{"truncate", "hash", "drop"} {
+		r, err := newRedactor(policy, "keep", nil, []byte("key"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := &server{redactor: r}
+		for name, value := range headers {
+			t.Run(policy+"/"+name, func(t *testing.T) {
+				req, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+				req.RemoteAddr = clientAddr + ":5000"
+				req.Header.Set(name, value)
+				data, _ := config.FromRequest(req)
+				if data.Headers[strings.ToLower(name)] == "" {
+					t.Fatalf("%s is not extracted", name)
+				}
+
+				sighting := s.sighting(data, time.Now(), config.Components)
+				if strings.Contains(sighting.IP, clientAddr) {
+					t.Errorf("sighting IP = %q", sighting.IP)
+				}
+				for _, component := range sighting.Components {
+					if strings.Contains(component, clientAddr) {
+						t.Errorf("component %q keeps the client IP", component)
+					}
+				}
+				redacted := r.Data(data)
+				for _, field := range []string{redacted.RemoteAddr, redacted.XForwardedFor, redacted.XRealIP} {
+					if strings.Contains(field, clientAddr) {
+						t.Errorf("redacted data keeps the client IP in %q", field)
+					}
+				}
+			})
+		}
+	}
+}
+
+// TestIPHeadersCoverDefaults fails when a header that carries addresses
+// joins the default header list without the IP policy covering it.
+func TestIPHeadersCoverDefaults(t *testing.T) {
+	for _, name := range fingerprint.DefaultHeaders() {
+		lower := strings.ToLower(name)
+		carriesIP := strings.HasSuffix(lower, "-ip") || strings.Contains(lower, "forwarded-for")
+		if carriesIP && !ipHeaders[lower] {
+			t.Errorf("default header %s carries IPs but is not redacted", name)
+		}
+	}
+	for name := range ipHeaders {
+		if name != strings.ToLower(name) {
+			t.Errorf("ipHeaders has %q, which is not lower-cased", name)
+		}
+	}
+}
+
+func TestRedactorPolicies(t *testing.T) {
+	tests := []struct {
+		policy string
+		ip     string
+		want   string
+	}{
+		{"keep", "203.0.113.7", "203.0.113.7"},
+		{"truncate", "203.0.113.7", "203.0.113.0"},
+		{"truncate", "2001:db8:1:2::7", "2001:db8:1::"},
+		{"truncate", "not-an-ip", redacted},
+		{"drop", "203.0.113.7", redacted},
+		{"drop", "", ""},
+	}
+	for _, tt := range tests {
+		r, err := newRedactor(tt.policy, "keep", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := r.IP(tt.ip); got != tt.want {
+			t.Errorf("%s: IP(%q) = %q, want %q", tt.policy, tt.ip, got, tt.want)
+		}
+	}
+
+	r, _ := newRedactor("hash", "hash", []string{"Authorization"}, []byte("key"))
+	if a, b := r.IP("203.0.113.7"), r.IP("203.0.113.7"); a != b || a == "203.0.113.7" || len(a) != 32 {
+		t.Errorf("hashed IP = %q then %q", a, b)
+	}
+	data := r.Data(fingerprint.Data{UserAgent: "curl/8.4.0", Headers: map[string]string{"authorization": "Bearer secret", "user-agent": "curl/8.4.0"}})
+	if data.Headers["authorization"] != redacted {
+		t.Errorf("authorization = %q, want %q", data.Headers["authorization"], redacted)
+	}
+	if data.UserAgent == "curl/8.4.0" || data.Headers["user-agent"] != data.UserAgent {
+		t.Errorf("User-Agent = %q, header %q", data.UserAgent, data.Headers["user-agent"])
+	}
+	if _, err := newRedactor("mask", "keep", nil, nil); err == nil {
+		t.Error("newRedactor accepted an unknown IP policy")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// responseHeaderNames maps the -response-headers fields to the /fingerprint
+// response headers they are copied to.
+var responseHeaderNames = map[string]string{
+	"fingerprint":        "X-Fingerprint",
+	"stable_fingerprint": "X-Fingerprint-Stable",
+	"bot_score":          "X-Bot-Score",
+}
+
+// hashValue matches a versioned hex fingerprint, the only form copied into
+// a response header.
+var hashValue = regexp.MustCompile(`^v[0-9]+:[0-9a-f]+$`)
+
+// parseResponseHeaders parses the comma-separated -response-headers list.
+func parseResponseHeaders(list string) ([]string, error) {
+	var fields []string
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if _, ok := responseHeaderNames[field]; !ok {
+			return nil, fmt.Errorf("unknown response header field %q, expected one of %v", field, slices.Sorted(maps.Keys(responseHeaderNames)))
+		}
+		if !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// setResponseHeaders copies the -response-headers fields of resp into
+// response headers on w. A hash that is not a versioned hex digest is
+// left out rather than risk an unsafe header value.
+func (s *server) setResponseHeaders(w http.ResponseWriter, resp fingerprintResponse) {
+	for _, field := range s.responseHeaders {
+		var value string
+		switch field {
+		case "fingerprint":
+			value = resp.Fingerprint
+		case "stable_fingerprint":
+			value = resp.StableFingerprint
+		case "bot_score":
+			w.Header().Set(responseHeaderNames[field], strconv.Itoa(resp.BotScore))
+			continue
+		}
+		if hashValue.MatchString(value) {
+			w.Header().Set(responseHeaderNames[field], value)
+		}
+	}
+}
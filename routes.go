@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// routesConfig is the -routes file format, in YAML or JSON. Routes are
+// tried in order and the first that matches the request path applies;
+// paths no route matches use the policy the flags configure.
+//
+//	routes:
+//	  - prefix: /fingerprint/login
+//	    signals: {cookie-names: true}
+//	    enforce_list: true
+//	    rate_limit: 0.5
+//	    burst: 3
+//	  - regex: ^/fingerprint/(blog|docs)/
+//	    enforce_list: false
+//	    rate_limit: 0
+type routesConfig struct {
+	Routes []routeConfig `yaml:"routes"`
+}
+
+// routeConfig is one route of a routesConfig. Fields left out inherit the
+// value the flags configure.
+type routeConfig struct {
+	Prefix string `yaml:"prefix"`
+	Regex  string `yaml:"regex"`
+
+	// Signals turns the optional signals in routeSignals on or off
+	Signals map[string]bool `yaml:"signals"`
+	// Headers replaces the fingerprinted header list
+	Headers []string `yaml:"headers"`
+
+	EnforceList *bool `yaml:"enforce_list"`
+	// RateLimit is in requests per second per client, 0 for none
+	RateLimit *float64 `yaml:"rate_limit"`
+	Burst     *int     `yaml:"burst"`
+}
+
+// routeSignals maps the signal names of a route to the Config fields they
+// set. ip is on unless -no-ip is set; the others are off unless their
+// flag is set.
+var routeSignals = map[string]func(c *fingerprint.Config, on bool){
+	"ip":           func(c *fingerprint.Config, on bool) { c.ExcludeIP = !on },
+	"body":         func(c *fingerprint.Config, on bool) { c.BodySignals = on },
+	"sec-fetch":    func(c *fingerprint.Config, on bool) { c.SecFetchSignal = on },
+	"path":         func(c *fingerprint.Config, on bool) { c.IncludePath = on },
+	"query-keys":   func(c *fingerprint.Config, on bool) { c.IncludeQueryKeys = on },
+	"cookie-names": func(c *fingerprint.Config, on bool) { c.IncludeCookieNames = on },
+	"transport":    func(c *fingerprint.Config, on bool) { c.TransportSignal = on },
+	"tls-order":    func(c *fingerprint.Config, on bool) { c.TLSOrder = on },
+}
+
+// routePolicy is how requests to a route are fingerprinted, checked, and
+// limited.
+type routePolicy struct {
+	// name identifies the route in logs, "default" for the fallback
+	name   string
+	config *fingerprint.Config
+	// enforceList applies the denylist or allowlist, if one is loaded
+	enforceList bool
+	// limiter is nil when the route is not rate limited. Routes that
+	// inherit the rate limit share the default limiter's buckets.
+	limiter *rateLimiter
+}
+
+type route struct {
+	prefix string
+	regex  *regexp.Regexp
+	policy *routePolicy
+}
+
+func (rt route) matches(path string) bool {
+	if rt.regex != nil {
+		return rt.regex.MatchString(path)
+	}
+	return strings.HasPrefix(path, rt.prefix)
+}
+
+// routeTable picks the policy of each request from its path.
+type routeTable struct {
+	routes   []route
+	fallback *routePolicy
+}
+
+// match returns the policy of the first route matching path, or the
+// fallback.
+func (t *routeTable) match(path string) *routePolicy {
+	for _, rt := range t.routes {
+		if rt.matches(path) {
+			return rt.policy
+		}
+	}
+	return t.fallback
+}
+
+// rateLimited reports whether any policy rate limits requests.
+func (t *routeTable) rateLimited() bool {
+	return slices.ContainsFunc(t.routes, func(rt route) bool { return rt.policy.limiter != nil }) ||
+		t.fallback.limiter != nil
+}
+
+// enforcesList reports whether any policy applies the fingerprint list.
+func (t *routeTable) enforcesList() bool {
+	return slices.ContainsFunc(t.routes, func(rt route) bool { return rt.policy.enforceList }) ||
+		t.fallback.enforceList
+}
+
+// loadRoutes reads the -routes file at path. Routes inherit what they
+// leave out from fallback, and a route that sets rate_limit without burst
+// gets burst. listed says whether a denylist or allowlist is loaded for
+// enforce_list to apply.
+func loadRoutes(path string, fallback *routePolicy, burst int, listed bool) (*routeTable, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read routes: %w", err)
+	}
+
+	var cfg routesConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(raw))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("parse routes %s: %w", path, err)
+	}
+	if len(cfg.Routes) == 0 {
+		return nil, fmt.Errorf("routes %s: no routes", path)
+	}
+
+	t := &routeTable{fallback: fallback}
+	for i, rc := range cfg.Routes {
+		rt, err := rc.route(fallback, burst, listed)
+		if err != nil {
+			return nil, fmt.Errorf("routes %s: route %d: %w", path, i+1, err)
+		}
+		t.routes = append(t.routes, rt)
+	}
+	return t, nil
+}
+
+func (rc routeConfig) route(fallback *routePolicy, burst int, listed bool) (route, error) {
+	var rt route
+	switch {
+	case rc.Prefix != "" && rc.Regex != "":
+		return route{}, errors.New("prefix and regex are mutually exclusive")
+	case rc.Prefix != "":
+		if !strings.HasPrefix(rc.Prefix, "/") {
+			return route{}, fmt.Errorf("prefix %q does not start with /", rc.Prefix)
+		}
+		rt.prefix = rc.Prefix
+	case rc.Regex != "":
+		re, err := regexp.Compile(rc.Regex)
+		if err != nil {
+			return route{}, fmt.Errorf("invalid regex: %w", err)
+		}
+		rt.regex = re
+	default:
+		return route{}, errors.New("needs a prefix or regex")
+	}
+
+	config := *fallback.config
+	for name, on := range rc.Signals {
+		set, ok := routeSignals[name]
+		if !ok {
+			return route{}, fmt.Errorf("unknown signal %q", name)
+		}
+		set(&config, on)
+	}
+	if len(rc.Headers) > 0 {
+		for _, name := range rc.Headers {
+			if !validHeaderName(name) {
+				return route{}, fmt.Errorf("invalid header name %q", name)
+			}
+		}
+		config.Headers = rc.Headers
+	}
+
+	policy := &routePolicy{
+		name:        rc.Prefix + rc.Regex,
+		config:      &config,
+		enforceList: fallback.enforceList,
+		limiter:     fallback.limiter,
+	}
+	if rc.EnforceList != nil {
+		if *rc.EnforceList && !listed {
+			return route{}, errors.New("enforce_list needs -denylist or -allowlist")
+		}
+		policy.enforceList = *rc.EnforceList
+	}
+	if rc.Burst != nil {
+		if *rc.Burst < 1 {
+			return route{}, errors.New("burst must be at least 1")
+		}
+		if rc.RateLimit == nil {
+			return route{}, errors.New("burst needs rate_limit")
+		}
+		burst = *rc.Burst
+	}
+	if rc.RateLimit != nil {
+		switch {
+		case *rc.RateLimit < 0:
+			return route{}, errors.New("rate_limit must not be negative")
+		case *rc.RateLimit == 0:
+			policy.limiter = nil
+		default:
+			policy.limiter = newRateLimiter(*rc.RateLimit, burst)
+		}
+	}
+	rt.policy = policy
+	return rt, nil
+}
+
+// policy returns the route policy of r.
+func (s *server) policy(r *http.Request) *routePolicy {
+	return s.routes.match(r.URL.Path)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand/v2"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// sampler decides which fingerprinted requests get side effects: logging,
+// sinks, persistence, /stats, the entropy table, and clustering. Every
+// request still gets its fingerprint in the response. A nil *sampler
+// samples every request.
+type sampler struct {
+	// rate is the fraction of requests sampled, from 0 to 1
+	rate float64
+	// byFingerprint samples by the fingerprint hash rather than per
+	// request, so a client is either always or never sampled
+	byFingerprint bool
+}
+
+// Sample reports whether the request with the fingerprint hash is
+// sampled.
+func (s *sampler) Sample(hash string) bool {
+	switch {
+	case s == nil || s.rate >= 1:
+		return true
+	case s.rate <= 0:
+		return false
+	case s.byFingerprint:
+		return hashFraction(hash) < s.rate
+	default:
+		return rand.Float64() < s.rate
+	}
+}
+
+// hashFraction maps a versioned fingerprint onto [0, 1) using the leading
+// bits of its digest, which are already uniformly distributed. Hashes
+// that cannot be decoded map to 0, so they are always sampled.
+func hashFraction(hash string) float64 {
+	_, digest, ok := fingerprint.SplitVersion(hash)
+	if !ok || len(digest) < 16 {
+		return 0
+	}
+	raw, err := hex.DecodeString(digest[:16])
+	if err != nil {
+		return 0
+	}
+	return float64(binary.BigEndian.Uint64(raw)>>11) / (1 << 53)
+}
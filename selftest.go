@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// selfTestResponse is the body of /fingerprint/self-test.
+type selfTestResponse struct {
+	Passed        bool              `json:"passed"`
+	SchemaVersion int               `json:"schema_version"`
+	Fixtures      []selfTestFixture `json:"fixtures"`
+}
+
+type selfTestFixture struct {
+	Name                      string `json:"name"`
+	Passed                    bool   `json:"passed"`
+	Fingerprint               string `json:"fingerprint"`
+	ExpectedFingerprint       string `json:"expected_fingerprint"`
+	StableFingerprint         string `json:"stable_fingerprint"`
+	ExpectedStableFingerprint string `json:"expected_stable_fingerprint"`
+}
+
+// handleSelfTest runs fingerprint.SelfTest and reports the result of each
+// fixture. It answers 200 when every fixture passes and 500 otherwise, so
+// it can back a deployment check. The fixtures are hashed with the default
+// configuration, whatever the flags are.
+func (s *server) handleSelfTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+
+	resp := selfTestResponse{Passed: true, SchemaVersion: fingerprint.SchemaVersion}
+	for _, result := range fingerprint.SelfTest() {
+		resp.Passed = resp.Passed && result.Passed
+		resp.Fixtures = append(resp.Fixtures, selfTestFixture{
+			Name:                      result.Name,
+			Passed:                    result.Passed,
+			Fingerprint:               result.Fingerprint,
+			ExpectedFingerprint:       result.ExpectedFingerprint,
+			StableFingerprint:         result.StableFingerprint,
+			ExpectedStableFingerprint: result.ExpectedStableFingerprint,
+		})
+	}
+	status := http.StatusOK
+	if !resp.Passed {
+		status = http.StatusInternalServerError
+	}
+	writeJSON(w, status, resp)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// TestSelfTestIgnoresConfig checks that /fingerprint/self-test hashes the
+// fixtures with the default configuration, whatever the server uses.
+func TestSelfTestIgnoresConfig(t *testing.T) {
+	s := newTestServer(t)
+	s.config = &fingerprint.Config{Salt: "deployment-a", ExcludeIP: true}
+
+	w := get(s.handleSelfTest, "/fingerprint/self-test", "192.0.2.1:1234", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp selfTestResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Passed || resp.SchemaVersion != fingerprint.SchemaVersion || len(resp.Fixtures) != len(fingerprint.SelfTestFixtures()) {
+		t.Errorf("response = %+v", resp)
+	}
+}
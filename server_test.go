@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"browser-fingerprint/fingerprint"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// newTestServer returns a server with the defaults of main and no
+// optional features, for handler tests to enable what they need.
+func newTestServer(t testing.TB) *server {
+	t.Helper()
+	s := &server{
+		config:    &fingerprint.Config{},
+		now:       time.Now,
+		entropy:   newEntropyTable(time.Hour),
+		stats:     newStatsTracker(time.Now()),
+		metrics:   newMetrics(prometheus.NewRegistry(), false, nil, nil, 1, false),
+		clientKey: ipKey{},
+	}
+	s.tools, _ = fingerprint.NewToolClassifier(fingerprint.DefaultToolSignatures())
+	s.routes = &routeTable{fallback: &routePolicy{name: "default", config: s.config}}
+	return s
+}
+
+// get sends a GET request for target from remoteAddr through handler and
+// returns the recorded response.
+func get(handler http.HandlerFunc, target, remoteAddr string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	r.RemoteAddr = remoteAddr
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// lowConfidence reports whether data lacks a User-Agent or has fewer than
+// -min-signals other signals, making its fingerprint nearly worthless. It
+// is always false when -min-signals is not set.
+func (s *server) lowConfidence(data fingerprint.Data) bool {
+	return s.minSignals > 0 && (data.UserAgent == "" || fingerprint.SignalCount(data) < s.minSignals)
+}
+
+// requireSignals rejects low-confidence requests with 422 Unprocessable
+// Entity before next runs. It is a no-op unless -low-signal=reject.
+func (s *server) requireSignals(next http.HandlerFunc) http.HandlerFunc {
+	if s.minSignals == 0 || !s.rejectLowSignal {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, _, _ := s.peekFingerprint(r)
+		if s.lowConfidence(data) {
+			writeJSON(w, http.StatusUnprocessableEntity, errorResponse{
+				Error: fmt.Sprintf("too few fingerprint signals: a User-Agent and %d of Accept, Accept-Language, Accept-Encoding, Sec-Ch-*, and Sec-Fetch-* are required", s.minSignals),
+			})
+			return
+		}
+		next(w, r)
+	}
+}
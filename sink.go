@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// Sink receives every fingerprint the server computes, so fingerprints can
+// be forwarded to other systems. seen is when the request was
+// fingerprinted. Record is called synchronously from the request handler
+// and must not block for long; sinks that do I/O should queue the work.
+// Sinks that also implement sinkCloser are closed on shutdown.
+type Sink interface {
+	Record(ctx context.Context, data fingerprint.Data, fingerprint string, seen time.Time) error
+}
+
+// sinkCloser is implemented by sinks that buffer records and must flush
+// them before the server exits.
+type sinkCloser interface {
+	Close(ctx context.Context) error
+}
+
+// record passes a fingerprint to every configured sink, logging failures
+// so one broken sink does not affect the response or the other sinks.
+// Sinks get the data redacted.
+func (s *server) record(ctx context.Context, data fingerprint.Data, hash string, seen time.Time) {
+	data = s.redactor.Data(data)
+	for _, sink := range s.sinks {
+		if err := sink.Record(ctx, data, hash, seen); err != nil {
+			slog.Error("failed to record fingerprint", "sink", fmt.Sprintf("%T", sink), "fingerprint", hash, "error", err)
+		}
+	}
+}
+
+// closeSinks flushes and closes the sinks that buffer records.
+func (s *server) closeSinks(ctx context.Context) {
+	for _, sink := range s.sinks {
+		if closer, ok := sink.(sinkCloser); ok {
+			if err := closer.Close(ctx); err != nil {
+				slog.Error("failed to close sink", "sink", fmt.Sprintf("%T", sink), "error", err)
+			}
+		}
+	}
+}
+
+// sinkEvent is the JSON form of a recorded fingerprint.
+type sinkEvent struct {
+	Fingerprint string            `json:"fingerprint"`
+	IP          string            `json:"ip"`
+	UserAgent   string            `json:"user_agent"`
+	Method      string            `json:"method"`
+	Protocol    string            `json:"protocol"`
+	TLSVersion  string            `json:"tls_version,omitempty"`
+	JA3         string            `json:"ja3,omitempty"`
+	JA4         string            `json:"ja4,omitempty"`
+	Headers     map[string]string `json:"headers"`
+	Timestamp   string            `json:"timestamp"`
+}
+
+func newSinkEvent(data fingerprint.Data, hash string, now time.Time) sinkEvent {
+	return sinkEvent{
+		Fingerprint: hash,
+		IP:          data.IPAddress,
+		UserAgent:   data.UserAgent,
+		Method:      data.Method,
+		Protocol:    data.Protocol,
+		TLSVersion:  data.TLSVersion,
+		JA3:         data.JA3,
+		JA4:         data.JA4,
+		Headers:     data.Headers,
+		Timestamp:   now.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// logSink writes each fingerprint as a structured log line, which goes to
+// stdout or -log-file. It is the default sink. The line is timestamped
+// with seen rather than the time it is written.
+type logSink struct {
+	// headers are the lower-cased names of headers logged as an
+	// excluded_headers group, for -log-excluded-headers
+	headers []string
+}
+
+func (s logSink) Record(ctx context.Context, data fingerprint.Data, hash string, seen time.Time) error {
+	handler := slog.Default().Handler()
+	if !handler.Enabled(ctx, slog.LevelInfo) {
+		return nil
+	}
+	record := slog.NewRecord(seen, slog.LevelInfo, "fingerprint", 0)
+	record.AddAttrs(
+		slog.String("fingerprint", hash),
+		slog.String("ip", data.IPAddress),
+		slog.String("user_agent", data.UserAgent),
+		slog.String("method", data.Method),
+		slog.String("protocol", data.Protocol),
+		slog.String("tls_version", data.TLSVersion))
+	if len(s.headers) > 0 {
+		var headers []any
+		for _, name := range s.headers {
+			if value, ok := data.Headers[name]; ok {
+				headers = append(headers, slog.String(name, value))
+			}
+		}
+		record.AddAttrs(slog.Group("excluded_headers", headers...))
+	}
+	return handler.Handle(ctx, record)
+}
+
+const (
+	// webhookQueueSize bounds the events waiting for delivery, so a slow
+	// or unreachable endpoint cannot grow memory without limit.
+	webhookQueueSize = 1000
+
+	// webhookBackoff is the delay before the first retry; it doubles for
+	// each later one.
+	webhookBackoff = 500 * time.Millisecond
+)
+
+var errWebhookQueueFull = errors.New("webhook queue full, dropping fingerprint")
+
+// webhookSink POSTs each fingerprint as a JSON sinkEvent to a URL. Events
+// are queued and delivered in order by a single worker, so Record never
+// waits on the network. Failed deliveries, including 5xx and 429
+// responses, are retried with exponential backoff.
+type webhookSink struct {
+	url     string
+	retries int
+	client  *http.Client
+
+	mu     sync.Mutex
+	closed bool
+	queue  chan []byte
+	done   chan struct{}
+
+	// stop is canceled when Close runs out of time, to abort the delivery
+	// in progress, request and retry backoff alike
+	stop   context.Context
+	cancel context.CancelFunc
+}
+
+func newWebhookSink(url string, retries int, timeout time.Duration) *webhookSink {
+	w := &webhookSink{
+		url:     url,
+		retries: retries,
+		client:  &http.Client{Timeout: timeout},
+		queue:   make(chan []byte, webhookQueueSize),
+		done:    make(chan struct{}),
+	}
+	w.stop, w.cancel = context.WithCancel(context.Background())
+	go w.run()
+	return w
+}
+
+func (w *webhookSink) Record(_ context.Context, data fingerprint.Data, hash string, seen time.Time) error {
+	// The request context ends with the response, so delivery does not
+	// use it
+	body, err := json.Marshal(newSinkEvent(data, hash, seen))
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return errors.New("webhook sink closed")
+	}
+	select {
+	case w.queue <- body:
+		return nil
+	default:
+		return errWebhookQueueFull
+	}
+}
+
+func (w *webhookSink) run() {
+	defer close(w.done)
+	dropped := 0
+	for body := range w.queue {
+		if w.stop.Err() != nil {
+			dropped++
+			continue
+		}
+		if err := w.deliver(body); err != nil {
+			slog.Error("webhook delivery failed", "url", w.url, "error", err)
+		}
+	}
+	if dropped > 0 {
+		slog.Error("dropped undelivered webhook fingerprints on shutdown", "url", w.url, "count", dropped)
+	}
+}
+
+// deliver POSTs body, retrying up to w.retries times.
+func (w *webhookSink) deliver(body []byte) error {
+	backoff := webhookBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = w.post(body); err == nil {
+			return nil
+		}
+		if attempt == w.retries {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt+1, err)
+		}
+		select {
+		case <-time.After(backoff):
+		case <-w.stop.Done():
+			return fmt.Errorf("shutting down: %w", err)
+		}
+		backoff *= 2
+	}
+}
+
+func (w *webhookSink) post(body []byte) error {
+	req, err := http.NewRequestWithContext(w.stop, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	if resp.StatusCode >= 400 {
+		// Retrying will not fix a rejected payload
+		slog.Error("webhook rejected fingerprint", "url", w.url, "status", resp.Status)
+	}
+	return nil
+}
+
+// Close stops accepting events and waits for the queued ones to be
+// delivered. When ctx is done first, the delivery in progress is aborted
+// and the rest are dropped, so Close returns soon after the deadline
+// rather than after the webhook timeout.
+func (w *webhookSink) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		<-w.done
+		return fmt.Errorf("webhook deliveries abandoned: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"browser-fingerprint/fingerprint"
+)
+
+func TestWebhookSinkClose(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   func(release <-chan struct{}) http.HandlerFunc
+		events    int
+		delivered int
+		wantErr   error
+	}{
+		{
+			name: "delivers queued events",
+			handler: func(<-chan struct{}) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {}
+			},
+			events:    3,
+			delivered: 3,
+		},
+		{
+			name: "hung endpoint",
+			handler: func(release <-chan struct{}) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					select {
+					case <-release:
+					case <-r.Context().Done():
+					}
+				}
+			},
+			events:  3,
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "retrying endpoint",
+			handler: func(<-chan struct{}) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			},
+			events:  1,
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			var delivered atomic.Int32
+			handler := tt.handler(release)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handler(w, r)
+				delivered.Add(1)
+			}))
+			defer srv.Close()
+			defer close(release)
+
+			// The webhook timeout and retries outlast the deadline
+			sink := newWebhookSink(srv.URL, 10, time.Minute)
+			for range tt.events {
+				if err := sink.Record(t.Context(), fingerprint.Data{}, "v7:aaaa", time.Now()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			err := sink.Close(ctx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Close = %v, want %v", err, tt.wantErr)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("Close took %v past a 200ms deadline", elapsed)
+			}
+			if tt.delivered > 0 && int(delivered.Load()) != tt.delivered {
+				t.Errorf("delivered %d events, want %d", delivered.Load(), tt.delivered)
+			}
+			if err := sink.Record(t.Context(), fingerprint.Data{}, "v7:aaaa", time.Now()); err == nil {
+				t.Error("Record after Close succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS fingerprints (
+	fingerprint     TEXT PRIMARY KEY,
+	first_seen      TEXT NOT NULL,
+	last_seen       TEXT NOT NULL,
+	hit_count       INTEGER NOT NULL,
+	last_ip         TEXT NOT NULL,
+	last_user_agent TEXT NOT NULL,
+	ips             TEXT NOT NULL DEFAULT '[]',
+	user_agents     TEXT NOT NULL DEFAULT '[]',
+	components      TEXT NOT NULL DEFAULT '[]'
+)`
+
+// sqliteColumns are the columns added to the schema since it was first
+// released, which older databases are migrated to.
+var sqliteColumns = map[string]string{
+	"ips":         "TEXT NOT NULL DEFAULT '[]'",
+	"user_agents": "TEXT NOT NULL DEFAULT '[]'",
+	"components":  "TEXT NOT NULL DEFAULT '[]'",
+}
+
+const sqliteUpsert = `
+INSERT INTO fingerprints (fingerprint, first_seen, last_seen, hit_count, last_ip, last_user_agent, ips, user_agents, components)
+VALUES (?1, ?2, ?2, 1, ?3, ?4, ?5, ?6, ?7)
+ON CONFLICT (fingerprint) DO UPDATE SET
+	last_seen = excluded.last_seen,
+	hit_count = hit_count + 1,
+	last_ip = excluded.last_ip,
+	last_user_agent = excluded.last_user_agent,
+	ips = excluded.ips,
+	user_agents = excluded.user_agents,
+	components = excluded.components
+RETURNING hit_count, first_seen, last_seen, last_ip, last_user_agent, ips, user_agents, components`
+
+const sqliteGet = `
+SELECT hit_count, first_seen, last_seen, last_ip, last_user_agent, ips, user_agents, components
+FROM fingerprints WHERE fingerprint = ?1`
+
+const sqliteRecent = `SELECT ips, user_agents FROM fingerprints WHERE fingerprint = ?1`
+
+// sqliteStore persists fingerprints to a SQLite database, keyed by hash.
+type sqliteStore struct {
+	// mu serializes writes; SQLite allows a single writer at a time
+	mu     sync.Mutex
+	db     *sql.DB
+	upsert *sql.Stmt
+	recent *sql.Stmt
+}
+
+// sqliteDSN returns the URI that opens the database file at path. The
+// path is escaped, so one containing ? or # names a file rather than
+// starting the query.
+func sqliteDSN(path string) string {
+	u := url.URL{
+		Scheme:   "file",
+		OmitHost: true,
+		Path:     path,
+		// Transactions take the write lock up front, so one that reads
+		// before writing waits for other writers instead of failing to
+		// upgrade
+		RawQuery: url.Values{
+			"_pragma": {"busy_timeout(5000)", "journal_mode(WAL)"},
+			"_txlock": {"immediate"},
+		}.Encode(),
+	}
+	return u.String()
+}
+
+func openSQLiteStore(path string) (*sqliteStore, error) {
+	db, err := sql.Open("sqlite", sqliteDSN(path))
+	if err != nil {
+		return nil, fmt.Errorf("open database %s: %w", path, err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create schema in %s: %w", path, err)
+	}
+	if err := migrateSQLite(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate schema in %s: %w", path, err)
+	}
+
+	upsert, err := db.Prepare(sqliteUpsert)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("prepare upsert: %w", err)
+	}
+	recent, err := db.Prepare(sqliteRecent)
+	if err != nil {
+		upsert.Close()
+		db.Close()
+		return nil, fmt.Errorf("prepare recent values: %w", err)
+	}
+
+	return &sqliteStore{db: db, upsert: upsert, recent: recent}, nil
+}
+
+// migrateSQLite adds the sqliteColumns a database created by an older
+// version lacks.
+func migrateSQLite(db *sql.DB) error {
+	for column, definition := range sqliteColumns {
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('fingerprints') WHERE name = ?1", column).Scan(&n); err != nil {
+			return err
+		}
+		if n == 0 {
+			if _, err := db.Exec("ALTER TABLE fingerprints ADD COLUMN " + column + " " + definition); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Upsert records a sighting of fp and returns the updated record. The
+// recent IPs and User-Agents are read and rewritten in one transaction, so
+// other processes sharing the file cannot interleave with it.
+func (s *sqliteStore) Upsert(ctx context.Context, fp string, meta Sighting) (Visit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Visit{}, fmt.Errorf("record fingerprint: %w", err)
+	}
+	defer tx.Rollback()
+
+	var ipsJSON, userAgentsJSON string
+	err = tx.StmtContext(ctx, s.recent).QueryRowContext(ctx, fp).Scan(&ipsJSON, &userAgentsJSON)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return Visit{}, fmt.Errorf("record fingerprint: %w", err)
+	}
+	var ips, userAgents []string
+	json.Unmarshal([]byte(ipsJSON), &ips)
+	json.Unmarshal([]byte(userAgentsJSON), &userAgents)
+	ipsJSON, userAgentsJSON = sqliteList(addRecent(ips, meta.IP)), sqliteList(addRecent(userAgents, meta.UserAgent))
+
+	row := tx.StmtContext(ctx, s.upsert).QueryRowContext(ctx, fp, meta.Seen.UTC().Format(time.RFC3339Nano),
+		meta.IP, meta.UserAgent, ipsJSON, userAgentsJSON, sqliteList(meta.Components))
+	v, err := scanSQLiteVisit(row)
+	if err != nil {
+		return Visit{}, fmt.Errorf("record fingerprint: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return Visit{}, fmt.Errorf("record fingerprint: %w", err)
+	}
+	return v, nil
+}
+
+// sqliteList encodes values as the JSON array stored in the ips,
+// user_agents, and components columns.
+func sqliteList(values []string) string {
+	if len(values) == 0 {
+		return "[]"
+	}
+	b, _ := json.Marshal(values)
+	return string(b)
+}
+
+func (s *sqliteStore) Get(ctx context.Context, fp string) (Visit, error) {
+	v, err := scanSQLiteVisit(s.db.QueryRowContext(ctx, sqliteGet, fp))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Visit{}, errNotFound
+	}
+	if err != nil {
+		return Visit{}, fmt.Errorf("get fingerprint: %w", err)
+	}
+	return v, nil
+}
+
+func (s *sqliteStore) Count(ctx context.Context) (int64, error) {
+	var n int64
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM fingerprints").Scan(&n); err != nil {
+		return 0, fmt.Errorf("count fingerprints: %w", err)
+	}
+	return n, nil
+}
+
+// scanSQLiteVisit scans a row of hit_count, first_seen, last_seen, last_ip,
+// last_user_agent, ips, user_agents, and components. The timestamps are
+// stored as RFC 3339 text and the lists as JSON arrays.
+func scanSQLiteVisit(row *sql.Row) (Visit, error) {
+	var v Visit
+	var firstSeen, lastSeen, ips, userAgents, components string
+	if err := row.Scan(&v.HitCount, &firstSeen, &lastSeen, &v.LastIP, &v.LastUserAgent, &ips, &userAgents, &components); err != nil {
+		return Visit{}, err
+	}
+	if err := json.Unmarshal([]byte(ips), &v.IPs); err != nil {
+		return Visit{}, fmt.Errorf("parse ips: %w", err)
+	}
+	if err := json.Unmarshal([]byte(userAgents), &v.UserAgents); err != nil {
+		return Visit{}, fmt.Errorf("parse user_agents: %w", err)
+	}
+	if err := json.Unmarshal([]byte(components), &v.Components); err != nil {
+		return Visit{}, fmt.Errorf("parse components: %w", err)
+	}
+
+	var err error
+	if v.FirstSeen, err = time.Parse(time.RFC3339Nano, firstSeen); err != nil {
+		return Visit{}, fmt.Errorf("parse first_seen %q: %w", firstSeen, err)
+	}
+	if v.LastSeen, err = time.Parse(time.RFC3339Nano, lastSeen); err != nil {
+		return Visit{}, fmt.Errorf("parse last_seen %q: %w", lastSeen, err)
+	}
+	return v, nil
+}
+
+// Ping verifies the database is still reachable.
+func (s *sqliteStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *sqliteStore) Close() error {
+	s.upsert.Close()
+	s.recent.Close()
+	return s.db.Close()
+}
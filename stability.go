@@ -0,0 +1,150 @@
+package main
+
+import (
+	"container/list"
+	"hash/maphash"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxStabilityHistory bounds the fingerprints remembered per client key.
+const maxStabilityHistory = 16
+
+// stabilityWindow maps fingerprints that drift slightly, such as after a
+// browser update or a changed Accept-Language, to the fingerprint the
+// client was first seen with, so returning-visitor detection does not
+// count every small header change as a new visitor. A fingerprint takes
+// the canonical fingerprint of the nearest one its client key produced
+// within window, if it differs from it in at most distance components,
+// and is otherwise its own canonical fingerprint.
+//
+// Each sighting refreshes the window, so a client drifting one component
+// at a time keeps its canonical fingerprint for as long as it stays
+// active. At most size keys are kept; the least recently seen is evicted
+// to make room, and a key whose fingerprints have all left the window is
+// forgotten. It is safe for concurrent use.
+type stabilityWindow struct {
+	distance int
+	window   time.Duration
+	size     int
+	seed     maphash.Seed
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type stabilityEntry struct {
+	key string
+	// history holds the distinct fingerprints in the window, least
+	// recently seen first
+	history []stabilitySighting
+}
+
+type stabilitySighting struct {
+	hash      string
+	canonical string
+	vector    clusterVector
+	seen      time.Time
+}
+
+func newStabilityWindow(distance int, window time.Duration, size int) *stabilityWindow {
+	return &stabilityWindow{
+		distance: distance,
+		window:   window,
+		size:     size,
+		seed:     maphash.MakeSeed(),
+		entries:  make(map[string]*list.Element, size),
+		order:    list.New(),
+	}
+}
+
+// Canonical records that key produced the fingerprint hash with the given
+// components at now, and returns its canonical fingerprint.
+func (w *stabilityWindow) Canonical(key, hash string, components []string, now time.Time) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var entry *stabilityEntry
+	if elem, ok := w.entries[key]; ok {
+		entry = elem.Value.(*stabilityEntry)
+		w.order.MoveToFront(elem)
+	} else {
+		entry = &stabilityEntry{key: key}
+		w.entries[key] = w.order.PushFront(entry)
+		if w.order.Len() > w.size {
+			oldest := w.order.Back()
+			w.order.Remove(oldest)
+			delete(w.entries, oldest.Value.(*stabilityEntry).key)
+		}
+	}
+
+	cutoff := now.Add(-w.window)
+	entry.history = slices.DeleteFunc(entry.history, func(s stabilitySighting) bool {
+		return !s.seen.After(cutoff)
+	})
+	v := newClusterVector(w.seed, components)
+	canonical := w.match(entry, hash, v)
+	entry.history = slices.DeleteFunc(entry.history, func(s stabilitySighting) bool {
+		return s.hash == hash
+	})
+	entry.history = append(entry.history, stabilitySighting{hash: hash, canonical: canonical, vector: v, seen: now})
+	if len(entry.history) > maxStabilityHistory {
+		entry.history = slices.Delete(entry.history, 0, len(entry.history)-maxStabilityHistory)
+	}
+	w.expire(now)
+	return canonical
+}
+
+// Lookup returns the canonical fingerprint Canonical would return, without
+// recording the sighting.
+func (w *stabilityWindow) Lookup(key, hash string, components []string, now time.Time) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	elem, ok := w.entries[key]
+	if !ok {
+		return hash
+	}
+	cutoff := now.Add(-w.window)
+	entry := &stabilityEntry{key: key}
+	for _, s := range elem.Value.(*stabilityEntry).history {
+		if s.seen.After(cutoff) {
+			entry.history = append(entry.history, s)
+		}
+	}
+	return w.match(entry, hash, newClusterVector(w.seed, components))
+}
+
+// match returns the canonical fingerprint of hash among the sightings of
+// entry: that of hash itself if it was seen, or else of the fingerprint
+// with the fewest edits from v within the distance, preferring the most
+// recently seen on ties, or else hash.
+func (w *stabilityWindow) match(entry *stabilityEntry, hash string, v clusterVector) string {
+	canonical, best := hash, w.distance+1
+	for i := len(entry.history) - 1; i >= 0; i-- {
+		s := entry.history[i]
+		if s.hash == hash {
+			return s.canonical
+		}
+		if edits := v.edits(s.vector); edits < best {
+			canonical, best = s.canonical, edits
+		}
+	}
+	return canonical
+}
+
+// expire forgets the least recently seen keys whose fingerprints have all
+// left the window, so idle keys do not wait for eviction to free memory.
+func (w *stabilityWindow) expire(now time.Time) {
+	cutoff := now.Add(-w.window)
+	for elem := w.order.Back(); elem != nil; elem = w.order.Back() {
+		entry := elem.Value.(*stabilityEntry)
+		if entry.history[len(entry.history)-1].seen.After(cutoff) {
+			return
+		}
+		w.order.Remove(elem)
+		delete(w.entries, entry.key)
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"cmp"
+	"container/heap"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// statsCapacity bounds the distinct values each top-N counter tracks,
+	// and so the largest ?top= a /stats request can ask for.
+	statsCapacity = 256
+
+	// defaultStatsTop is the number of entries /stats lists by default.
+	defaultStatsTop = 10
+)
+
+// statsTracker accumulates the traffic summary returned by /stats. It is
+// safe for concurrent use and its memory is bounded: high-cardinality
+// values such as User-Agents are kept in fixed-size top-N counters.
+type statsTracker struct {
+	started time.Time
+
+	mu         sync.Mutex
+	requests   int64
+	protocols  map[string]int64
+	clients    *topCounter
+	userAgents *topCounter
+	countries  *topCounter
+	ages       [ageOlder + 1]int64
+}
+
+func newStatsTracker(now time.Time) *statsTracker {
+	return &statsTracker{
+		started:    now,
+		protocols:  make(map[string]int64),
+		clients:    newTopCounter(statsCapacity),
+		userAgents: newTopCounter(statsCapacity),
+		countries:  newTopCounter(statsCapacity),
+	}
+}
+
+// observe records a fingerprinted request from the client with the
+// -client-key key. country is empty when GeoIP enrichment is off or found
+// nothing.
+func (t *statsTracker) observe(client, userAgent, country, protocol string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.requests++
+	t.protocols[protocolLabel(protocol)]++
+	t.clients.add(client)
+	t.userAgents.add(userAgent)
+	if country != "" {
+		t.countries.add(country)
+	}
+}
+
+// ageBucket is an upper bound on the time since a fingerprint was first
+// seen, and its label in /stats.
+type ageBucket struct {
+	label string
+	max   time.Duration
+}
+
+// ageBuckets bucket persisted sightings of returning fingerprints by the
+// time since they were first seen.
+var ageBuckets = [...]ageBucket{
+	{"<1h", time.Hour},
+	{"<1d", 24 * time.Hour},
+	{"<7d", 7 * 24 * time.Hour},
+	{"<30d", 30 * 24 * time.Hour},
+}
+
+// Age histogram slots: first sightings, then one per ageBuckets entry,
+// then everything older.
+const (
+	ageNew   = 0
+	ageOlder = len(ageBuckets) + 1
+)
+
+// ageSlot returns the histogram slot of a sighting of v at now.
+func ageSlot(v Visit, now time.Time) int {
+	if v.HitCount <= 1 {
+		return ageNew
+	}
+	age := now.Sub(v.FirstSeen)
+	for i, b := range ageBuckets {
+		if age < b.max {
+			return i + 1
+		}
+	}
+	return ageOlder
+}
+
+// ageLabel returns the /stats label of a histogram slot.
+func ageLabel(slot int) string {
+	switch slot {
+	case ageNew:
+		return "new"
+	case ageOlder:
+		return "older"
+	}
+	return ageBuckets[slot-1].label
+}
+
+// observeAge records a persisted sighting of v at now. It is fed the
+// record the store returns from each upsert, so the histogram costs no
+// query of its own.
+func (t *statsTracker) observeAge(v Visit, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ages[ageSlot(v, now)]++
+}
+
+// topCounter tracks the most frequent values of a stream in bounded memory
+// with the Space-Saving algorithm (Metwally et al., 2005). When it is full,
+// a new value replaces the least frequent one and inherits its count, so
+// counts can overestimate by at most that inherited amount, and any value
+// more frequent than 1/capacity of the stream is guaranteed to be kept.
+// Entries are kept in a min-heap by count, so each update is O(log n).
+type topCounter struct {
+	capacity int
+	entries  map[string]*topEntry
+	heap     topHeap
+}
+
+type topEntry struct {
+	value string
+	count int64
+	index int
+}
+
+func newTopCounter(capacity int) *topCounter {
+	return &topCounter{capacity: capacity, entries: make(map[string]*topEntry, capacity)}
+}
+
+func (c *topCounter) add(value string) {
+	if e, ok := c.entries[value]; ok {
+		e.count++
+		heap.Fix(&c.heap, e.index)
+		return
+	}
+	if len(c.heap) < c.capacity {
+		e := &topEntry{value: value, count: 1}
+		c.entries[value] = e
+		heap.Push(&c.heap, e)
+		return
+	}
+
+	e := c.heap[0]
+	delete(c.entries, e.value)
+	e.value = value
+	e.count++
+	c.entries[value] = e
+	heap.Fix(&c.heap, 0)
+}
+
+// statsEntry is one value of a top-N list and its count.
+type statsEntry struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// top returns the n most frequent values, most frequent first, with ties
+// ordered by value.
+func (c *topCounter) top(n int) []statsEntry {
+	entries := make([]statsEntry, len(c.heap))
+	for i, e := range c.heap {
+		entries[i] = statsEntry{Value: e.value, Count: e.count}
+	}
+	slices.SortFunc(entries, func(a, b statsEntry) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Value, b.Value))
+	})
+	return entries[:min(n, len(entries))]
+}
+
+// topHeap implements heap.Interface, ordering entries by ascending count.
+type topHeap []*topEntry
+
+func (h topHeap) Len() int           { return len(h) }
+func (h topHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+func (h topHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *topHeap) Push(x any) {
+	e := x.(*topEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *topHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+type statsResponse struct {
+	Since              string `json:"since"`
+	Requests           int64  `json:"requests"`
+	UniqueFingerprints int64  `json:"unique_fingerprints"`
+	// StoredFingerprints is the exact count of the fingerprint store, which
+	// may be shared with other instances
+	StoredFingerprints *int64           `json:"stored_fingerprints,omitempty"`
+	Protocols          map[string]int64 `json:"protocols"`
+	// Clients are the most active clients by -client-key, listed only to
+	// requests with the -admin-token since the keys identify clients
+	Clients    []statsEntry `json:"clients,omitempty"`
+	UserAgents []statsEntry `json:"user_agents"`
+	Countries  []statsEntry `json:"countries,omitempty"`
+	// FingerprintAges counts persisted sightings by the age of their
+	// fingerprint, first sightings first and oldest last
+	FingerprintAges []statsEntry `json:"fingerprint_ages,omitempty"`
+}
+
+// handleStats summarizes the requests fingerprinted since startup. ?top=N
+// sets how many clients, User-Agents, and countries are listed.
+func (s *server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+
+	top := defaultStatsTop
+	if value := r.URL.Query().Get("top"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > statsCapacity {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "top must be between 1 and " + strconv.Itoa(statsCapacity)})
+			return
+		}
+		top = n
+	}
+
+	admin := s.isAdmin(r)
+	t := s.stats
+	t.mu.Lock()
+	resp := statsResponse{
+		Since:      t.started.Format(time.RFC3339),
+		Requests:   t.requests,
+		Protocols:  make(map[string]int64, len(t.protocols)),
+		UserAgents: t.userAgents.top(top),
+	}
+	if admin {
+		resp.Clients = t.clients.top(top)
+	}
+	for protocol, count := range t.protocols {
+		resp.Protocols[protocol] = count
+	}
+	if s.geo != nil {
+		resp.Countries = t.countries.top(top)
+	}
+	if s.store != nil {
+		resp.FingerprintAges = make([]statsEntry, len(t.ages))
+		for slot, count := range t.ages {
+			resp.FingerprintAges[slot] = statsEntry{Value: ageLabel(slot), Count: count}
+		}
+	}
+	t.mu.Unlock()
+
+	resp.UniqueFingerprints = int64(math.Round(s.metrics.unique.Estimate()))
+	if s.store != nil {
+		n, err := s.store.Count(r.Context())
+		if err != nil {
+			slog.Error("failed to count stored fingerprints", "error", err)
+		} else {
+			resp.StoredFingerprints = &n
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
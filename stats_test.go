@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStatsCounts fingerprints a known mix of requests and checks the
+// totals /stats reports for them.
+func TestStatsCounts(t *testing.T) {
+	s := newTestServer(t)
+	userAgents := []string{"curl/8.5.0", "Wget/1.21.4", "python-requests/2.31.0"}
+	const n = 30
+	for i := range n {
+		r := httptest.NewRequest(http.MethodGet, "/fingerprint", nil)
+		r.RemoteAddr = "198.51.100.7:5000"
+		r.Header.Set("User-Agent", userAgents[i%len(userAgents)])
+		if i%2 == 0 {
+			r.Proto, r.ProtoMajor, r.ProtoMinor = "HTTP/2.0", 2, 0
+		}
+		w := httptest.NewRecorder()
+		s.handleFingerprint(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("/fingerprint = %d: %s", w.Code, w.Body)
+		}
+	}
+
+	w := get(s.handleStats, "/stats", "192.0.2.1:5000", nil)
+	var resp statsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("/stats = %d %s: %v", w.Code, w.Body, err)
+	}
+	if resp.Requests != n {
+		t.Errorf("requests = %d, want %d", resp.Requests, n)
+	}
+	if want := map[string]int64{"HTTP/1.1": n / 2, "HTTP/2.0": n / 2}; !maps.Equal(resp.Protocols, want) {
+		t.Errorf("protocols = %v, want %v", resp.Protocols, want)
+	}
+	// Each User-Agent is seen over both protocols
+	if want := int64(2 * len(userAgents)); resp.UniqueFingerprints != want {
+		t.Errorf("unique fingerprints = %d, want %d", resp.UniqueFingerprints, want)
+	}
+	if len(resp.UserAgents) != len(userAgents) {
+		t.Fatalf("user agents = %v, want %d entries", resp.UserAgents, len(userAgents))
+	}
+	for _, entry := range resp.UserAgents {
+		if entry.Count != n/int64(len(userAgents)) {
+			t.Errorf("user agent %q counted %d times, want %d", entry.Value, entry.Count, n/len(userAgents))
+		}
+	}
+}
+
+func TestStatsRedactsClients(t *testing.T) {
+	tests := []struct {
+		ipPolicy, uaPolicy string
+		client             string
+	}{
+		{"keep", "keep", "198.51.100.77"},
+		{"truncate", "keep", "198.51.100.0"},
+		{"drop", "drop", redacted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ipPolicy, func(t *testing.T) {
+			s := newTestServer(t)
+			s.adminToken = testAdminToken
+			var err error
+			if s.redactor, err = newRedactor(tt.ipPolicy, tt.uaPolicy, nil, []byte("key")); err != nil {
+				t.Fatal(err)
+			}
+			if w := get(s.handleFingerprint, "/fingerprint", "198.51.100.77:5000", http.Header{"User-Agent": {"secret-agent/1.0"}}); w.Code != http.StatusOK {
+				t.Fatalf("/fingerprint = %d: %s", w.Code, w.Body)
+			}
+
+			admin := http.Header{"Authorization": {"Bearer " + testAdminToken}}
+			body := get(s.handleStats, "/stats", "192.0.2.1:5000", admin).Body.String()
+			if !strings.Contains(body, `"value":"`+tt.client+`"`) {
+				t.Errorf("/stats does not list client %q: %s", tt.client, body)
+			}
+			if tt.ipPolicy != "keep" && strings.Contains(body, "198.51.100.77") {
+				t.Errorf("/stats shows the client IP: %s", body)
+			}
+			if tt.uaPolicy != "keep" && strings.Contains(body, "secret-agent") {
+				t.Errorf("/stats shows the User-Agent: %s", body)
+			}
+		})
+	}
+}
+
+// TestStatsClientsRequireAdmin checks that /stats lists clients only to
+// requests with the admin token, since an ip or ip+ua key holds the raw
+// address when -redact-ip is keep.
+func TestStatsClientsRequireAdmin(t *testing.T) {
+	tests := []struct {
+		name       string
+		adminToken string
+		auth       string
+		listed     bool
+	}{
+		{name: "no token set", auth: "Bearer "},
+		{name: "no credentials", adminToken: testAdminToken},
+		{name: "wrong token", adminToken: testAdminToken, auth: "Bearer wrong-token-0123456789"},
+		{name: "admin", adminToken: testAdminToken, auth: "Bearer " + testAdminToken, listed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			s.adminToken = tt.adminToken
+			s.clientKey = ipUserAgentKey{}
+			if w := get(s.handleFingerprint, "/fingerprint", "198.51.100.77:5000", http.Header{"User-Agent": {"curl/8.5.0"}}); w.Code != http.StatusOK {
+				t.Fatalf("/fingerprint = %d: %s", w.Code, w.Body)
+			}
+
+			w := get(s.handleStats, "/stats", "192.0.2.1:5000", http.Header{"Authorization": {tt.auth}})
+			var resp statsResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("/stats = %d %s: %v", w.Code, w.Body, err)
+			}
+			if listed := len(resp.Clients) > 0; listed != tt.listed {
+				t.Errorf("clients %v, want listed %v", resp.Clients, tt.listed)
+			}
+			if !tt.listed && strings.Contains(w.Body.String(), "198.51.100.77") {
+				t.Errorf("/stats shows the client IP: %s", w.Body)
+			}
+			if len(resp.UserAgents) != 1 || resp.Requests != 1 {
+				t.Errorf("user agents %v after %d requests, want the one", resp.UserAgents, resp.Requests)
+			}
+		})
+	}
+}
+
+// BenchmarkStatsObserve measures the accumulator under concurrent
+// requests, with clients and User-Agents that fit in its counters and with
+// more distinct ones than it tracks.
+func BenchmarkStatsObserve(b *testing.B) {
+	for _, distinct := range []int{16, 4 * statsCapacity} {
+		b.Run(strconv.Itoa(distinct)+"-user-agents", func(b *testing.B) {
+			clients, userAgents := make([]string, distinct), make([]string, distinct)
+			for i := range userAgents {
+				clients[i] = "client-" + strconv.Itoa(i)
+				userAgents[i] = "agent/" + strconv.Itoa(i)
+			}
+			t := newStatsTracker(time.Now())
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					t.observe(clients[i%distinct], userAgents[i%distinct], "NZ", "HTTP/2.0")
+					i++
+				}
+			})
+		})
+	}
+}
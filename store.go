@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// errNotFound is returned by Store.Get for a fingerprint never recorded.
+var errNotFound = errors.New("fingerprint not found")
+
+// Store persists every fingerprint seen and how often it was seen. Every
+// method honors cancellation of its context, and implementations are safe
+// for concurrent use. Stores backed by a shared database let several
+// instances count the same visitors.
+type Store interface {
+	// Upsert records a sighting of fp, creating its record on the first
+	// one, and returns the updated record.
+	Upsert(ctx context.Context, fp string, meta Sighting) (Visit, error)
+
+	// Get returns the record of fp, including the IPs and User-Agents it
+	// was seen with and its latest components, or errNotFound.
+	Get(ctx context.Context, fp string) (Visit, error)
+
+	// Count returns the number of distinct fingerprints recorded.
+	Count(ctx context.Context) (int64, error)
+
+	// Ping verifies the backing database is reachable.
+	Ping(ctx context.Context) error
+
+	Close() error
+}
+
+// Sighting describes one request that produced a fingerprint.
+type Sighting struct {
+	IP        string
+	UserAgent string
+	Seen      time.Time
+	// Components are the key:value parts the fingerprint was hashed from,
+	// redacted like IP and UserAgent
+	Components []string
+}
+
+// Visit is the stored record of a fingerprint.
+type Visit struct {
+	HitCount      int64
+	FirstSeen     time.Time
+	LastSeen      time.Time
+	LastIP        string
+	LastUserAgent string
+
+	// IPs and UserAgents are the distinct values the fingerprint was seen
+	// with, most recently seen first, up to maxVisitValues of each. Get
+	// fills them in; Upsert need not.
+	IPs        []string
+	UserAgents []string
+
+	// Components are those of the latest sighting, so two stored
+	// fingerprints can be diffed. Get fills them in; Upsert need not.
+	Components []string
+}
+
+// maxVisitValues bounds the distinct IPs and User-Agents kept for each
+// fingerprint, so a fingerprint shared by many clients does not grow its
+// record without limit.
+const maxVisitValues = 20
+
+// addRecent returns values with value moved to the front, dropping the
+// least recent beyond maxVisitValues. An empty value, such as an IP
+// dropped by redaction, is not recorded. values is modified in place.
+func addRecent(values []string, value string) []string {
+	if value == "" {
+		return values
+	}
+	if i := slices.Index(values, value); i >= 0 {
+		values = slices.Delete(values, i, i+1)
+	}
+	values = slices.Insert(values, 0, value)
+	return values[:min(len(values), maxVisitValues)]
+}
+
+// storeKinds lists the -store backends.
+var storeKinds = []string{"memory", "sqlite", "postgres", "redis"}
+
+// openStore opens the store of the given kind. dsn is the SQLite file, the
+// Postgres connection string, or the Redis URL, and is unused for memory.
+func openStore(ctx context.Context, kind, dsn string) (Store, error) {
+	if kind != "memory" && dsn == "" {
+		return nil, fmt.Errorf("the %s store needs -store-dsn", kind)
+	}
+	switch kind {
+	case "memory":
+		return newMemoryStore(), nil
+	case "sqlite":
+		return openSQLiteStore(dsn)
+	case "postgres":
+		return openPostgresStore(ctx, dsn)
+	case "redis":
+		return openRedisStore(ctx, dsn)
+	}
+	return nil, fmt.Errorf("unknown store %q, expected one of %v", kind, storeKinds)
+}
+
+// memoryStore keeps fingerprints in process memory. It is lost on restart
+// and not shared between instances, and it grows with every distinct
+// fingerprint, so it suits development and short-lived deployments.
+type memoryStore struct {
+	mu     sync.Mutex
+	visits map[string]Visit
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{visits: make(map[string]Visit)}
+}
+
+func (s *memoryStore) Upsert(ctx context.Context, fp string, meta Sighting) (Visit, error) {
+	if err := ctx.Err(); err != nil {
+		return Visit{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.visits[fp]
+	if !ok {
+		v.FirstSeen = meta.Seen
+	}
+	v.HitCount++
+	v.LastSeen, v.LastIP, v.LastUserAgent = meta.Seen, meta.IP, meta.UserAgent
+	v.IPs = addRecent(v.IPs, meta.IP)
+	v.UserAgents = addRecent(v.UserAgents, meta.UserAgent)
+	v.Components = meta.Components
+	s.visits[fp] = v
+	v.IPs, v.UserAgents, v.Components = nil, nil, nil
+	return v, nil
+}
+
+func (s *memoryStore) Get(ctx context.Context, fp string) (Visit, error) {
+	if err := ctx.Err(); err != nil {
+		return Visit{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.visits[fp]
+	if !ok {
+		return Visit{}, errNotFound
+	}
+	// The lists are modified in place by later sightings
+	v.IPs, v.UserAgents = slices.Clone(v.IPs), slices.Clone(v.UserAgents)
+	return v, nil
+}
+
+func (s *memoryStore) Count(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(len(s.visits)), nil
+}
+
+func (s *memoryStore) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
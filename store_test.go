@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestStores runs the Store suite against every backend. Postgres and
+// Redis need a server, given by FINGERPRINT_TEST_POSTGRES_DSN and
+// FINGERPRINT_TEST_REDIS_URL; without one they are skipped. Records
+// are keyed by the test's start time, so the shared databases need not be
+// empty.
+func TestStores(t *testing.T) {
+	tests := []struct {
+		kind string
+		dsn  func(t *testing.T) string
+	}{
+		{kind: "memory", dsn: func(*testing.T) string { return "" }},
+		{kind: "sqlite", dsn: func(t *testing.T) string { return filepath.Join(t.TempDir(), "fingerprints.db") }},
+		{kind: "postgres", dsn: testStoreEnv("FINGERPRINT_TEST_POSTGRES_DSN")},
+		{kind: "redis", dsn: testStoreEnv("FINGERPRINT_TEST_REDIS_URL")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			dsn := tt.dsn(t)
+			store, err := openStore(t.Context(), tt.kind, dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+			testStore(t, store)
+		})
+	}
+}
+
+func testStoreEnv(name string) func(t *testing.T) string {
+	return func(t *testing.T) string {
+		dsn := os.Getenv(name)
+		if dsn == "" {
+			t.Skip(name + " is not set")
+		}
+		return dsn
+	}
+}
+
+// testStore checks the behavior every Store must share.
+func testStore(t *testing.T, store Store) {
+	ctx := t.Context()
+	prefix := "v7:test-" + strconv.FormatInt(time.Now().UnixNano(), 36) + "-"
+	start := time.Now().UTC().Truncate(time.Second)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+
+	before, err := store.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("get unknown", func(t *testing.T) {
+		if _, err := store.Get(ctx, prefix+"unknown"); !errors.Is(err, errNotFound) {
+			t.Errorf("Get = %v, want errNotFound", err)
+		}
+	})
+
+	t.Run("upsert", func(t *testing.T) {
+		fp := prefix + "upsert"
+		sightings := []struct {
+			meta Sighting
+			want Visit
+		}{
+			{
+				meta: Sighting{IP: "192.0.2.1", UserAgent: "ua-1", Seen: at(0), Components: []string{"ua:ua-1"}},
+				want: Visit{HitCount: 1, FirstSeen: at(0), LastSeen: at(0), LastIP: "192.0.2.1", LastUserAgent: "ua-1"},
+			},
+			{
+				meta: Sighting{IP: "192.0.2.2", UserAgent: "ua-2", Seen: at(1), Components: []string{"ua:ua-2"}},
+				want: Visit{HitCount: 2, FirstSeen: at(0), LastSeen: at(1), LastIP: "192.0.2.2", LastUserAgent: "ua-2"},
+			},
+			{
+				meta: Sighting{IP: "192.0.2.1", UserAgent: "ua-2", Seen: at(2), Components: []string{"ua:ua-2", "ip:192.0.2.1"}},
+				want: Visit{HitCount: 3, FirstSeen: at(0), LastSeen: at(2), LastIP: "192.0.2.1", LastUserAgent: "ua-2"},
+			},
+			{
+				// A redacted IP is kept as the latest but not listed
+				meta: Sighting{UserAgent: "ua-2", Seen: at(3), Components: []string{"ua:ua-2"}},
+				want: Visit{HitCount: 4, FirstSeen: at(0), LastSeen: at(3), LastUserAgent: "ua-2"},
+			},
+		}
+		for i, s := range sightings {
+			got, err := store.Upsert(ctx, fp, s.meta)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sameVisit(got, s.want) {
+				t.Errorf("sighting %d: Upsert = %+v, want %+v", i, got, s.want)
+			}
+		}
+
+		got, err := store.Get(ctx, fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := sightings[len(sightings)-1].want
+		want.IPs = []string{"192.0.2.1", "192.0.2.2"}
+		want.UserAgents = []string{"ua-2", "ua-1"}
+		want.Components = []string{"ua:ua-2"}
+		if !sameVisit(got, want) || !slices.Equal(got.IPs, want.IPs) ||
+			!slices.Equal(got.UserAgents, want.UserAgents) || !slices.Equal(got.Components, want.Components) {
+			t.Errorf("Get = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("recent values are bounded", func(t *testing.T) {
+		fp := prefix + "bounded"
+		for i := range maxVisitValues + 5 {
+			if _, err := store.Upsert(ctx, fp, Sighting{IP: fmt.Sprintf("192.0.2.%d", i), UserAgent: "ua", Seen: at(i)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got, err := store.Get(ctx, fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.IPs) != maxVisitValues || got.IPs[0] != fmt.Sprintf("192.0.2.%d", maxVisitValues+4) {
+			t.Errorf("IPs = %q, want the latest %d", got.IPs, maxVisitValues)
+		}
+	})
+
+	t.Run("concurrent upserts", func(t *testing.T) {
+		fp := prefix + "concurrent"
+		const writers = 20
+		var wg sync.WaitGroup
+		for i := range writers {
+			wg.Go(func() {
+				if _, err := store.Upsert(ctx, fp, Sighting{IP: "192.0.2.1", UserAgent: "ua", Seen: at(i)}); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+		wg.Wait()
+		got, err := store.Get(ctx, fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.HitCount != writers {
+			t.Errorf("HitCount = %d, want %d", got.HitCount, writers)
+		}
+	})
+
+	t.Run("count", func(t *testing.T) {
+		after, err := store.Count(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if after-before != 3 {
+			t.Errorf("Count grew by %d, want 3", after-before)
+		}
+	})
+
+	t.Run("ping", func(t *testing.T) {
+		if err := store.Ping(ctx); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, err := store.Upsert(canceled, prefix+"canceled", Sighting{Seen: at(0)}); err == nil {
+			t.Error("Upsert succeeded with a canceled context")
+		}
+		if _, err := store.Get(canceled, prefix+"upsert"); err == nil {
+			t.Error("Get succeeded with a canceled context")
+		}
+		if _, err := store.Count(canceled); err == nil {
+			t.Error("Count succeeded with a canceled context")
+		}
+		if err := store.Ping(canceled); err == nil {
+			t.Error("Ping succeeded with a canceled context")
+		}
+	})
+}
+
+// sameVisit compares the scalar fields of two records, allowing for stores
+// that return times in another location.
+func sameVisit(a, b Visit) bool {
+	return a.HitCount == b.HitCount && a.FirstSeen.Equal(b.FirstSeen) && a.LastSeen.Equal(b.LastSeen) &&
+		a.LastIP == b.LastIP && a.LastUserAgent == b.LastUserAgent
+}
+
+func TestSQLiteDSN(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"plain.db", "query?mode=memory.db", "frag#ment.db", "per%41cent.db", "sp ace.db"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			store, err := openSQLiteStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+			if _, err := store.Upsert(t.Context(), "v7:aaaa", Sighting{Seen: time.Now()}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("database not created at %s: %v", path, err)
+			}
+		})
+	}
+}
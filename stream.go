@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// maxStreamSubscribers bounds the /stream connections held open at once.
+const maxStreamSubscribers = 100
+
+var (
+	errStreamFull   = errors.New("too many stream subscribers")
+	errStreamClosed = errors.New("server shutting down")
+)
+
+// streamBroadcaster is a Sink that fans every recorded fingerprint out to
+// the /stream subscribers. Each subscriber has its own bounded buffer, and
+// a record that does not fit is dropped for that subscriber alone, so a
+// slow consumer never holds up requests or the other subscribers.
+type streamBroadcaster struct {
+	buffer int
+
+	mu     sync.Mutex
+	subs   map[*streamSubscriber]struct{}
+	closed bool
+
+	// dropped counts records dropped across all subscribers
+	dropped atomic.Int64
+}
+
+// streamSubscriber is one /stream connection.
+type streamSubscriber struct {
+	events chan []byte
+	// dropped counts records dropped since the subscriber was last told
+	dropped atomic.Int64
+	// done is closed when the broadcaster shuts down
+	done chan struct{}
+}
+
+func newStreamBroadcaster(buffer int) *streamBroadcaster {
+	return &streamBroadcaster{buffer: buffer, subs: make(map[*streamSubscriber]struct{})}
+}
+
+// Record encodes the fingerprint once and queues it for every subscriber.
+func (b *streamBroadcaster) Record(_ context.Context, data fingerprint.Data, hash string, seen time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.subs) == 0 {
+		return nil
+	}
+	event, err := json.Marshal(newSinkEvent(data, hash, seen))
+	if err != nil {
+		return err
+	}
+	for sub := range b.subs {
+		select {
+		case sub.events <- event:
+		default:
+			sub.dropped.Add(1)
+			b.dropped.Add(1)
+		}
+	}
+	return nil
+}
+
+// subscribe adds a subscriber, which must be removed with unsubscribe.
+func (b *streamBroadcaster) subscribe() (*streamSubscriber, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, errStreamClosed
+	}
+	if len(b.subs) >= maxStreamSubscribers {
+		return nil, errStreamFull
+	}
+	sub := &streamSubscriber{events: make(chan []byte, b.buffer), done: make(chan struct{})}
+	b.subs[sub] = struct{}{}
+	return sub, nil
+}
+
+func (b *streamBroadcaster) unsubscribe(sub *streamSubscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subs, sub)
+}
+
+func (b *streamBroadcaster) subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Close ends every subscriber's stream, so open /stream connections do
+// not hold up a graceful shutdown, and refuses new ones.
+func (b *streamBroadcaster) Close(context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.closed = true
+		for sub := range b.subs {
+			close(sub.done)
+		}
+	}
+	return nil
+}
+
+// handleStream holds the connection open and writes every fingerprint
+// recorded from then on, one JSON object per line, flushing after each.
+// Clients that accept text/event-stream, such as an EventSource, get
+// server-sent events instead. When records were dropped because the
+// client fell behind, a {"dropped": N} object says how many before the
+// next record.
+func (s *server) handleStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+	sub, err := s.stream.subscribe()
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: err.Error()})
+		return
+	}
+	defer s.stream.unsubscribe(sub)
+
+	// The stream outlives -write-timeout
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	sse := strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+	if sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	write := func(kind string, payload []byte) error {
+		if sse {
+			w.Write([]byte("event: " + kind + "\ndata: "))
+			w.Write(payload)
+			w.Write([]byte("\n\n"))
+		} else {
+			w.Write(payload)
+			w.Write([]byte("\n"))
+		}
+		return rc.Flush()
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sub.done:
+			return
+		case event := <-sub.events:
+			if n := sub.dropped.Swap(0); n > 0 {
+				if write("dropped", []byte(`{"dropped":`+strconv.FormatInt(n, 10)+`}`)) != nil {
+					return
+				}
+			}
+			if write("fingerprint", event) != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"browser-fingerprint/fingerprint"
+)
+
+const testAdminToken = "test-admin-token-0123456789"
+
+func TestStreamRequiresAdmin(t *testing.T) {
+	s := newTestServer(t)
+	s.adminToken = testAdminToken
+	s.stream = newStreamBroadcaster(4)
+	srv := httptest.NewServer(s.requireAdmin(s.handleStream))
+	defer srv.Close()
+
+	for _, auth := range []string{"", "Bearer ", "Bearer wrong-token-0123456789", testAdminToken} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status %d, want 401", auth, resp.StatusCode)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want 200", resp.StatusCode)
+	}
+	// The subscription is open once the headers have arrived
+	s.stream.Record(ctx, fingerprint.Data{IPAddress: "203.0.113.7"}, "v2:abc", time.Now())
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(line, `"v2:abc"`) {
+		t.Errorf("stream line = %q", line)
+	}
+}
+
+// TestStreamBroadcasterConcurrent records fingerprints while subscribers
+// come and go, then closes the broadcaster under them. Run it with -race.
+func TestStreamBroadcasterConcurrent(t *testing.T) {
+	b := newStreamBroadcaster(4)
+	var recorders, subscribers, subscribed sync.WaitGroup
+	stop := make(chan struct{})
+	for range 4 {
+		recorders.Go(func() {
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if err := b.Record(t.Context(), fingerprint.Data{IPAddress: "203.0.113.7"}, "v2:abc", time.Now()); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		})
+	}
+	subscribed.Add(8)
+	for range 8 {
+		subscribers.Go(func() {
+			// Short-lived subscribers leave while records are queued
+			for range 20 {
+				sub, err := b.subscribe()
+				if err != nil {
+					subscribed.Done()
+					t.Error(err)
+					return
+				}
+				select {
+				case <-sub.events:
+				case <-time.After(time.Second):
+					t.Error("no record within a second")
+				}
+				b.unsubscribe(sub)
+			}
+			// The last one stays until the broadcaster closes
+			sub, err := b.subscribe()
+			subscribed.Done()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer b.unsubscribe(sub)
+			<-sub.done
+		})
+	}
+
+	subscribed.Wait()
+	b.Close(t.Context())
+	subscribers.Wait()
+	close(stop)
+	recorders.Wait()
+
+	if _, err := b.subscribe(); !errors.Is(err, errStreamClosed) {
+		t.Errorf("subscribe after Close = %v, want errStreamClosed", err)
+	}
+}
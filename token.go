@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// minTokenSecret is the shortest -token-secret accepted, in bytes.
+const minTokenSecret = 32
+
+// tokenIssuer signs the token field of /fingerprint responses.
+type tokenIssuer struct {
+	secret []byte
+	ttl    time.Duration
+	// fields are the response fields signed into the token as metadata
+	fields []string
+}
+
+// parseTokenFields parses the comma-separated -token-fields list of
+// response fields.
+func parseTokenFields(list string) ([]string, error) {
+	var fields []string
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if field == "fingerprint" || field == "token" || !slices.Contains(fingerprintFields, field) {
+			return nil, fmt.Errorf("unknown token field %q", field)
+		}
+		if !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// needs returns the fields sign reads from a response, nil when t is nil.
+func (t *tokenIssuer) needs() []string {
+	if t == nil {
+		return nil
+	}
+	return t.fields
+}
+
+// sign returns the token of resp, issued at now. Fields of resp that are
+// empty and omitted from its JSON are left out of the metadata.
+func (t *tokenIssuer) sign(resp fingerprintResponse, now time.Time) (string, error) {
+	token := fingerprint.Token{Fingerprint: resp.Fingerprint, IssuedAt: now, ExpiresAt: now.Add(t.ttl)}
+	if len(t.fields) > 0 {
+		resp.fields = fieldSet{}.with(t.fields...)
+		body, err := json.Marshal(resp)
+		if err != nil {
+			return "", err
+		}
+		if err := json.Unmarshal(body, &token.Metadata); err != nil {
+			return "", err
+		}
+	}
+	return fingerprint.SignToken(token, t.secret)
+}
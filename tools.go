@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// loadToolSignatures reads the -client-tools file at path, a YAML list of
+// tool signatures, and returns a classifier matching them ahead of the
+// built-in ones, so a signature reusing a built-in name replaces it:
+//
+//	# an in-house crawler that only asks for JSON
+//	- name: my-scraper
+//	  user_agent: ^MyScraper/
+//	  headers: {accept: 'application/json'}
+//	  absent: [accept-language]
+//	  order: [host, user-agent, accept]
+func loadToolSignatures(path string) (*fingerprint.ToolClassifier, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read client tools: %w", err)
+	}
+
+	var custom []fingerprint.ToolSignature
+	decoder := yaml.NewDecoder(bytes.NewReader(raw))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&custom); err != nil {
+		return nil, fmt.Errorf("parse client tools %s: %w", path, err)
+	}
+
+	names := make(map[string]bool, len(custom))
+	for _, sig := range custom {
+		names[sig.Name] = true
+	}
+	signatures := custom
+	for _, sig := range fingerprint.DefaultToolSignatures() {
+		if !names[sig.Name] {
+			signatures = append(signatures, sig)
+		}
+	}
+	classifier, err := fingerprint.NewToolClassifier(signatures)
+	if err != nil {
+		return nil, fmt.Errorf("client tools %s: %w", path, err)
+	}
+	return classifier, nil
+}
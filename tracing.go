@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"browser-fingerprint/fingerprint"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingScope is the instrumentation scope and default service name of
+// the exported spans.
+const tracingScope = "browser-fingerprint"
+
+// newTracerProvider returns a provider that exports spans in batches over
+// OTLP/HTTP. The exporter is configured by the standard
+// OTEL_EXPORTER_OTLP_* environment variables, such as
+// OTEL_EXPORTER_OTLP_ENDPOINT, and sampling by OTEL_TRACES_SAMPLER; by
+// default a span is sampled when its incoming parent was, or when it has
+// none. OTEL_SERVICE_NAME overrides the service name.
+func newTracerProvider(ctx context.Context) (*sdktrace.TracerProvider, error) {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", tracingScope)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK())
+	if err != nil {
+		return nil, err
+	}
+	return sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res)), nil
+}
+
+// traced runs next in a server span named name, continuing the trace of
+// an incoming W3C traceparent header. It is a no-op when tracing is off.
+func (s *server) traced(name string, next http.HandlerFunc) http.HandlerFunc {
+	if s.tracer == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagation.TraceContext{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := s.tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path)))
+		defer span.End()
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// spanFields returns the response fields annotateSpan needs when the span
+// in ctx is recording, so they are computed even when ?fields= leaves
+// them out of the body.
+func spanFields(ctx context.Context) []string {
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		return nil
+	}
+	return []string{"bot_score"}
+}
+
+// annotateSpan adds the fingerprint of a request to the span in ctx, if
+// one is recording. The client IP and headers are left out, since spans
+// are not redacted.
+func annotateSpan(ctx context.Context, data fingerprint.Data, resp fingerprintResponse) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	span.SetAttributes(
+		attribute.String("fingerprint", resp.Fingerprint),
+		attribute.String("fingerprint.protocol", resp.Protocol),
+		attribute.String("fingerprint.tls_version", data.TLSVersion),
+		attribute.Int("fingerprint.bot_score", resp.BotScore))
+}
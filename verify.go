@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// verifyRequest is a set of request attributes together with the
+// fingerprint previously computed for them.
+type verifyRequest struct {
+	requestAttributes
+	Expected string `json:"expected"`
+}
+
+type verifyResponse struct {
+	Match       bool   `json:"match"`
+	Fingerprint string `json:"fingerprint"`
+
+	// Components are only included on a mismatch when the request asks
+	// for ?debug=1
+	Components []string `json:"components,omitempty"`
+}
+
+// handleVerify recomputes the fingerprint of the posted attributes and
+// reports whether it still equals the expected hash, so an edge that
+// cached a fingerprint can revalidate it without sending the live request.
+func (s *server) handleVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+
+	var req verifyRequest
+	if err := decodeJSONBody(w, r, &req, maxCompareBody); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	if req.Expected == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: `"expected" is required`})
+		return
+	}
+
+	data, hash := s.config.FromData(req.data())
+	resp := verifyResponse{
+		Match:       strings.EqualFold(req.Expected, hash),
+		Fingerprint: hash,
+	}
+	// The expected hash cannot be split back into components, so the
+	// current ones are returned for the caller to diff against its own
+	if !resp.Match && isDebug(r) {
+		resp.Components = s.config.Components(data)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"browser-fingerprint/fingerprint"
+)
+
+// webSocketGUID is appended to the client key to compute
+// Sec-WebSocket-Accept (RFC 6455, Section 1.3).
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// webSocketCloseWait bounds how long the server waits for the client's
+// Close frame before closing the connection.
+const webSocketCloseWait = time.Second
+
+// handleWebSocketFingerprint fingerprints a WebSocket upgrade request,
+// including its handshake headers, for clients that connect over
+// WebSocket rather than plain HTTP. It completes the upgrade, sends the
+// fingerprint as a single JSON text message, and closes the connection.
+// With ?upgrade=0 the fingerprint is returned as a plain JSON response
+// instead of upgrading.
+func (s *server) handleWebSocketFingerprint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+	if !fingerprint.IsWebSocketUpgrade(r) {
+		w.Header().Set("Connection", "Upgrade")
+		w.Header().Set("Upgrade", "websocket")
+		writeJSON(w, http.StatusUpgradeRequired, errorResponse{Error: "websocket upgrade required"})
+		return
+	}
+
+	resp := s.fingerprint(w, r)
+	if upgrade, err := strconv.ParseBool(r.URL.Query().Get("upgrade")); err == nil && !upgrade {
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		writeJSON(w, http.StatusUpgradeRequired, errorResponse{Error: "unsupported websocket version"})
+		return
+	}
+	if raw, err := base64.StdEncoding.DecodeString(key); err != nil || len(raw) != 16 {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid Sec-WebSocket-Key"})
+		return
+	}
+
+	message, err := json.Marshal(resp)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "failed to encode response"})
+		return
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "websocket upgrade not supported"})
+		return
+	}
+	defer conn.Close()
+
+	// Headers already set on w, such as the visitor cookie, are lost on
+	// hijack, so they are copied into the handshake response
+	accept := sha1.Sum([]byte(key + webSocketGUID))
+	fmt.Fprint(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	fmt.Fprintf(rw, "Sec-WebSocket-Accept: %s\r\n", base64.StdEncoding.EncodeToString(accept[:]))
+	w.Header().Write(rw)
+	fmt.Fprint(rw, "\r\n")
+
+	writeWebSocketFrame(rw, 0x1, message)
+	closePayload := binary.BigEndian.AppendUint16(nil, 1000)
+	writeWebSocketFrame(rw, 0x8, closePayload)
+	if err := rw.Flush(); err != nil {
+		slog.Debug("websocket write failed", "error", err)
+		return
+	}
+
+	// The server closes the TCP connection after the client's Close
+	// frame (RFC 6455, Section 7.1.1); its contents are not needed
+	conn.SetReadDeadline(time.Now().Add(webSocketCloseWait))
+	rw.Read(make([]byte, 128))
+}
+
+// writeWebSocketFrame writes one unmasked, unfragmented frame, as sent
+// from server to client.
+func writeWebSocketFrame(w io.Writer, opcode byte, payload []byte) {
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xffff:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	w.Write(header)
+	w.Write(payload)
+}